@@ -2,7 +2,12 @@ package main
 
 import (
 	"fmt"
+	"nctl/internal/conf"
 	"nctl/internal/iface"
+	"nctl/internal/neigh"
+	nctlnet "nctl/internal/net"
+	"nctl/internal/route"
+	"nctl/internal/utils/output"
 
 	"os"
 
@@ -19,8 +24,19 @@ func main() {
 		},
 	}
 
+	// 全局输出格式
+	output.RegisterFlag(rootCmd)
+
 	// 挂载 iface 系列命令
 	iface.RegisterIfaceCommands(rootCmd)
+	// 挂载 conf 系列命令
+	conf.RegisterConfCommands(rootCmd)
+	// 挂载 route 系列命令
+	route.RegisterRouteCommands(rootCmd)
+	// 挂载 neigh 系列命令
+	neigh.RegisterNeighCommands(rootCmd)
+	// 挂载 net 系列命令
+	nctlnet.RegisterNetCommands(rootCmd)
 
 	// 执行根命令
 	if err := rootCmd.Execute(); err != nil {
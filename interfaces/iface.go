@@ -1,6 +1,7 @@
 package interfaces
 
 import (
+	"nctl/internal/utils/dhcp"
 	"net"
 )
 
@@ -19,6 +20,48 @@ type Ifaces interface {
 	// 覆盖接口的dns设置
 	SetDNSs(ifaceName string, dnsIPs []net.IP) error
 
-	// 网关设置操作
-	SetGateway(iface string, gateway net.IP) error
+	// 设置接口的默认网关，只替换该接口上同一地址族的默认路由，metric 为 0 时由系统决定
+	SetGateway(iface string, gateway net.IP, metric int) error
+	// 在接口上添加一条默认路由，不影响已有的默认网关，用于恢复多个默认网关
+	AddGateway(iface string, gateway net.IP, metric int) error
+	// 删除接口上经由该网关的默认路由
+	DelGateway(iface string, gateway net.IP) error
+
+	// 读取接口的链路层详情和收发计数
+	GetLinkDetails(iface string) (*LinkDetails, error)
+
+	// 读取接口当前的 ip（不含 link-local）、dns 和默认网关
+	GetIPs(iface string) ([]*net.IPNet, error)
+	// GetIPs 中由 dhcp、slaac 等动态获取的地址
+	GetDynamicIPs(iface string) ([]*net.IPNet, error)
+	GetDNSs(iface string) ([]net.IP, error)
+	// 默认网关按 metric 从小到大排列
+	GetGateways(iface string) ([]Gateway, error)
+
+	// 清空接口上的 ip（保留 link-local）、静态路由和 dns
+	FlushIPs(iface string) error
+	FlushRoutes(iface string) error
+	FlushDNS(iface string) error
+
+	// 通过 dhcp 获取地址。windows 上启用系统的 dhcp 客户端并返回 nil，租约由系统维护；
+	// 其他平台通过内置客户端获取租约并返回，由调用方应用、保存和续约
+	ObtainDHCP(iface string) (*dhcp.Lease, error)
+
+	// 链路层设置
+	// 启用或禁用接口
+	SetLinkState(iface string, up bool) error
+	SetMAC(iface string, mac net.HardwareAddr) error
+	SetMTU(iface string, mtu int) error
+	SetPromisc(iface string, enable bool) error
+	SetARP(iface string, enable bool) error
+	// 设置接口的描述（别名）
+	SetAlias(iface string, alias string) error
+	// 启用或禁用接口上的 ipv6
+	SetIPv6(iface string, enable bool) error
+	// 设置速率（Mb/s）和双工模式，speed 为 0 或 duplex 为空时保持不变
+	SetLinkMode(iface string, speed int, duplex string) error
+	// 在接口上创建 vlan 子接口
+	AddVLAN(iface string, id int) error
+	// 设置接口发出报文的 ttl / hop limit，linux 上 ipv4 ttl 是全局参数，只设置 ipv6 hop limit
+	SetTTL(iface string, ttl int) error
 }
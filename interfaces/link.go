@@ -0,0 +1,43 @@
+package interfaces
+
+import "net"
+
+// 接口的链路层详情，平台无法获取的字段保持零值
+type LinkDetails struct {
+	// 管理状态是否为 up
+	Up           bool
+	MTU          int
+	HardwareAddr net.HardwareAddr
+	// 运行状态（up, down, dormant, unknown ...）
+	OperState string
+	// 是否检测到载波
+	Carrier bool
+	// 链路类型（device, veth, bridge, bond, vlan, tuntap ...）
+	Type string
+	// 所属的 bridge / bond 设备
+	Master string
+	// 驱动及其版本、固件版本
+	Driver        string
+	DriverVersion string
+	Firmware      string
+	// 协商速率（Mb/s），未知时为 0
+	Speed int
+	// 双工模式（half, full），未知时为空
+	Duplex string
+	// 发送队列长度
+	TxQueueLen int
+	// 收发计数
+	Stats LinkStats
+}
+
+// 接口收发计数
+type LinkStats struct {
+	RxBytes   uint64
+	RxPackets uint64
+	RxErrors  uint64
+	RxDropped uint64
+	TxBytes   uint64
+	TxPackets uint64
+	TxErrors  uint64
+	TxDropped uint64
+}
@@ -0,0 +1,56 @@
+package interfaces
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// 邻居表（ipv4 的 ARP、ipv6 的 ND）中的一条记录
+type Neigh struct {
+	// 4 或 6
+	Family int
+	IP     net.IP
+	// 未解析出链路层地址时为空
+	MAC   net.HardwareAddr
+	Iface string
+	// INCOMPLETE, REACHABLE, STALE, DELAY, PROBE, FAILED, NOARP, PERMANENT
+	State string
+	// ipv6 邻居通告中声明自己是路由器
+	Router bool
+}
+
+// 列出和清空邻居表时的过滤条件，零值表示不过滤
+type NeighFilter struct {
+	Family int
+	Iface  string
+	State  string
+}
+
+// 添加邻居时可以使用的状态，未指定时为 PERMANENT
+var NeighAddStates = []string{"PERMANENT", "NOARP", "REACHABLE", "STALE"}
+
+// 所有状态名称，按邻居状态机的顺序排列
+var NeighStates = []string{"INCOMPLETE", "REACHABLE", "STALE", "DELAY", "PROBE", "FAILED", "NOARP", "PERMANENT"}
+
+// 解析状态名称，不区分大小写
+func ParseNeighState(s string, valid []string) (string, error) {
+	upper := strings.ToUpper(s)
+	for _, state := range valid {
+		if state == upper {
+			return state, nil
+		}
+	}
+	return "", fmt.Errorf("invalid neighbor state '%s' (value: %s)", s, strings.ToLower(strings.Join(valid, ", ")))
+}
+
+type Neighs interface {
+	// 列出符合条件的邻居
+	ListNeighs(filter NeighFilter) ([]Neigh, error)
+	// 添加邻居，已存在时覆盖
+	AddNeigh(n *Neigh) error
+	// 删除邻居，未指定接口时在所有接口上查找
+	DelNeigh(n *Neigh) error
+	// 删除符合条件的动态邻居，filter.State 为 PERMANENT 或 NOARP 时才删除静态邻居，返回删除的数量
+	FlushNeighs(filter NeighFilter) (int, error)
+}
@@ -0,0 +1,111 @@
+package interfaces
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// 代理设置的作用范围
+const (
+	// linux 上为 /etc/environment 和 /etc/profile.d，windows 上为 HKLM 下的 WinINET 设置
+	ProxyScopeSystem = "system"
+	// linux 上为 ~/.profile 中由 nctl 管理的一段，windows 上为 HKCU 下的 WinINET 设置
+	ProxyScopeUser = "user"
+)
+
+// 代理设置，空字符串表示未设置
+type ProxySettings struct {
+	// 代理地址，如 http://proxy.example.com:3128
+	HTTP  string
+	HTTPS string
+	// 不经过代理的主机、域名后缀或网段
+	NoProxy []string
+	// 读取设置的位置，如 /etc/environment
+	Source string
+}
+
+// 是否没有任何设置
+func (p *ProxySettings) Empty() bool {
+	return p.HTTP == "" && p.HTTPS == "" && len(p.NoProxy) == 0
+}
+
+// 解析代理地址，省略协议时为 http://，必须带有端口
+func ParseProxyURL(s string) (string, error) {
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Hostname() == "" {
+		return "", fmt.Errorf("invalid proxy address '%s'", s)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return "", fmt.Errorf("unsupported proxy scheme '%s' (value: http, https, socks5, socks5h)", u.Scheme)
+	}
+	if u.Port() == "" {
+		return "", fmt.Errorf("proxy address '%s' has no port", s)
+	}
+	if !validProxyHost(u.Hostname()) {
+		return "", fmt.Errorf("invalid proxy host '%s'", u.Hostname())
+	}
+	// 代理地址会写入 shell 脚本，重新编码用户名和密码，只保留不需要转义的字符
+	userinfo := ""
+	if u.User != nil {
+		userinfo = escapeUserinfo(u.User.Username())
+		if password, ok := u.User.Password(); ok {
+			userinfo += ":" + escapeUserinfo(password)
+		}
+		userinfo += "@"
+	}
+	return u.Scheme + "://" + userinfo + u.Host, nil
+}
+
+func validProxyHost(host string) bool {
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	for _, r := range host {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' || r == '_') {
+			return false
+		}
+	}
+	return host != ""
+}
+
+// 对 RFC 3986 unreserved 以外的字符做百分号编码
+func escapeUserinfo(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("-._~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// 检查 no_proxy 中的一项，这些值会写入 shell 脚本和注册表，不允许空白、控制字符和引号
+func ParseNoProxy(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	for _, r := range s {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune("\"'`\\", r) {
+			return "", fmt.Errorf("invalid no-proxy entry '%s'", s)
+		}
+	}
+	return s, nil
+}
+
+type Proxies interface {
+	// 读取指定范围的代理设置
+	GetProxy(scope string) (*ProxySettings, error)
+	// 覆盖指定范围的代理设置
+	SetProxy(scope string, p *ProxySettings) error
+	// 清除指定范围的代理设置
+	UnsetProxy(scope string) error
+}
@@ -0,0 +1,217 @@
+package interfaces
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// 路由表中的一条路由，平台不支持的字段保持零值
+type Route struct {
+	// 4 或 6
+	Family int
+	// 目的网段，默认路由为 0.0.0.0/0 或 ::/0
+	Dst     *net.IPNet
+	Gateway net.IP
+	// 出接口
+	Iface string
+	// 首选源地址
+	Src    net.IP
+	Metric int
+	// 路由表编号，windows 上固定为 main
+	Table int
+	// universe, site, link, host, nowhere
+	Scope string
+	// kernel, boot, static, dhcp, ra ...
+	Protocol string
+	// unicast, local, broadcast, blackhole, unreachable, prohibit ...
+	Type string
+}
+
+// 接口上的一条默认路由
+type Gateway struct {
+	IP     net.IP
+	Metric int
+}
+
+// 列出路由时的过滤条件，零值表示不过滤
+type RouteFilter struct {
+	Family   int
+	Table    int
+	Iface    string
+	Protocol string
+}
+
+// 策略路由规则
+type Rule struct {
+	Family   int
+	Priority int
+	// 选择条件，零值表示不限制
+	Src *net.IPNet
+	Dst *net.IPNet
+	// fwmark 选择条件，Mask 为 0 时表示 0xffffffff
+	Mark uint32
+	Mask uint32
+	Iif  string
+	Oif  string
+	// 选择条件取反
+	Invert bool
+	// lookup, goto, nop, blackhole, unreachable, prohibit
+	Action string
+	// Action 为 lookup 时查询的路由表
+	Table int
+	// Action 为 goto 时跳转到的规则优先级
+	Goto int
+	// 忽略前缀长度不大于该值的路由，-1 表示不启用
+	SuppressPrefixlen int
+}
+
+// 以 ip rule 的格式描述规则
+func (r Rule) String() string {
+	desc := fmt.Sprintf("%d:", r.Priority)
+	if r.Invert {
+		desc += " not"
+	}
+	if r.Src != nil {
+		desc += " from " + r.Src.String()
+	} else {
+		desc += " from all"
+	}
+	if r.Dst != nil {
+		desc += " to " + r.Dst.String()
+	}
+	if r.Mark != 0 || r.Mask != 0 {
+		desc += fmt.Sprintf(" fwmark %#x", r.Mark)
+		if r.Mask != 0 && r.Mask != 0xffffffff {
+			desc += fmt.Sprintf("/%#x", r.Mask)
+		}
+	}
+	if r.Iif != "" {
+		desc += " iif " + r.Iif
+	}
+	if r.Oif != "" {
+		desc += " oif " + r.Oif
+	}
+	switch r.Action {
+	case "lookup":
+		desc += " lookup " + TableName(r.Table)
+	case "goto":
+		desc += fmt.Sprintf(" goto %d", r.Goto)
+	default:
+		desc += " " + r.Action
+	}
+	if r.SuppressPrefixlen >= 0 {
+		desc += fmt.Sprintf(" suppress_prefixlength %d", r.SuppressPrefixlen)
+	}
+	return desc
+}
+
+// 路由查询的条件，零值表示不指定
+type RouteQuery struct {
+	Dst  net.IP
+	Src  net.IP
+	Mark uint32
+	// 模拟从该接口收到的报文
+	Iif string
+}
+
+// 路由查询的结果
+type RouteLookup struct {
+	// 选中的路由，Dst 为匹配到的路由表项的目的网段
+	Route Route
+	// 选中该路由的策略规则，平台不支持策略路由或无法确定时为 nil
+	Rule *Rule
+}
+
+// 常用路由表的编号
+const (
+	TableDefault = 253
+	TableMain    = 254
+	TableLocal   = 255
+)
+
+var tableNames = map[int]string{
+	TableDefault: "default",
+	TableMain:    "main",
+	TableLocal:   "local",
+}
+
+// 返回路由表的名称，没有名称时返回编号
+func TableName(table int) string {
+	if name, ok := tableNames[table]; ok {
+		return name
+	}
+	return strconv.Itoa(table)
+}
+
+// 解析路由表的名称或编号
+func ParseTable(s string) (int, error) {
+	for table, name := range tableNames {
+		if name == s {
+			return table, nil
+		}
+	}
+	table, err := strconv.Atoi(s)
+	if err != nil || table <= 0 {
+		return 0, fmt.Errorf("invalid routing table '%s' (value: main, local, default or a positive number)", s)
+	}
+	return table, nil
+}
+
+// 解析规则中的网段，空字符串和 all 表示不限制，单个地址视为主机前缀
+func ParsePrefix(s string) (*net.IPNet, error) {
+	if s == "" || s == "all" {
+		return nil, nil
+	}
+	if _, ipnet, err := net.ParseCIDR(s); err == nil {
+		return ipnet, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid prefix '%s'", s)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// 解析 mark[/mask]，支持十进制和 0x 开头的十六进制
+func ParseFwmark(s string) (mark, mask uint32, err error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	markStr, maskStr, hasMask := strings.Cut(s, "/")
+	m, err := strconv.ParseUint(markStr, 0, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid fwmark '%s'", s)
+	}
+	mask = 0xffffffff
+	if hasMask {
+		v, err := strconv.ParseUint(maskStr, 0, 32)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid fwmark mask '%s'", s)
+		}
+		mask = uint32(v)
+	}
+	return uint32(m), mask, nil
+}
+
+type Routes interface {
+	// 列出所有路由表中符合条件的路由
+	ListRoutes(filter RouteFilter) ([]Route, error)
+	// 路由的增删改，未填写的字段由系统决定
+	AddRoute(r *Route) error
+	DelRoute(r *Route) error
+	// 目的网段相同的路由存在时替换，否则添加
+	ReplaceRoute(r *Route) error
+	// 查询内核为报文选择的路由
+	LookupRoute(q RouteQuery) (*RouteLookup, error)
+
+	// 按优先级列出策略路由规则，family 为 0 时列出所有地址族
+	ListRules(family int) ([]Rule, error)
+	// 规则的增删，Priority 为负数时由内核分配或不参与匹配
+	AddRule(r *Rule) error
+	DelRule(r *Rule) error
+}
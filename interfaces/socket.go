@@ -0,0 +1,40 @@
+package interfaces
+
+import "net"
+
+// 一个 TCP 或 UDP 套接字
+type Socket struct {
+	// tcp 或 udp
+	Proto string
+	// 4 或 6
+	Family     int
+	LocalIP    net.IP
+	LocalPort  int
+	RemoteIP   net.IP
+	RemotePort int
+	// 与 ss 的写法一致：LISTEN, ESTAB, TIME-WAIT ...，未连接的 UDP 套接字为 UNCONN
+	State string
+	// 所属进程，无权限读取时为 0
+	PID     int
+	Command string
+}
+
+// 列出套接字时的过滤条件，TCP 和 UDP 都为 false 时表示两者都列出
+type SocketFilter struct {
+	TCP bool
+	UDP bool
+	// 只列出监听中的 TCP 套接字和未连接的 UDP 套接字
+	Listen bool
+	// 本端或对端端口，0 表示不过滤
+	Port int
+}
+
+// 是否为监听状态
+func (s *Socket) Listening() bool {
+	return s.State == "LISTEN" || s.State == "UNCONN"
+}
+
+type Sockets interface {
+	// 列出符合条件的套接字及其所属进程
+	ListSockets(filter SocketFilter) ([]Socket, error)
+}
@@ -0,0 +1,106 @@
+package interfaces
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"time"
+)
+
+// WireGuard 密钥的长度
+const WireGuardKeyLen = 32
+
+// WireGuard 接口的当前状态
+type WireGuardDevice struct {
+	Name string
+	// base64 编码的密钥，未设置时为空
+	PrivateKey string
+	PublicKey  string
+	// 0 表示未设置，由内核随机选择
+	ListenPort int
+	FwMark     uint32
+	Peers      []WireGuardPeer
+}
+
+// 一个对端的配置和统计
+type WireGuardPeer struct {
+	PublicKey    string
+	PresharedKey string
+	// 对端地址，未知时为 nil
+	Endpoint            *net.UDPAddr
+	AllowedIPs          []*net.IPNet
+	PersistentKeepalive time.Duration
+	// 最近一次握手的时间，从未握手时为零值
+	LastHandshake time.Time
+	RxBytes       uint64
+	TxBytes       uint64
+}
+
+// 修改 WireGuard 接口时的参数，指针为 nil 的字段保持不变
+type WireGuardConfig struct {
+	PrivateKey *string
+	ListenPort *int
+	FwMark     *uint32
+	// 删除 Peers 以外的所有对端
+	ReplacePeers bool
+	Peers        []WireGuardPeerConfig
+}
+
+// 修改一个对端时的参数，对端不存在时会被创建
+type WireGuardPeerConfig struct {
+	PublicKey string
+	// 删除该对端，其他字段被忽略
+	Remove              bool
+	PresharedKey        *string
+	Endpoint            *net.UDPAddr
+	PersistentKeepalive *time.Duration
+	// 用 AllowedIPs 替换已有的列表，否则追加
+	ReplaceAllowedIPs bool
+	AllowedIPs        []*net.IPNet
+}
+
+// 解析 base64 编码的密钥
+func ParseWireGuardKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(key) != WireGuardKeyLen {
+		return nil, fmt.Errorf("invalid WireGuard key '%s'", s)
+	}
+	return key, nil
+}
+
+// 生成一个私钥，与 wg genkey 一样按 curve25519 的要求处理
+func GenerateWireGuardKey() (string, error) {
+	key := make([]byte, WireGuardKeyLen)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	key[0] &= 248
+	key[31] = (key[31] & 127) | 64
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// 由私钥计算公钥
+func WireGuardPublicKey(privateKey string) (string, error) {
+	key, err := ParseWireGuardKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	priv, err := ecdh.X25519().NewPrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(priv.PublicKey().Bytes()), nil
+}
+
+type WireGuards interface {
+	// 创建 WireGuard 接口，创建后处于 down 状态
+	CreateWireGuard(name string) error
+	// 读取接口的配置和每个对端的统计，name 为空时列出所有 WireGuard 接口
+	ListWireGuards(name string) ([]WireGuardDevice, error)
+	// 修改接口和对端的配置
+	ConfigureWireGuard(name string, cfg *WireGuardConfig) error
+	// 删除 WireGuard 接口，不是 WireGuard 接口时返回错误
+	DeleteWireGuard(name string) error
+}
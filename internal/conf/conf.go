@@ -0,0 +1,31 @@
+package conf
+
+import (
+	"nctl/internal/conf/in"
+	"nctl/internal/conf/out"
+
+	"github.com/spf13/cobra"
+)
+
+var confCmd = &cobra.Command{
+	Use:   "conf",
+	Short: "Declarative network configuration",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// 注册所有 conf 下的子命令
+func RegisterConfCommands(rootCmd *cobra.Command) {
+	// 挂载 conf 子命令
+	rootCmd.AddCommand(confCmd)
+
+	// 挂载 conf apply 命令
+	confCmd.AddCommand(in.Apply())
+
+	// 挂载 conf diff 命令
+	confCmd.AddCommand(in.Diff())
+
+	// 挂载 conf export 命令
+	confCmd.AddCommand(out.Export())
+}
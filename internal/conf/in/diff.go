@@ -0,0 +1,132 @@
+package in
+
+import (
+	"errors"
+	"fmt"
+	"nctl/internal/conf/schema"
+	"nctl/internal/utils"
+	"nctl/internal/utils/output"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// conf diff 的退出码，便于作为定时检查使用
+const (
+	ExitInSync = 0
+	ExitDrift  = 1
+	ExitError  = 2
+)
+
+var diffFile string
+
+// 一处差异的输出格式
+type driftRecord struct {
+	Iface  string `json:"iface" yaml:"iface"`
+	Field  string `json:"field" yaml:"field"`
+	Detail string `json:"detail" yaml:"detail"`
+}
+
+func Diff() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Report drift between a config file and the live host",
+		Long: "Report drift between a config file and the live host.\n\n" +
+			"Exit status is 0 when the host is in sync, 1 when drift was found and 2 on error.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			os.Exit(runDiff(cmd))
+		},
+	}
+
+	cmd.Flags().StringVarP(&diffFile, "file", "f", "", "Path of the config file (see config/iface.yml)")
+
+	return cmd
+}
+
+func runDiff(cmd *cobra.Command) int {
+	format, err := output.Format(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return ExitError
+	}
+	if diffFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: --file is required")
+		return ExitError
+	}
+
+	cfg, err := schema.Load(diffFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return ExitError
+	}
+
+	ifaceUtils, routeUtils := utils.IfaceUtils(), utils.RouteUtils()
+	records := []driftRecord{}
+	failed := false
+	for _, desired := range cfg.Interfaces {
+		// 接口不存在也属于差异
+		if err := ifaceUtils.IsExistingIface(desired.Name); err != nil {
+			records = append(records, driftRecord{desired.Name, "name", "interface does not exist"})
+			continue
+		}
+
+		changes, err := PlanInterface(ifaceUtils, routeUtils, desired)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", desired.Name, err)
+			failed = true
+			continue
+		}
+		for _, c := range changes {
+			records = append(records, driftRecord{c.Iface, c.Field, c.Drift})
+		}
+	}
+
+	if len(cfg.Rules) > 0 {
+		changes, err := PlanRules(routeUtils, cfg.Rules)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", RulesLabel, err)
+			failed = true
+		}
+		for _, c := range changes {
+			records = append(records, driftRecord{c.Iface, c.Field, c.Drift})
+		}
+	}
+
+	if err := printDrift(format, records); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return ExitError
+	}
+
+	switch {
+	case failed:
+		return ExitError
+	case len(records) > 0:
+		return ExitDrift
+	default:
+		return ExitInSync
+	}
+}
+
+func printDrift(format string, records []driftRecord) error {
+	switch format {
+	case output.JSON, output.YAML:
+		return output.Write(os.Stdout, format, records)
+	case output.CSV:
+		var rows [][]string
+		for _, r := range records {
+			rows = append(rows, []string{r.Iface, r.Field, r.Detail})
+		}
+		return output.WriteCSV(os.Stdout, []string{"iface", "field", "detail"}, rows)
+	case output.Table:
+		if len(records) == 0 {
+			fmt.Println("In sync")
+			return nil
+		}
+		for _, r := range records {
+			fmt.Printf("%s: [%s] %s\n", r.Iface, r.Field, r.Detail)
+		}
+		return nil
+	}
+	return errors.New("unsupported output format")
+}
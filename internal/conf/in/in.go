@@ -1 +1,343 @@
 package in
+
+import (
+	"fmt"
+	"nctl/interfaces"
+	"nctl/internal/conf/schema"
+	"nctl/internal/utils"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	applyFile   string
+	applyDryRun bool
+)
+
+// 配置与主机之间的一处差异，Drift 描述差异本身，Desc 描述消除差异的操作
+type Change struct {
+	Iface string
+	Field string
+	Drift string
+	Desc  string
+	Apply func() error
+}
+
+func Apply() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Reconcile the host network configuration against a config file",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if applyFile == "" {
+				fmt.Fprintln(os.Stderr, "Error: --file is required")
+				cmd.Help()
+				os.Exit(1)
+			}
+
+			cfg, err := schema.Load(applyFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			// 任何接口规划或执行失败时以 1 退出，其余接口照常处理
+			failed := false
+			ifaceUtils, routeUtils := utils.IfaceUtils(), utils.RouteUtils()
+			for _, desired := range cfg.Interfaces {
+				changes, err := PlanInterface(ifaceUtils, routeUtils, desired)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%s: %v\n", desired.Name, err)
+					failed = true
+					continue
+				}
+				if !applyChanges(desired.Name, changes) {
+					failed = true
+				}
+			}
+
+			// 规则可能引用接口和路由表，放在接口之后处理
+			if len(cfg.Rules) > 0 {
+				changes, err := PlanRules(routeUtils, cfg.Rules)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%s: %v\n", RulesLabel, err)
+					failed = true
+				} else if !applyChanges(RulesLabel, changes) {
+					failed = true
+				}
+			}
+
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&applyFile, "file", "f", "", "Path of the config file (see config/iface.yml)")
+	cmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Print the changes without applying them")
+
+	return cmd
+}
+
+// 依次执行 changes，单个失败后继续，全部成功时返回 true
+func applyChanges(label string, changes []Change) bool {
+	if len(changes) == 0 {
+		fmt.Printf("%s: in sync\n", label)
+		return true
+	}
+
+	ok := true
+	fmt.Printf("%s:\n", label)
+	for _, c := range changes {
+		if applyDryRun {
+			fmt.Printf("  [PLAN] %s\n", c.Desc)
+			continue
+		}
+		if err := c.Apply(); err != nil {
+			fmt.Fprintf(os.Stderr, "  [FAIL] %s: %v\n", c.Desc, err)
+			ok = false
+		} else {
+			fmt.Printf("  [ OK ] %s\n", c.Desc)
+		}
+	}
+	return ok
+}
+
+func containsIPNet(list []*net.IPNet, ipnet *net.IPNet) bool {
+	for _, item := range list {
+		if item.String() == ipnet.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// 与 ip 同一地址族的网关
+func sameFamily(gws []interfaces.Gateway, ip net.IP) []interfaces.Gateway {
+	var result []interfaces.Gateway
+	for _, gw := range gws {
+		if (gw.IP.To4() == nil) == (ip.To4() == nil) {
+			result = append(result, gw)
+		}
+	}
+	return result
+}
+
+func describeGateway(ip net.IP, metric int) string {
+	if metric == 0 {
+		return ip.String()
+	}
+	return fmt.Sprintf("%s metric %d", ip, metric)
+}
+
+func describeGateways(gws []interfaces.Gateway) string {
+	if len(gws) == 0 {
+		return "none"
+	}
+	var list []string
+	for _, gw := range gws {
+		list = append(list, describeGateway(gw.IP, gw.Metric))
+	}
+	return strings.Join(list, ", ")
+}
+
+func sameIPs(a, b []net.IP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// 转换为经由接口的路由，未指定 metric 时由系统决定
+func toRoute(iface string, r schema.Route) *interfaces.Route {
+	_, dst, _ := net.ParseCIDR(r.To)
+	route := &interfaces.Route{Family: 4, Dst: dst, Gateway: net.ParseIP(r.Via), Iface: iface, Metric: r.Metric}
+	if dst.IP.To4() == nil {
+		route.Family = 6
+	}
+	return route
+}
+
+func describeRoute(r schema.Route) string {
+	desc := r.To
+	if r.Via != "" {
+		desc += " via " + r.Via
+	}
+	if r.Metric > 0 {
+		desc += fmt.Sprintf(" metric %d", r.Metric)
+	}
+	return desc
+}
+
+// 未指定 metric 时只比较目的网段和网关
+func containsRoute(list []interfaces.Route, r schema.Route) bool {
+	want := toRoute("", r)
+	for _, item := range list {
+		if item.Dst.String() != want.Dst.String() || !item.Gateway.Equal(want.Gateway) {
+			continue
+		}
+		if want.Metric == 0 || item.Metric == want.Metric {
+			return true
+		}
+	}
+	return false
+}
+
+// 比较接口的期望状态与当前状态，按执行顺序返回需要的改动，已一致时返回空
+func PlanInterface(ifaceUtils interfaces.Ifaces, routeUtils interfaces.Routes, desired schema.Interface) ([]Change, error) {
+	name := desired.Name
+	if err := ifaceUtils.IsExistingIface(name); err != nil {
+		return nil, err
+	}
+
+	details, err := ifaceUtils.GetLinkDetails(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read link details: %w", err)
+	}
+	isUp := details.Up
+
+	var changes []Change
+	add := func(field, drift, desc string, apply func() error) {
+		changes = append(changes, Change{Iface: name, Field: field, Drift: drift, Desc: desc, Apply: apply})
+	}
+
+	// 需要启用时最先执行，后续的地址和路由设置依赖接口处于 up 状态
+	if desired.State == "up" && !isUp {
+		add("state", "link is down, want up", "Setting link UP", func() error { return ifaceUtils.SetLinkState(name, true) })
+	}
+
+	if desired.MAC != "" {
+		mac, _ := net.ParseMAC(desired.MAC)
+		if details.HardwareAddr.String() != mac.String() {
+			add("mac", fmt.Sprintf("MAC is %s, want %s", details.HardwareAddr, mac), fmt.Sprintf("Changing MAC address: %s -> %s", details.HardwareAddr, mac), func() error {
+				return ifaceUtils.SetMAC(name, mac)
+			})
+		}
+	}
+
+	if desired.MTU > 0 && details.MTU != desired.MTU {
+		add("mtu", fmt.Sprintf("MTU is %d, want %d", details.MTU, desired.MTU), fmt.Sprintf("Changing MTU: %d -> %d", details.MTU, desired.MTU), func() error {
+			return ifaceUtils.SetMTU(name, desired.MTU)
+		})
+	}
+
+	// addresses 为 nil 表示不管理，空列表表示清空，dhcp、slaac 获取的动态地址不在管理范围内
+	if desired.Addresses != nil {
+		want, _ := desired.IPNets()
+		current, err := ifaceUtils.GetIPs(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read IP addresses: %w", err)
+		}
+		dynamic, err := ifaceUtils.GetDynamicIPs(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read IP addresses: %w", err)
+		}
+		for _, ipnet := range current {
+			if !containsIPNet(want, ipnet) && !containsIPNet(dynamic, ipnet) {
+				add("addresses", "extra address "+ipnet.String(), "Removing IP "+ipnet.String(), func() error { return ifaceUtils.DelIP(name, ipnet) })
+			}
+		}
+		for _, ipnet := range want {
+			if !containsIPNet(current, ipnet) {
+				add("addresses", "missing address "+ipnet.String(), "Adding IP "+ipnet.String(), func() error { return ifaceUtils.AddIP(name, ipnet) })
+			}
+		}
+	}
+
+	if gws := desired.AllGateways(); len(gws) > 0 {
+		current, err := ifaceUtils.GetGateways(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gateways: %w", err)
+		}
+		for _, want := range gws {
+			gw := net.ParseIP(want.Via)
+			have := sameFamily(current, gw)
+			if len(have) == 1 && have[0].IP.Equal(gw) && (want.Metric == 0 || have[0].Metric == want.Metric) {
+				continue
+			}
+			metric := want.Metric
+			add("gateway", fmt.Sprintf("default gateway is %s, want %s", describeGateways(have), describeGateway(gw, metric)),
+				fmt.Sprintf("Setting default gateway: %s -> %s", describeGateways(have), describeGateway(gw, metric)), func() error {
+					return ifaceUtils.SetGateway(name, gw, metric)
+				})
+		}
+	}
+
+	if len(desired.Routes) > 0 {
+		current, err := routeUtils.ListRoutes(interfaces.RouteFilter{Iface: name, Table: interfaces.TableMain})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read routes: %w", err)
+		}
+		for _, r := range desired.Routes {
+			if !containsRoute(current, r) {
+				route := toRoute(name, r)
+				add("routes", "missing route "+describeRoute(r), "Adding route "+describeRoute(r), func() error { return routeUtils.ReplaceRoute(route) })
+			}
+		}
+	}
+
+	if desired.DNS != nil {
+		want, _ := desired.DNSIPs()
+		current, err := ifaceUtils.GetDNSs(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read DNS servers: %w", err)
+		}
+		// 顺序决定优先级，顺序不同也需要重新设置
+		if !sameIPs(current, want) {
+			add("dns", fmt.Sprintf("DNS servers are %v, want %v", current, want), fmt.Sprintf("Setting DNS servers: %v -> %v", current, want), func() error {
+				return ifaceUtils.SetDNSs(name, want)
+			})
+		}
+	}
+
+	// 需要禁用时最后执行
+	if desired.State == "down" && isUp {
+		add("state", "link is up, want down", "Setting link DOWN", func() error { return ifaceUtils.SetLinkState(name, false) })
+	}
+
+	return changes, nil
+}
+
+// 规则的差异在输出中使用的名称
+const RulesLabel = "rules"
+
+// 比较期望的规则与当前规则，返回缺少的规则，列表之外的规则保持不变
+func PlanRules(routeUtils interfaces.Routes, desired []schema.Rule) ([]Change, error) {
+	current, err := routeUtils.ListRules(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	// 按 ip rule 的写法比较，同时包含地址族避免 v4 与 v6 的规则互相匹配
+	have := map[string]bool{}
+	for _, r := range current {
+		have[fmt.Sprintf("%d %s", r.Family, r)] = true
+	}
+
+	var changes []Change
+	for _, d := range desired {
+		rule, err := d.Rule()
+		if err != nil {
+			return nil, err
+		}
+		if have[fmt.Sprintf("%d %s", rule.Family, rule)] {
+			continue
+		}
+		desc := fmt.Sprintf("ipv%d %s", rule.Family, rule)
+		changes = append(changes, Change{
+			Iface: RulesLabel,
+			Field: "rule",
+			Drift: "missing rule " + desc,
+			Desc:  "Adding rule " + desc,
+			Apply: func() error { return routeUtils.AddRule(rule) },
+		})
+	}
+	return changes, nil
+}
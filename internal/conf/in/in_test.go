@@ -0,0 +1,102 @@
+package in
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"nctl/interfaces"
+	"nctl/internal/conf/schema"
+	"nctl/internal/utils/fake"
+)
+
+func fields(changes []Change) []string {
+	var out []string
+	for _, c := range changes {
+		out = append(out, c.Field)
+	}
+	return out
+}
+
+func TestPlanInterfaceIsIdempotent(t *testing.T) {
+	_, extra, _ := net.ParseCIDR("192.0.2.99/24")
+	host := &fake.Host{
+		Details:  interfaces.LinkDetails{MTU: 1500, HardwareAddr: net.HardwareAddr{2, 0, 0, 0, 0, 1}},
+		IPs:      []*net.IPNet{extra},
+		Gateways: []interfaces.Gateway{{IP: net.ParseIP("192.0.2.254"), Metric: 100}},
+	}
+	desired := schema.Interface{
+		Name:      "eth0",
+		State:     "up",
+		MAC:       "02:00:00:00:00:02",
+		MTU:       9000,
+		Addresses: []string{"192.0.2.10/24", "2001:db8::10/64"},
+		Gateways:  []schema.Gateway{{Via: "192.0.2.1", Metric: 50}, {Via: "2001:db8::1"}},
+		DNS:       []string{"192.0.2.53", "2001:db8::53"},
+		Routes:    []schema.Route{{To: "198.51.100.0/24", Via: "192.0.2.1", Metric: 10}, {To: "2001:db8:1::/48"}},
+	}
+
+	changes, err := PlanInterface(host, host, desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"state", "mac", "mtu", "addresses", "addresses", "addresses", "gateway", "gateway", "routes", "routes", "dns"}
+	if got := fields(changes); !reflect.DeepEqual(got, want) {
+		t.Fatalf("fields = %v, want %v", got, want)
+	}
+	for _, c := range changes {
+		if err := c.Apply(); err != nil {
+			t.Fatalf("%s: %v", c.Desc, err)
+		}
+	}
+
+	// 应用之后再次规划不应有任何改动
+	changes, err = PlanInterface(host, host, desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		var descs []string
+		for _, c := range changes {
+			descs = append(descs, c.Desc)
+		}
+		t.Fatalf("second plan = %v, want none", descs)
+	}
+}
+
+func TestPlanInterfaceUnmanagedFields(t *testing.T) {
+	_, ipnet, _ := net.ParseCIDR("192.0.2.10/24")
+	host := &fake.Host{
+		Details: interfaces.LinkDetails{Up: true, MTU: 1500},
+		IPs:     []*net.IPNet{ipnet},
+		DNS:     []net.IP{net.ParseIP("192.0.2.53")},
+	}
+
+	// 未填写的字段不管理
+	changes, err := PlanInterface(host, host, schema.Interface{Name: "eth0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("fields = %v, want none", fields(changes))
+	}
+
+	// 空列表表示清空
+	changes, err = PlanInterface(host, host, schema.Interface{Name: "eth0", State: "down", Addresses: []string{}, DNS: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"addresses", "dns", "state"}; !reflect.DeepEqual(fields(changes), want) {
+		t.Fatalf("fields = %v, want %v", fields(changes), want)
+	}
+
+	// 动态获取的地址不属于静态配置，不会被删除
+	host.Dynamic = host.IPs
+	changes, err = PlanInterface(host, host, schema.Interface{Name: "eth0", Addresses: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("fields = %v, want none", fields(changes))
+	}
+}
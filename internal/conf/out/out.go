@@ -1 +1,239 @@
 package out
+
+import (
+	"fmt"
+	"io"
+	"nctl/interfaces"
+	"nctl/internal/conf/schema"
+	"nctl/internal/utils"
+	"nctl/internal/utils/snapshot"
+	"net"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFile   string
+	exportRedact bool
+)
+
+func Export() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Dump the live network state as a config file",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := Collect(utils.IfaceUtils(), utils.RouteUtils(), exportRedact, func(err error) {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return
+			}
+			// 不支持策略路由的平台上只导出接口
+			if cfg.Rules, err = CollectRules(utils.RouteUtils()); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: rules are not exported: %v\n", err)
+			}
+
+			var w io.Writer = os.Stdout
+			if exportFile != "" {
+				f, err := os.Create(exportFile)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					return
+				}
+				defer f.Close()
+				w = f
+			}
+
+			if err := cfg.Write(w); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: failed to write config: %v\n", err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&exportFile, "out", "o", "", "Write the config to a file instead of stdout")
+	cmd.Flags().BoolVar(&exportRedact, "redact", false, "Leave MAC addresses out of the config")
+
+	return cmd
+}
+
+// 读取除回环接口外所有接口的当前配置。
+// 单个接口读取失败时通过 warn 报告并跳过该接口，只有列出接口失败时返回错误
+func Collect(ifaceUtils interfaces.Ifaces, routeUtils interfaces.Routes, redact bool, warn func(error)) (*schema.Config, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interfaces: %w", err)
+	}
+
+	cfg := &schema.Config{}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		entry, err := collectIface(ifaceUtils, routeUtils, iface, redact, warn)
+		if err != nil {
+			warn(fmt.Errorf("%s: skipped: %w", iface.Name, err))
+			continue
+		}
+		cfg.Interfaces = append(cfg.Interfaces, *entry)
+	}
+	return cfg, nil
+}
+
+func collectIface(ifaceUtils interfaces.Ifaces, routeUtils interfaces.Routes, iface net.Interface, redact bool, warn func(error)) (*schema.Interface, error) {
+	dnsFailed := false
+	s, err := snapshot.Take(ifaceUtils, iface.Name, func(err error) {
+		warn(fmt.Errorf("%s: %w", iface.Name, err))
+		dnsFailed = true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entry := schema.Interface{
+		Name:  iface.Name,
+		State: "down",
+		MTU:   iface.MTU,
+		// 读取成功时即使为空也导出，应用时会清空之后出现的地址和 dns
+		Addresses: schema.List{},
+		DNS:       schema.List(s.DNS),
+	}
+	if !dnsFailed && entry.DNS == nil {
+		entry.DNS = schema.List{}
+	}
+	if iface.Flags&net.FlagUp != 0 {
+		entry.State = "up"
+	}
+	if !redact && len(iface.HardwareAddr) > 0 {
+		entry.MAC = iface.HardwareAddr.String()
+	}
+
+	// dhcp、slaac 获取的地址不属于静态配置
+	dynamic, err := ifaceUtils.GetDynamicIPs(iface.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range s.IPs {
+		if !isDynamic(dynamic, addr) {
+			entry.Addresses = append(entry.Addresses, addr)
+		}
+	}
+
+	// schema 中每个地址族只有一个默认网关，取优先级最高的一个
+	families := map[bool]bool{}
+	for _, gw := range s.Gateways {
+		isV4 := net.ParseIP(gw).To4() != nil
+		if families[isV4] {
+			continue
+		}
+		families[isV4] = true
+		entry.Gateways = append(entry.Gateways, schema.Gateway{Via: gw, Metric: s.GatewayMetrics[gw]})
+	}
+
+	if entry.Routes, err = collectRoutes(routeUtils, iface.Name); err != nil {
+		return nil, fmt.Errorf("failed to read routes: %w", err)
+	}
+
+	return &entry, nil
+}
+
+func isDynamic(dynamic []*net.IPNet, addr string) bool {
+	for _, ipnet := range dynamic {
+		if ipnet.String() == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// 只导出 main 表中手动添加的路由，内核、dhcp、路由通告添加的路由和默认路由不导出
+func collectRoutes(routeUtils interfaces.Routes, ifaceName string) ([]schema.Route, error) {
+	routes, err := routeUtils.ListRoutes(interfaces.RouteFilter{Iface: ifaceName, Table: interfaces.TableMain})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []schema.Route
+	for _, r := range routes {
+		if r.Protocol != "static" && r.Protocol != "boot" || r.Type != "unicast" || r.Dst == nil {
+			continue
+		}
+		if ones, _ := r.Dst.Mask.Size(); ones == 0 {
+			continue
+		}
+		route := schema.Route{To: r.Dst.String(), Metric: r.Metric}
+		if r.Gateway != nil {
+			route.Via = r.Gateway.String()
+		}
+		result = append(result, route)
+	}
+	return result, nil
+}
+
+// 系统自带的规则，导出时跳过
+var defaultRules = map[int]int{
+	0:     interfaces.TableLocal,
+	32766: interfaces.TableMain,
+	32767: interfaces.TableDefault,
+}
+
+func isDefaultRule(r interfaces.Rule) bool {
+	table, ok := defaultRules[r.Priority]
+	return ok && r.Action == "lookup" && r.Table == table && !r.Invert &&
+		r.Src == nil && r.Dst == nil && r.Mark == 0 && r.Iif == "" && r.Oif == "" && r.SuppressPrefixlen < 0
+}
+
+// 读取系统自带规则之外的策略路由规则
+func CollectRules(routeUtils interfaces.Routes) ([]schema.Rule, error) {
+	rules, err := routeUtils.ListRules(0)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []schema.Rule
+	for _, r := range rules {
+		if isDefaultRule(r) {
+			continue
+		}
+		entry := schema.Rule{
+			Priority: r.Priority,
+			Iif:      r.Iif,
+			Oif:      r.Oif,
+			Not:      r.Invert,
+		}
+		if r.Src != nil {
+			entry.From = r.Src.String()
+		}
+		if r.Dst != nil {
+			entry.To = r.Dst.String()
+		}
+		// 地址族可以由 from/to 推断时省略
+		if r.Src == nil && r.Dst == nil && r.Family == 6 {
+			entry.Family = 6
+		}
+		if r.Mark != 0 || r.Mask != 0 {
+			entry.Fwmark = fmt.Sprintf("%#x", r.Mark)
+			if r.Mask != 0 && r.Mask != 0xffffffff {
+				entry.Fwmark += fmt.Sprintf("/%#x", r.Mask)
+			}
+		}
+		switch r.Action {
+		case "lookup":
+			if r.Table != interfaces.TableMain {
+				entry.Table = interfaces.TableName(r.Table)
+			}
+		case "goto":
+			entry.Action = r.Action
+			entry.Goto = r.Goto
+		default:
+			entry.Action = r.Action
+		}
+		if r.SuppressPrefixlen >= 0 {
+			entry.SuppressPrefixlength = &r.SuppressPrefixlen
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
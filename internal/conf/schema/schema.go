@@ -0,0 +1,301 @@
+package schema
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"nctl/interfaces"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 配置文件的根节点，对应 config/iface.yml 和 config/net.yml
+type Config struct {
+	Interfaces []Interface `yaml:"interfaces"`
+	// 策略路由规则，只保证存在，不删除列表之外的规则
+	Rules []Rule `yaml:"rules,omitempty"`
+}
+
+// 单个接口的期望状态，未填写的字段表示不管理
+type Interface struct {
+	Name string `yaml:"name"`
+	// up 或 down
+	State string `yaml:"state,omitempty"`
+	MAC   string `yaml:"mac,omitempty"`
+	MTU   int    `yaml:"mtu,omitempty"`
+	// CIDR 格式的静态地址，不含 link-local 地址；写成 [] 表示清空
+	Addresses List `yaml:"addresses,omitempty"`
+	// gateways 的简写，等价于不指定 metric 的一个网关
+	Gateway string `yaml:"gateway,omitempty"`
+	// 每个地址族最多一个默认网关，metric 越小越优先，用于区分主备上行链路
+	Gateways []Gateway `yaml:"gateways,omitempty"`
+	// 按优先级排列，写成 [] 表示清空
+	DNS List `yaml:"dns,omitempty"`
+	// 默认路由之外的静态路由，只保证存在，不删除列表之外的路由
+	Routes []Route `yaml:"routes,omitempty"`
+}
+
+// 区分未填写和空列表的字符串列表：nil 表示不管理，空列表表示清空，写出时为 []
+type List []string
+
+// yaml 的 omitempty 只省略 nil，保留空列表
+func (l List) IsZero() bool {
+	return l == nil
+}
+
+// 接口上的一个默认网关
+type Gateway struct {
+	Via    string `yaml:"via"`
+	Metric int    `yaml:"metric,omitempty"`
+}
+
+// 经由接口的一条静态路由
+type Route struct {
+	// CIDR 格式的目的网段
+	To     string `yaml:"to"`
+	Via    string `yaml:"via,omitempty"`
+	Metric int    `yaml:"metric,omitempty"`
+}
+
+// 一条策略路由规则，字段含义与 ip rule 相同
+type Rule struct {
+	// 必填，同一地址族内唯一
+	Priority int `yaml:"priority"`
+	// 4 或 6，from 和 to 都未填写时使用，默认 4
+	Family int `yaml:"family,omitempty"`
+	// 网段或单个地址，不填表示 all
+	From string `yaml:"from,omitempty"`
+	To   string `yaml:"to,omitempty"`
+	// mark 或 mark/mask
+	Fwmark string `yaml:"fwmark,omitempty"`
+	Iif    string `yaml:"iif,omitempty"`
+	Oif    string `yaml:"oif,omitempty"`
+	// lookup 的路由表，名称或编号，默认 main
+	Table string `yaml:"table,omitempty"`
+	// lookup、goto、nop、blackhole、unreachable、prohibit，默认 lookup
+	Action string `yaml:"action,omitempty"`
+	Goto   int    `yaml:"goto,omitempty"`
+	Not    bool   `yaml:"not,omitempty"`
+	// 忽略前缀长度不超过该值的路由，0 常用于只忽略默认路由
+	SuppressPrefixlength *int `yaml:"suppress_prefixlength,omitempty"`
+}
+
+// 读取并校验配置文件
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// 以 yaml 格式写出配置
+func (c *Config) Write(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	defer enc.Close()
+	return enc.Encode(c)
+}
+
+// 校验配置中的每个字段
+func (c *Config) Validate() error {
+	seen := map[string]bool{}
+	for i, iface := range c.Interfaces {
+		if iface.Name == "" {
+			return fmt.Errorf("interfaces[%d]: name is required", i)
+		}
+		if seen[iface.Name] {
+			return fmt.Errorf("interface %s is defined more than once", iface.Name)
+		}
+		seen[iface.Name] = true
+
+		if iface.State != "" && iface.State != "up" && iface.State != "down" {
+			return fmt.Errorf("interface %s: invalid state '%s' (value: up, down)", iface.Name, iface.State)
+		}
+		if iface.MAC != "" {
+			if _, err := net.ParseMAC(iface.MAC); err != nil {
+				return fmt.Errorf("interface %s: %w", iface.Name, err)
+			}
+		}
+		if iface.MTU < 0 {
+			return fmt.Errorf("interface %s: invalid mtu %d", iface.Name, iface.MTU)
+		}
+		if _, err := iface.IPNets(); err != nil {
+			return fmt.Errorf("interface %s: %w", iface.Name, err)
+		}
+		if err := iface.validateGateways(); err != nil {
+			return fmt.Errorf("interface %s: %w", iface.Name, err)
+		}
+		if _, err := iface.DNSIPs(); err != nil {
+			return fmt.Errorf("interface %s: %w", iface.Name, err)
+		}
+		for _, r := range iface.Routes {
+			if _, _, err := net.ParseCIDR(r.To); err != nil {
+				return fmt.Errorf("interface %s: invalid route destination '%s', CIDR format required", iface.Name, r.To)
+			}
+			if r.Via != "" && net.ParseIP(r.Via) == nil {
+				return fmt.Errorf("interface %s: invalid route gateway '%s'", iface.Name, r.Via)
+			}
+			if r.Metric < 0 {
+				return fmt.Errorf("interface %s: invalid route metric %d", iface.Name, r.Metric)
+			}
+		}
+	}
+
+	priorities := map[[2]int]bool{}
+	for i, r := range c.Rules {
+		rule, err := r.Rule()
+		if err != nil {
+			return fmt.Errorf("rules[%d]: %w", i, err)
+		}
+		key := [2]int{rule.Family, rule.Priority}
+		if priorities[key] {
+			return fmt.Errorf("rules[%d]: priority %d is used more than once", i, r.Priority)
+		}
+		priorities[key] = true
+	}
+	return nil
+}
+
+// 转换为规则，地址族由 from/to 推断
+func (r *Rule) Rule() (*interfaces.Rule, error) {
+	if r.Priority <= 0 {
+		return nil, fmt.Errorf("priority must be a positive number")
+	}
+	rule := &interfaces.Rule{
+		Family:            4,
+		Priority:          r.Priority,
+		Iif:               r.Iif,
+		Oif:               r.Oif,
+		Invert:            r.Not,
+		Action:            r.Action,
+		Goto:              r.Goto,
+		SuppressPrefixlen: -1,
+	}
+	if r.Family != 0 {
+		if r.Family != 4 && r.Family != 6 {
+			return nil, fmt.Errorf("invalid family %d (value: 4, 6)", r.Family)
+		}
+		rule.Family = r.Family
+	}
+
+	var err error
+	if rule.Src, err = interfaces.ParsePrefix(r.From); err != nil {
+		return nil, err
+	}
+	if rule.Dst, err = interfaces.ParsePrefix(r.To); err != nil {
+		return nil, err
+	}
+	for _, ipnet := range []*net.IPNet{rule.Src, rule.Dst} {
+		if ipnet == nil {
+			continue
+		}
+		family := 4
+		if ipnet.IP.To4() == nil {
+			family = 6
+		}
+		if r.Family != 0 && family != r.Family {
+			return nil, fmt.Errorf("address %s does not match family %d", ipnet, r.Family)
+		}
+		rule.Family = family
+	}
+	if rule.Src != nil && rule.Dst != nil && (rule.Src.IP.To4() == nil) != (rule.Dst.IP.To4() == nil) {
+		return nil, fmt.Errorf("from and to must be of the same address family")
+	}
+	if rule.Mark, rule.Mask, err = interfaces.ParseFwmark(r.Fwmark); err != nil {
+		return nil, err
+	}
+
+	switch rule.Action {
+	case "":
+		rule.Action = "lookup"
+	case "lookup", "goto", "nop", "blackhole", "unreachable", "prohibit":
+	default:
+		return nil, fmt.Errorf("invalid action '%s' (value: lookup, goto, nop, blackhole, unreachable, prohibit)", r.Action)
+	}
+	if rule.Action == "lookup" {
+		rule.Table = interfaces.TableMain
+		if r.Table != "" {
+			if rule.Table, err = interfaces.ParseTable(r.Table); err != nil {
+				return nil, err
+			}
+		}
+	} else if r.Table != "" {
+		return nil, fmt.Errorf("table can only be used with the lookup action")
+	}
+	if r.SuppressPrefixlength != nil {
+		if *r.SuppressPrefixlength < 0 || *r.SuppressPrefixlength > 128 {
+			return nil, fmt.Errorf("invalid suppress_prefixlength %d", *r.SuppressPrefixlength)
+		}
+		rule.SuppressPrefixlen = *r.SuppressPrefixlength
+	}
+	if rule.Action == "goto" && r.Goto <= r.Priority {
+		return nil, fmt.Errorf("goto must point to a rule with a higher priority number than %d", r.Priority)
+	}
+	return rule, nil
+}
+
+// 解析 addresses，IPNet.IP 保留主机地址
+func (i *Interface) IPNets() ([]*net.IPNet, error) {
+	var ipnets []*net.IPNet
+	for _, s := range i.Addresses {
+		ip, ipnet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address '%s', CIDR format required", s)
+		}
+		ipnet.IP = ip
+		ipnets = append(ipnets, ipnet)
+	}
+	return ipnets, nil
+}
+
+// 合并 gateway 和 gateways
+func (i *Interface) AllGateways() []Gateway {
+	gws := i.Gateways
+	if i.Gateway != "" {
+		gws = append([]Gateway{{Via: i.Gateway}}, gws...)
+	}
+	return gws
+}
+
+func (i *Interface) validateGateways() error {
+	families := map[bool]bool{}
+	for _, gw := range i.AllGateways() {
+		ip := net.ParseIP(gw.Via)
+		if ip == nil {
+			return fmt.Errorf("invalid gateway '%s'", gw.Via)
+		}
+		if gw.Metric < 0 {
+			return fmt.Errorf("invalid gateway metric %d", gw.Metric)
+		}
+		isV4 := ip.To4() != nil
+		if families[isV4] {
+			return fmt.Errorf("more than one default gateway of the same address family")
+		}
+		families[isV4] = true
+	}
+	return nil
+}
+
+// 解析 dns
+func (i *Interface) DNSIPs() ([]net.IP, error) {
+	var ips []net.IP
+	for _, s := range i.DNS {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid DNS address '%s'", s)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
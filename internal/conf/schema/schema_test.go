@@ -0,0 +1,36 @@
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestListKeepsEmptyAndUnmanagedApart(t *testing.T) {
+	cfg := &Config{Interfaces: []Interface{
+		{Name: "eth0", Addresses: List{}, DNS: List{}},
+		{Name: "eth1"},
+	}}
+	var buf bytes.Buffer
+	if err := cfg.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Count(out, "addresses: []") != 1 || strings.Count(out, "dns: []") != 1 {
+		t.Fatalf("empty lists were not written as []:\n%s", out)
+	}
+
+	var back Config
+	if err := yaml.Unmarshal(buf.Bytes(), &back); err != nil {
+		t.Fatal(err)
+	}
+	eth0, eth1 := back.Interfaces[0], back.Interfaces[1]
+	if eth0.Addresses == nil || len(eth0.Addresses) != 0 || eth0.DNS == nil || len(eth0.DNS) != 0 {
+		t.Fatalf("eth0 = %+v, want empty managed lists", eth0)
+	}
+	if eth1.Addresses != nil || eth1.DNS != nil {
+		t.Fatalf("eth1 = %+v, want unmanaged lists", eth1)
+	}
+}
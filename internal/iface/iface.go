@@ -3,7 +3,9 @@ package iface
 import (
 	"nctl/internal/iface/list"
 	"nctl/internal/iface/set"
+	"nctl/internal/iface/stats"
 	"nctl/internal/iface/status"
+	"nctl/internal/iface/watch"
 
 	"github.com/spf13/cobra"
 )
@@ -26,4 +28,10 @@ func RegisterIfaceCommands(rootCmd *cobra.Command) {
 	// 挂载 iface status 系列命令
 	ifaceCmd.AddCommand(status.Status())
 	ifaceCmd.AddCommand(set.SetC())
+	// 挂载 confirm 命令，用于确认 iface set 的事务
+	rootCmd.AddCommand(set.ConfirmC())
+	// 挂载 iface stats 命令
+	ifaceCmd.AddCommand(stats.Stats())
+	// 挂载 iface watch 命令
+	ifaceCmd.AddCommand(watch.Watch())
 }
@@ -8,7 +8,9 @@ import (
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
 
+	"nctl/interfaces"
 	"nctl/internal/utils"
+	"nctl/internal/utils/output"
 )
 
 // InterfaceInfo 存储网络接口的所有相关信息。
@@ -22,6 +24,26 @@ type InterfaceInfo struct {
 	BroadcastIPv4      []net.IP
 	DefaultGatewayIPv4 string
 	DefaultGatewayIPv6 string
+	DefaultRoutes      []DefaultRoute
+	OperState          string
+	Carrier            bool
+	LinkType           string
+	Master             string
+	Driver             string
+	DriverVersion      string
+	Firmware           string
+	Speed              int
+	Duplex             string
+	TxQueueLen         int
+	Stats              interfaces.LinkStats
+}
+
+// 接口上的一条默认路由，Preferred 表示它是该地址族实际使用的默认路由
+type DefaultRoute struct {
+	Family    int
+	Gateway   net.IP
+	Metric    int
+	Preferred bool
 }
 
 func List() *cobra.Command {
@@ -31,6 +53,11 @@ func List() *cobra.Command {
 		Args:  cobra.ArbitraryArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			setAll, _ := cmd.Flags().GetBool("all")
+			format, err := output.Format(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return
+			}
 
 			var targetInterfaces map[string]bool
 			if len(args) > 0 {
@@ -53,6 +80,13 @@ func List() *cobra.Command {
 				return
 			}
 
+			if format != output.Table {
+				if err := printStructured(cmd, infos, format); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "输出结果时出错: %v\n", err)
+				}
+				return
+			}
+
 			printResults(cmd, infos, setAll)
 		},
 	}
@@ -62,16 +96,90 @@ func List() *cobra.Command {
 	return cmd
 }
 
-func getInterfaceInfo(iface net.Interface) (*InterfaceInfo, error) {
-	ip4gw, ip6gw := utils.GetGW(&iface)
+// 读取主路由表中的默认路由，并标记每个地址族中 metric 最小且接口处于 up 状态的一条
+// windows 上实际比较的是路由 metric 与接口 metric 之和，这里只比较路由 metric
+func loadDefaultRoutes(allInterfaces []net.Interface) (map[string][]DefaultRoute, error) {
+	routes, err := utils.RouteUtils().ListRoutes(interfaces.RouteFilter{Table: interfaces.TableMain})
+	if err != nil {
+		return nil, err
+	}
+
+	up := map[string]bool{}
+	for _, iface := range allInterfaces {
+		up[iface.Name] = iface.Flags&net.FlagUp != 0
+	}
+	return defaultRoutes(routes, up), nil
+}
 
+// 按接口分组默认路由，并标记每个地址族中启用的接口上 metric 最小的一条
+func defaultRoutes(routes []interfaces.Route, up map[string]bool) map[string][]DefaultRoute {
+	// 每个地址族中首选的默认路由所在的接口和下标
+	type routeRef struct {
+		iface  string
+		index  int
+		metric int
+	}
+	result := map[string][]DefaultRoute{}
+	preferred := map[int]routeRef{}
+	for _, r := range routes {
+		if ones, _ := r.Dst.Mask.Size(); ones != 0 || r.Type != "unicast" || r.Iface == "" {
+			continue
+		}
+		result[r.Iface] = append(result[r.Iface], DefaultRoute{Family: r.Family, Gateway: r.Gateway, Metric: r.Metric})
+		// 按系统返回的顺序比较，metric 相同时与内核一样取先出现的一条
+		if !up[r.Iface] {
+			continue
+		}
+		if best, ok := preferred[r.Family]; !ok || r.Metric < best.metric {
+			preferred[r.Family] = routeRef{r.Iface, len(result[r.Iface]) - 1, r.Metric}
+		}
+	}
+	for _, best := range preferred {
+		result[best.iface][best.index].Preferred = true
+	}
+	return result
+}
+
+func getInterfaceInfo(iface net.Interface, defaults map[string][]DefaultRoute) (*InterfaceInfo, error) {
 	info := &InterfaceInfo{
 		Name:               iface.Name,
 		MACAddress:         iface.HardwareAddr,
 		MTU:                iface.MTU,
 		Flags:              iface.Flags,
-		DefaultGatewayIPv4: ip4gw,
-		DefaultGatewayIPv6: ip6gw,
+		DefaultGatewayIPv4: "N/A",
+		DefaultGatewayIPv6: "N/A",
+	}
+
+	// 路由表读取失败时按子网匹配系统默认网关
+	if defaults == nil {
+		info.DefaultGatewayIPv4, info.DefaultGatewayIPv6 = utils.GetGW(&iface)
+	}
+	info.DefaultRoutes = defaults[iface.Name]
+	for _, d := range info.DefaultRoutes {
+		if d.Gateway == nil {
+			continue
+		}
+		if d.Family == 4 && (info.DefaultGatewayIPv4 == "N/A" || d.Preferred) {
+			info.DefaultGatewayIPv4 = d.Gateway.String()
+		}
+		if d.Family == 6 && (info.DefaultGatewayIPv6 == "N/A" || d.Preferred) {
+			info.DefaultGatewayIPv6 = d.Gateway.String()
+		}
+	}
+
+	// 链路详情获取失败时保留 net.Interfaces() 提供的基本信息
+	if d, err := utils.IfaceUtils().GetLinkDetails(iface.Name); err == nil {
+		info.OperState = d.OperState
+		info.Carrier = d.Carrier
+		info.LinkType = d.Type
+		info.Master = d.Master
+		info.Driver = d.Driver
+		info.DriverVersion = d.DriverVersion
+		info.Firmware = d.Firmware
+		info.Speed = d.Speed
+		info.Duplex = d.Duplex
+		info.TxQueueLen = d.TxQueueLen
+		info.Stats = d.Stats
 	}
 
 	if iface.Flags&net.FlagUp != 0 {
@@ -107,13 +215,27 @@ func getInterfaceInfo(iface net.Interface) (*InterfaceInfo, error) {
 	return info, nil
 }
 
+// 读取所有接口的信息，供 net status 等命令复用，单个接口的错误输出到 cmd 的标准错误
+func Collect(cmd *cobra.Command) ([]InterfaceInfo, error) {
+	allInterfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	return processInterfaces(cmd, allInterfaces, nil), nil
+}
+
 func processInterfaces(cmd *cobra.Command, allInterfaces []net.Interface, targetNames map[string]bool) []InterfaceInfo {
+	defaults, err := loadDefaultRoutes(allInterfaces)
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "读取默认路由时出错: %v\n", err)
+	}
+
 	var infos []InterfaceInfo
 	for _, iface := range allInterfaces {
 		if targetNames != nil && !targetNames[iface.Name] {
 			continue
 		}
-		info, err := getInterfaceInfo(iface)
+		info, err := getInterfaceInfo(iface, defaults)
 		if err != nil {
 			fmt.Fprintf(cmd.ErrOrStderr(), "处理接口 %s 信息时出错: %v\n", iface.Name, err)
 			continue
@@ -211,6 +333,54 @@ func printDetailed(cmd *cobra.Command, infos []InterfaceInfo) {
 		}
 	}
 	t.Render()
+
+	printLinkDetails(cmd, infos)
+}
+
+// 输出链路层详情和收发计数
+func printLinkDetails(cmd *cobra.Command, infos []InterfaceInfo) {
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.AppendHeader(table.Row{
+		"INTERFACE", "OPERSTATE", "CARRIER", "TYPE", "MASTER", "DRIVER", "FIRMWARE", "SPEED", "TXQLEN",
+		"RX BYTES", "RX PKTS", "RX ERR/DROP", "TX BYTES", "TX PKTS", "TX ERR/DROP",
+	})
+
+	for _, info := range infos {
+		t.AppendRow([]interface{}{
+			info.Name,
+			orNA(info.OperState),
+			info.Carrier,
+			orNA(info.LinkType),
+			orNA(info.Master),
+			orNA(strings.TrimSpace(info.Driver + " " + info.DriverVersion)),
+			orNA(info.Firmware),
+			formatSpeed(info.Speed, info.Duplex),
+			info.TxQueueLen,
+			info.Stats.RxBytes,
+			info.Stats.RxPackets,
+			fmt.Sprintf("%d/%d", info.Stats.RxErrors, info.Stats.RxDropped),
+			info.Stats.TxBytes,
+			info.Stats.TxPackets,
+			fmt.Sprintf("%d/%d", info.Stats.TxErrors, info.Stats.TxDropped),
+		})
+	}
+	t.Render()
+}
+
+func orNA(s string) string {
+	if s == "" {
+		return "N/A"
+	}
+	return s
+}
+
+// 速率和双工模式，例如 1000Mb/s full
+func formatSpeed(speed int, duplex string) string {
+	if speed == 0 {
+		return "N/A"
+	}
+	return strings.TrimSpace(fmt.Sprintf("%dMb/s %s", speed, duplex))
 }
 
 func toStringSlice[T fmt.Stringer](list []T) []string {
@@ -221,7 +391,28 @@ func toStringSlice[T fmt.Stringer](list []T) []string {
 	return result
 }
 
+// 默认路由的描述，例如 192.168.1.1 metric 100 (preferred)
+func describeDefault(d DefaultRoute) string {
+	desc := "dev only"
+	if d.Gateway != nil {
+		desc = d.Gateway.String()
+	}
+	desc += fmt.Sprintf(" metric %d", d.Metric)
+	if d.Preferred {
+		desc += " (preferred)"
+	}
+	return desc
+}
+
 func gatherGateways(info InterfaceInfo) []string {
+	if len(info.DefaultRoutes) > 0 {
+		var gw []string
+		for _, d := range info.DefaultRoutes {
+			gw = append(gw, describeDefault(d))
+		}
+		return gw
+	}
+
 	var gw []string
 	if info.DefaultGatewayIPv4 != "N/A" {
 		gw = append(gw, info.DefaultGatewayIPv4)
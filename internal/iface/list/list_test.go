@@ -0,0 +1,34 @@
+package list
+
+import (
+	"net"
+	"testing"
+
+	"nctl/interfaces"
+)
+
+func defaultRoute(iface string, gw string, metric int) interfaces.Route {
+	_, dst, _ := net.ParseCIDR("0.0.0.0/0")
+	return interfaces.Route{Family: 4, Dst: dst, Gateway: net.ParseIP(gw), Iface: iface, Metric: metric, Type: "unicast"}
+}
+
+func TestDefaultRoutesPreferred(t *testing.T) {
+	routes := []interfaces.Route{
+		defaultRoute("eth2", "192.0.2.1", 100),
+		defaultRoute("eth0", "198.51.100.1", 100),
+		defaultRoute("eth1", "203.0.113.1", 50),
+		defaultRoute("eth0", "198.51.100.2", 200),
+	}
+	up := map[string]bool{"eth0": true, "eth2": true}
+
+	// 多次运行结果相同：eth1 未启用，eth2 和 eth0 的 metric 相同时取先出现的 eth2
+	for range 20 {
+		result := defaultRoutes(routes, up)
+		if len(result["eth0"]) != 2 || len(result["eth1"]) != 1 || len(result["eth2"]) != 1 {
+			t.Fatalf("result = %v", result)
+		}
+		if !result["eth2"][0].Preferred || result["eth0"][0].Preferred || result["eth0"][1].Preferred || result["eth1"][0].Preferred {
+			t.Fatalf("result = %v, want only eth2 preferred", result)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package list
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"nctl/internal/utils/output"
+)
+
+// 结构化输出中的接口信息，字段名保持稳定
+type interfaceRecord struct {
+	Name          string          `json:"name" yaml:"name"`
+	Status        string          `json:"status" yaml:"status"`
+	MACAddress    string          `json:"mac_address" yaml:"mac_address"`
+	MTU           int             `json:"mtu" yaml:"mtu"`
+	Flags         []string        `json:"flags" yaml:"flags"`
+	Addresses     []addressRecord `json:"addresses" yaml:"addresses"`
+	Broadcast     []string        `json:"broadcast_ipv4" yaml:"broadcast_ipv4"`
+	GatewayIPv4   *string         `json:"gateway_ipv4" yaml:"gateway_ipv4"`
+	GatewayIPv6   *string         `json:"gateway_ipv6" yaml:"gateway_ipv6"`
+	DefaultRoutes []defaultRecord `json:"default_routes" yaml:"default_routes"`
+	OperState     *string         `json:"oper_state" yaml:"oper_state"`
+	Carrier       bool            `json:"carrier" yaml:"carrier"`
+	LinkType      *string         `json:"link_type" yaml:"link_type"`
+	Master        *string         `json:"master" yaml:"master"`
+	Driver        *string         `json:"driver" yaml:"driver"`
+	DriverVersion *string         `json:"driver_version" yaml:"driver_version"`
+	Firmware      *string         `json:"firmware" yaml:"firmware"`
+	SpeedMbps     *int            `json:"speed_mbps" yaml:"speed_mbps"`
+	Duplex        *string         `json:"duplex" yaml:"duplex"`
+	TxQueueLen    int             `json:"tx_queue_len" yaml:"tx_queue_len"`
+	Statistics    statsRecord     `json:"statistics" yaml:"statistics"`
+}
+
+type statsRecord struct {
+	RxBytes   uint64 `json:"rx_bytes" yaml:"rx_bytes"`
+	RxPackets uint64 `json:"rx_packets" yaml:"rx_packets"`
+	RxErrors  uint64 `json:"rx_errors" yaml:"rx_errors"`
+	RxDropped uint64 `json:"rx_dropped" yaml:"rx_dropped"`
+	TxBytes   uint64 `json:"tx_bytes" yaml:"tx_bytes"`
+	TxPackets uint64 `json:"tx_packets" yaml:"tx_packets"`
+	TxErrors  uint64 `json:"tx_errors" yaml:"tx_errors"`
+	TxDropped uint64 `json:"tx_dropped" yaml:"tx_dropped"`
+}
+
+type defaultRecord struct {
+	Family    string  `json:"family" yaml:"family"`
+	Gateway   *string `json:"gateway" yaml:"gateway"`
+	Metric    int     `json:"metric" yaml:"metric"`
+	Preferred bool    `json:"preferred" yaml:"preferred"`
+}
+
+type addressRecord struct {
+	Address   string `json:"address" yaml:"address"`
+	PrefixLen int    `json:"prefix_len" yaml:"prefix_len"`
+	Family    string `json:"family" yaml:"family"`
+}
+
+// "N/A" 转换为 nil
+func nullable(s string) *string {
+	if s == "N/A" || s == "" {
+		return nil
+	}
+	return &s
+}
+
+// nil 转换为空字符串，用于 csv
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func toRecord(info InterfaceInfo) interfaceRecord {
+	r := interfaceRecord{
+		Name:          info.Name,
+		Status:        info.Status,
+		MACAddress:    info.MACAddress.String(),
+		MTU:           info.MTU,
+		Flags:         []string{},
+		Addresses:     []addressRecord{},
+		Broadcast:     []string{},
+		GatewayIPv4:   nullable(info.DefaultGatewayIPv4),
+		GatewayIPv6:   nullable(info.DefaultGatewayIPv6),
+		DefaultRoutes: []defaultRecord{},
+		OperState:     nullable(info.OperState),
+		Carrier:       info.Carrier,
+		LinkType:      nullable(info.LinkType),
+		Master:        nullable(info.Master),
+		Driver:        nullable(info.Driver),
+		DriverVersion: nullable(info.DriverVersion),
+		Firmware:      nullable(info.Firmware),
+		Duplex:        nullable(info.Duplex),
+		TxQueueLen:    info.TxQueueLen,
+		Statistics:    statsRecord(info.Stats),
+	}
+	if info.Speed != 0 {
+		speed := info.Speed
+		r.SpeedMbps = &speed
+	}
+
+	if info.Flags != 0 {
+		r.Flags = strings.Split(info.Flags.String(), "|")
+	}
+	for _, ipNet := range info.IPAddresses {
+		ones, _ := ipNet.Mask.Size()
+		family := "inet6"
+		if ipNet.IP.To4() != nil {
+			family = "inet"
+		}
+		r.Addresses = append(r.Addresses, addressRecord{
+			Address:   ipNet.IP.String(),
+			PrefixLen: ones,
+			Family:    family,
+		})
+	}
+	r.Broadcast = append(r.Broadcast, toStringSlice(info.BroadcastIPv4)...)
+	for _, d := range info.DefaultRoutes {
+		var gw *string
+		if d.Gateway != nil {
+			gw = nullable(d.Gateway.String())
+		}
+		r.DefaultRoutes = append(r.DefaultRoutes, defaultRecord{
+			Family:    "ipv" + strconv.Itoa(d.Family),
+			Gateway:   gw,
+			Metric:    d.Metric,
+			Preferred: d.Preferred,
+		})
+	}
+	return r
+}
+
+func printStructured(cmd *cobra.Command, infos []InterfaceInfo, format string) error {
+	records := make([]interfaceRecord, 0, len(infos))
+	for _, info := range infos {
+		records = append(records, toRecord(info))
+	}
+
+	if format != output.CSV {
+		return output.Write(cmd.OutOrStdout(), format, records)
+	}
+
+	header := []string{"name", "status", "mac_address", "mtu", "flags", "addresses", "broadcast_ipv4", "gateway_ipv4", "gateway_ipv6", "default_routes",
+		"oper_state", "carrier", "link_type", "master", "driver", "driver_version", "firmware", "speed_mbps", "duplex", "tx_queue_len",
+		"rx_bytes", "rx_packets", "rx_errors", "rx_dropped", "tx_bytes", "tx_packets", "tx_errors", "tx_dropped"}
+	var rows [][]string
+	for _, r := range records {
+		var addrs []string
+		for _, a := range r.Addresses {
+			addrs = append(addrs, fmt.Sprintf("%s/%d", a.Address, a.PrefixLen))
+		}
+		gw4, gw6 := deref(r.GatewayIPv4), deref(r.GatewayIPv6)
+		var defaults []string
+		for _, d := range r.DefaultRoutes {
+			desc := fmt.Sprintf("%s metric %d", deref(d.Gateway), d.Metric)
+			if d.Preferred {
+				desc += " preferred"
+			}
+			defaults = append(defaults, desc)
+		}
+		speed := ""
+		if r.SpeedMbps != nil {
+			speed = strconv.Itoa(*r.SpeedMbps)
+		}
+		rows = append(rows, []string{
+			r.Name,
+			r.Status,
+			r.MACAddress,
+			strconv.Itoa(r.MTU),
+			strings.Join(r.Flags, "|"),
+			strings.Join(addrs, ";"),
+			strings.Join(r.Broadcast, ";"),
+			gw4,
+			gw6,
+			strings.Join(defaults, ";"),
+			deref(r.OperState),
+			strconv.FormatBool(r.Carrier),
+			deref(r.LinkType),
+			deref(r.Master),
+			deref(r.Driver),
+			deref(r.DriverVersion),
+			deref(r.Firmware),
+			speed,
+			deref(r.Duplex),
+			strconv.Itoa(r.TxQueueLen),
+			strconv.FormatUint(r.Statistics.RxBytes, 10),
+			strconv.FormatUint(r.Statistics.RxPackets, 10),
+			strconv.FormatUint(r.Statistics.RxErrors, 10),
+			strconv.FormatUint(r.Statistics.RxDropped, 10),
+			strconv.FormatUint(r.Statistics.TxBytes, 10),
+			strconv.FormatUint(r.Statistics.TxPackets, 10),
+			strconv.FormatUint(r.Statistics.TxErrors, 10),
+			strconv.FormatUint(r.Statistics.TxDropped, 10),
+		})
+	}
+	return output.WriteCSV(cmd.OutOrStdout(), header, rows)
+}
@@ -0,0 +1,114 @@
+package set
+
+import (
+	"context"
+	"fmt"
+	"nctl/internal/utils"
+	"nctl/internal/utils/snapshot"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	setConfirmTimeout time.Duration
+	confirmRollback   bool
+)
+
+// 确认 iface set --confirm-timeout 应用的改动
+func ConfirmC() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "confirm [interface_name...]",
+		Short: "Keep changes made with 'iface set --confirm-timeout' instead of rolling them back",
+		Long: "Keep changes made with 'iface set --confirm-timeout' instead of rolling them back.\n\n" +
+			"With --rollback the changes are rolled back now. This also restores the saved state when\n" +
+			"the process that applied the changes exited before rolling them back.",
+		Args: cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			names := args
+			if len(names) == 0 {
+				pending, err := snapshot.Pending()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to list pending changes: %v\n", err)
+					return
+				}
+				if len(pending) == 0 {
+					fmt.Println("No changes are waiting for confirmation")
+					return
+				}
+				names = pending
+			}
+
+			for _, name := range names {
+				if confirmRollback {
+					rollbackPending(name)
+					continue
+				}
+				if err := snapshot.Confirm(name); err != nil {
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+					continue
+				}
+				fmt.Printf("Confirmed changes on %s\n", name)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&confirmRollback, "rollback", false, "Roll the pending changes back instead of keeping them")
+
+	return cmd
+}
+
+func rollbackPending(name string) {
+	restored, err := snapshot.RequestRollback(utils.IfaceUtils(), name, printStep)
+	switch {
+	case err != nil && !restored:
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Rollback of %s finished with errors\n", name)
+	case restored:
+		fmt.Printf("Restored the saved state of %s\n", name)
+	default:
+		fmt.Printf("Asked the waiting process to roll back %s\n", name)
+	}
+}
+
+// 开始事务，失败时返回 nil
+func beginTransaction(ifaceName string) *snapshot.Transaction {
+	tx, err := snapshot.Begin(utils.IfaceUtils(), ifaceName, setConfirmTimeout, printWarning)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to snapshot %s: %v\n", ifaceName, err)
+		return nil
+	}
+	fmt.Printf("Saved snapshot of %s before applying changes\n", ifaceName)
+	return tx
+}
+
+// 等待确认，超时或中断时回滚；applyErr 不为 nil 时说明改动没有全部成功，直接回滚
+func finishTransaction(tx *snapshot.Transaction, applyErr error) {
+	if applyErr != nil {
+		fmt.Printf("Applying changes failed (%v), rolling back %s...\n", applyErr, tx.Snapshot.Iface)
+		if err := tx.Rollback(utils.IfaceUtils(), printStep); err != nil {
+			fmt.Fprintf(os.Stderr, "Rollback finished with errors\n")
+		}
+		return
+	}
+
+	// 远程会话断开时仍需要完成回滚
+	signal.Ignore(syscall.SIGHUP)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("Run 'nctl confirm %s' within %s to keep these changes, otherwise they will be rolled back\n", tx.Snapshot.Iface, setConfirmTimeout)
+	if tx.Wait(ctx, setConfirmTimeout) {
+		fmt.Printf("Changes on %s confirmed\n", tx.Snapshot.Iface)
+		return
+	}
+
+	fmt.Printf("No confirmation received or rollback requested, rolling back %s...\n", tx.Snapshot.Iface)
+	if err := tx.Rollback(utils.IfaceUtils(), printStep); err != nil {
+		fmt.Fprintf(os.Stderr, "Rollback finished with errors\n")
+	}
+}
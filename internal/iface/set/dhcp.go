@@ -0,0 +1,220 @@
+package set
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"nctl/interfaces"
+	"nctl/internal/utils"
+	"nctl/internal/utils/dhcp"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func leasePath(ifaceName string) string {
+	return filepath.Join(utils.StateDir(), "dhcp", ifaceName+".json")
+}
+
+func saveLease(l *dhcp.Lease) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(leasePath(l.Iface)), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(leasePath(l.Iface), data, 0o644)
+}
+
+// 读取接口的租约，不存在时返回 nil
+func loadLease(ifaceName string) (*dhcp.Lease, error) {
+	data, err := os.ReadFile(leasePath(ifaceName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var l dhcp.Lease
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("invalid lease %s: %w", leasePath(ifaceName), err)
+	}
+	return &l, nil
+}
+
+// 处理 --mode，需要在 ip 相关设置之前执行
+func planMode(p *plan, ifaceUtils interfaces.Ifaces, cmd *cobra.Command) error {
+	if !cmd.Flags().Changed("mode") {
+		return nil
+	}
+
+	switch setMode {
+	case "dhcp":
+		p.add("Requesting DHCP lease", func() error { return runDHCP(ifaceUtils, p.iface) })
+	case "ip":
+		return planRelease(p, ifaceUtils)
+	case "local":
+		if err := planRelease(p, ifaceUtils); err != nil {
+			return err
+		}
+		p.add("Flushing IP addresses", func() error { return ifaceUtils.FlushIPs(p.iface) })
+		p.add("Flushing static routes", func() error { return ifaceUtils.FlushRoutes(p.iface) })
+	default:
+		return fmt.Errorf("invalid mode '%s' (value: dhcp, local, ip)", setMode)
+	}
+	return nil
+}
+
+// 获取租约并应用到接口上，租约由系统 dhcp 客户端维护时只启用 dhcp
+func runDHCP(ifaceUtils interfaces.Ifaces, ifaceName string) error {
+	lease, err := ifaceUtils.ObtainDHCP(ifaceName)
+	if err != nil || lease == nil {
+		return err
+	}
+	printLease(lease)
+	if err := applyLease(ifaceUtils, lease); err != nil {
+		return err
+	}
+
+	if !setKeep {
+		return nil
+	}
+
+	client, err := dhcp.NewClient(ifaceName)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	renewLoop(ctx, ifaceUtils, client, lease)
+	return nil
+}
+
+func printLease(l *dhcp.Lease) {
+	fmt.Printf("  address: %s\n", l.IPNet().String())
+	fmt.Printf("  routers: %v\n", l.Routers)
+	fmt.Printf("  dns:     %v\n", l.DNS)
+	fmt.Printf("  server:  %s\n", l.Server)
+	fmt.Printf("  lease:   %s (renew at %s)\n", l.LeaseTime, l.Obtained.Add(l.T1).Format(time.RFC3339))
+}
+
+// 通过 Ifaces 应用租约并保存，遇到失败的步骤时停止并返回错误
+func applyLease(ifaceUtils interfaces.Ifaces, l *dhcp.Lease) error {
+	steps := []planStep{{"Applying address " + l.IPNet().String(), func() error {
+		return ifaceUtils.SetIPs(l.Iface, []*net.IPNet{l.IPNet()})
+	}}}
+	if len(l.Routers) > 0 {
+		steps = append(steps, planStep{"Applying gateway " + l.Routers[0].String(), func() error {
+			return ifaceUtils.SetGateway(l.Iface, l.Routers[0], 0)
+		}})
+	}
+	if len(l.DNS) > 0 {
+		steps = append(steps, planStep{"Applying DNS servers", func() error { return ifaceUtils.SetDNSs(l.Iface, l.DNS) }})
+	}
+	steps = append(steps, planStep{"Saving lease to " + leasePath(l.Iface), func() error { return saveLease(l) }})
+
+	for _, st := range steps {
+		err := st.apply()
+		printStep(st.desc, err)
+		if err != nil {
+			return fmt.Errorf("%s: %w", st.desc, err)
+		}
+	}
+	return nil
+}
+
+// 在前台按 T1/T2 续约，直到收到中断信号
+func renewLoop(ctx context.Context, ifaceUtils interfaces.Ifaces, client *dhcp.Client, lease *dhcp.Lease) {
+	fmt.Println("Keeping the lease renewed, press Ctrl+C to stop")
+	for {
+		var next *dhcp.Lease
+		var err error
+
+		now := time.Now()
+		switch {
+		case now.Before(lease.Obtained.Add(lease.T1)):
+			if !sleepUntil(ctx, lease.Obtained.Add(lease.T1)) {
+				return
+			}
+			continue
+		case now.Before(lease.Obtained.Add(lease.T2)):
+			next, err = client.Renew(lease, false)
+			printStep("Renewing lease", err)
+			if err != nil && !sleepUntil(ctx, minTime(now.Add(time.Minute), lease.Obtained.Add(lease.T2))) {
+				return
+			}
+		case now.Before(lease.Expiry()):
+			next, err = client.Renew(lease, true)
+			printStep("Rebinding lease", err)
+			if err != nil && !sleepUntil(ctx, minTime(now.Add(time.Minute), lease.Expiry())) {
+				return
+			}
+		default:
+			// 租约已过期，重新走完整流程
+			next, err = client.Obtain()
+			printStep("Requesting new DHCP lease", err)
+			if err != nil && !sleepUntil(ctx, now.Add(time.Minute)) {
+				return
+			}
+		}
+
+		if next == nil {
+			continue
+		}
+		if next.SameConfig(lease) {
+			printStep("Saving lease to "+leasePath(next.Iface), saveLease(next))
+		} else {
+			printLease(next)
+			// 失败的步骤已经输出，继续按新的租约续约
+			applyLease(ifaceUtils, next)
+		}
+		lease = next
+	}
+}
+
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// 释放保存的租约并删除租约中的地址
+func planRelease(p *plan, ifaceUtils interfaces.Ifaces) error {
+	lease, err := loadLease(p.iface)
+	if err != nil {
+		return fmt.Errorf("failed to load DHCP lease: %w", err)
+	}
+	if lease == nil {
+		return nil
+	}
+
+	p.add("Releasing DHCP lease "+lease.IP.String(), func() error {
+		client, err := dhcp.NewClient(p.iface)
+		if err != nil {
+			return err
+		}
+		return client.Release(lease)
+	})
+	p.add("Removing leased address "+lease.IPNet().String(), func() error { return ifaceUtils.DelIP(p.iface, lease.IPNet()) })
+	p.add("Removing lease file "+leasePath(p.iface), func() error { return os.Remove(leasePath(p.iface)) })
+	return nil
+}
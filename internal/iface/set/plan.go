@@ -0,0 +1,46 @@
+package set
+
+import (
+	"fmt"
+	"io"
+)
+
+// iface set 的执行计划，先根据当前状态计算出所有改动，再统一执行
+type plan struct {
+	iface string
+	steps []planStep
+}
+
+type planStep struct {
+	desc  string
+	apply func() error
+}
+
+func (p *plan) add(desc string, apply func() error) {
+	p.steps = append(p.steps, planStep{desc: desc, apply: apply})
+}
+
+// 输出计划，用于 --dry-run
+func (p *plan) print(w io.Writer) {
+	if len(p.steps) == 0 {
+		fmt.Fprintf(w, "No changes for %s\n", p.iface)
+		return
+	}
+	fmt.Fprintf(w, "Plan for %s:\n", p.iface)
+	for i, s := range p.steps {
+		fmt.Fprintf(w, "  %d. %s\n", i+1, s.desc)
+	}
+}
+
+// 依次执行计划中的每一步并报告结果，返回第一个失败的步骤
+func (p *plan) run() error {
+	var firstErr error
+	for _, s := range p.steps {
+		err := s.apply()
+		printStep(s.desc, err)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", s.desc, err)
+		}
+	}
+	return firstErr
+}
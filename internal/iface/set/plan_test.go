@@ -0,0 +1,211 @@
+package set
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nctl/interfaces"
+	"nctl/internal/utils/dhcp"
+	"nctl/internal/utils/fake"
+)
+
+func mustCIDR(t *testing.T, s string) *net.IPNet {
+	t.Helper()
+	ip, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipnet.IP = ip
+	return ipnet
+}
+
+// 用给定的参数生成计划，SetC 注册参数时会把全局变量恢复为默认值
+func planFor(t *testing.T, f *fake.Host, args ...string) *plan {
+	t.Helper()
+	cmd := SetC()
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+	p, err := buildPlan(f, "eth0", cmd)
+	if err != nil {
+		t.Fatalf("buildPlan(%v): %v", args, err)
+	}
+	if len(f.Calls) != 0 {
+		t.Fatalf("planning changed the interface: %v", f.Calls)
+	}
+	return p
+}
+
+func descs(p *plan) []string {
+	var out []string
+	for _, s := range p.steps {
+		out = append(out, s.desc)
+	}
+	return out
+}
+
+func runPlan(p *plan) {
+	for _, s := range p.steps {
+		s.apply()
+	}
+}
+
+func TestPlanReplacesOnlyChangedAddresses(t *testing.T) {
+	f := &fake.Host{IPs: []*net.IPNet{mustCIDR(t, "10.0.0.1/24"), mustCIDR(t, "10.0.0.2/24")}}
+	p := planFor(t, f, "--ip", "10.0.0.2/24,10.0.0.3/24")
+
+	want := []string{"Removing IP 10.0.0.1/24", "Adding IP 10.0.0.3/24"}
+	if got := descs(p); !reflect.DeepEqual(got, want) {
+		t.Fatalf("steps = %v, want %v", got, want)
+	}
+	runPlan(p)
+	wantCalls := []string{"DelIP eth0 10.0.0.1/24", "AddIP eth0 10.0.0.3/24"}
+	if !reflect.DeepEqual(f.Calls, wantCalls) {
+		t.Fatalf("calls = %v, want %v", f.Calls, wantCalls)
+	}
+}
+
+func TestPlanAddSkipsExistingAddress(t *testing.T) {
+	f := &fake.Host{IPs: []*net.IPNet{mustCIDR(t, "10.0.0.1/24")}}
+	p := planFor(t, f, "--add", "--ip", "10.0.0.1/24")
+	if len(p.steps) != 0 {
+		t.Fatalf("steps = %v, want none", descs(p))
+	}
+}
+
+func TestPlanGateway(t *testing.T) {
+	gw := []interfaces.Gateway{{IP: net.ParseIP("192.0.2.1"), Metric: 100}}
+
+	// 网关相同且未指定 metric 时没有改动
+	p := planFor(t, &fake.Host{Gateways: gw}, "--gw", "192.0.2.1")
+	if len(p.steps) != 0 {
+		t.Fatalf("steps = %v, want none", descs(p))
+	}
+
+	f := &fake.Host{Gateways: gw}
+	p = planFor(t, f, "--gw", "192.0.2.254", "--gw-metric", "200")
+	want := []string{"Setting default gateway 192.0.2.254 metric 200 on eth0 (replaces via 192.0.2.1 metric 100)"}
+	if got := descs(p); !reflect.DeepEqual(got, want) {
+		t.Fatalf("steps = %v, want %v", got, want)
+	}
+	runPlan(p)
+	if want := []string{"SetGateway eth0 192.0.2.254 200"}; !reflect.DeepEqual(f.Calls, want) {
+		t.Fatalf("calls = %v, want %v", f.Calls, want)
+	}
+
+	// 只有 ipv4 网关时，设置 ipv6 网关不会替换它
+	p = planFor(t, &fake.Host{Gateways: gw}, "--gw", "2001:db8::1")
+	if got := descs(p); len(got) != 1 || strings.Contains(got[0], "replaces") {
+		t.Fatalf("steps = %v, want one step without replacement", got)
+	}
+}
+
+func TestPlanOnly(t *testing.T) {
+	f := &fake.Host{}
+	p := planFor(t, f, "--only", "4")
+	runPlan(p)
+	if want := []string{"SetIPv6 eth0 false"}; !reflect.DeepEqual(f.Calls, want) {
+		t.Fatalf("calls = %v, want %v", f.Calls, want)
+	}
+
+	// --only 6 不删除 ipv4 地址
+	f = &fake.Host{IPs: []*net.IPNet{mustCIDR(t, "10.0.0.1/24"), mustCIDR(t, "2001:db8::1/64")}}
+	p = planFor(t, f, "--only", "6")
+	runPlan(p)
+	if want := []string{"SetIPv6 eth0 true"}; !reflect.DeepEqual(f.Calls, want) {
+		t.Fatalf("calls = %v, want %v", f.Calls, want)
+	}
+
+	cmd := SetC()
+	if err := cmd.ParseFlags([]string{"--only", "5"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := buildPlan(&fake.Host{}, "eth0", cmd); err == nil {
+		t.Fatal("--only 5 was accepted")
+	}
+}
+
+func TestPlanRejectsInvalidOthers(t *testing.T) {
+	for _, args := range [][]string{
+		{"--ttl", "0"},
+		{"--ttl", "256"},
+		{"--mtu", "1400", "--qos", "3"},
+	} {
+		cmd := SetC()
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatal(err)
+		}
+		f := &fake.Host{}
+		if _, err := buildPlan(f, "eth0", cmd); err == nil {
+			t.Errorf("%v was accepted", args)
+		}
+		if len(f.Calls) != 0 {
+			t.Errorf("%v changed the interface: %v", args, f.Calls)
+		}
+	}
+
+	p := planFor(t, &fake.Host{}, "--ttl", "64")
+	if len(p.steps) != 1 {
+		t.Fatalf("steps = %v, want one", descs(p))
+	}
+}
+
+func TestDryRunOrder(t *testing.T) {
+	f := &fake.Host{
+		IPs:     []*net.IPNet{mustCIDR(t, "10.0.0.1/24")},
+		Details: interfaces.LinkDetails{MTU: 1500},
+	}
+	p := planFor(t, f, "--down", "--mtu", "9000", "--ip", "10.0.0.2/24", "--promisc")
+
+	var buf bytes.Buffer
+	p.print(&buf)
+	want := `Plan for eth0:
+  1. Setting link DOWN
+  2. Removing IP 10.0.0.1/24
+  3. Adding IP 10.0.0.2/24
+  4. Setting promiscuous mode to true
+  5. Changing MTU: 1500 -> 9000
+`
+	if buf.String() != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	var empty bytes.Buffer
+	planFor(t, &fake.Host{}, "--ip", "").print(&empty)
+	if empty.String() != "No changes for eth0\n" {
+		t.Fatalf("empty plan printed %q", empty.String())
+	}
+}
+
+func TestApplyLeaseStopsAtFailure(t *testing.T) {
+	f := &fake.Host{Fail: "SetGateway"}
+	l := &dhcp.Lease{
+		Iface:   "eth0",
+		IP:      net.ParseIP("192.0.2.10").To4(),
+		Mask:    net.CIDRMask(24, 32),
+		Routers: []net.IP{net.ParseIP("192.0.2.1")},
+		DNS:     []net.IP{net.ParseIP("192.0.2.53")},
+	}
+	if err := applyLease(f, l); err == nil {
+		t.Fatal("applyLease ignored the failed gateway")
+	}
+	want := []string{"SetIPs eth0 [192.0.2.10/24]", "SetGateway eth0 192.0.2.1 0"}
+	if !reflect.DeepEqual(f.Calls, want) {
+		t.Fatalf("calls = %v, want %v", f.Calls, want)
+	}
+}
+
+func TestModeDHCPUsesBackend(t *testing.T) {
+	f := &fake.Host{}
+	p := planFor(t, f, "--mode", "dhcp")
+	if err := p.run(); err != nil {
+		t.Fatal(err)
+	}
+	// 系统 dhcp 客户端维护租约时（fake 返回 nil 租约）不再应用地址
+	if want := []string{"ObtainDHCP eth0"}; !reflect.DeepEqual(f.Calls, want) {
+		t.Fatalf("calls = %v, want %v", f.Calls, want)
+	}
+}
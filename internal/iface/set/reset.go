@@ -0,0 +1,58 @@
+package set
+
+import (
+	"fmt"
+	"nctl/interfaces"
+	"nctl/internal/utils"
+	"nctl/internal/utils/snapshot"
+	"os"
+	"path/filepath"
+)
+
+func baselinePath(ifaceName string) string {
+	return filepath.Join(utils.StateDir(), "baseline", ifaceName+".json")
+}
+
+// 记录接口当前的配置作为基线
+func saveBaseline(ifaceName string) error {
+	s, err := snapshot.Take(utils.IfaceUtils(), ifaceName, printWarning)
+	if err != nil {
+		return err
+	}
+	return s.Save(baselinePath(ifaceName))
+}
+
+// 处理 reset 关键字：清空接口配置后恢复基线或重新获取 dhcp 租约
+func planReset(p *plan, ifaceUtils interfaces.Ifaces) error {
+	b, err := snapshot.Load(baselinePath(p.iface))
+	if err != nil {
+		return fmt.Errorf("failed to load baseline: %w", err)
+	}
+
+	if b != nil {
+		for _, st := range b.Steps(ifaceUtils) {
+			p.add(st.Desc, st.Apply)
+		}
+		return nil
+	}
+
+	p.add("Flushing IP addresses", func() error { return ifaceUtils.FlushIPs(p.iface) })
+	p.add("Flushing static routes", func() error { return ifaceUtils.FlushRoutes(p.iface) })
+	p.add("Flushing DNS servers", func() error { return ifaceUtils.FlushDNS(p.iface) })
+	p.add("Requesting DHCP lease", func() error { return runDHCP(ifaceUtils, p.iface) })
+	return nil
+}
+
+// 输出每一步操作的结果
+func printStep(step string, err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  [FAIL] %s: %v\n", step, err)
+		return
+	}
+	fmt.Printf("  [ OK ] %s\n", step)
+}
+
+// 输出不影响继续执行的问题
+func printWarning(err error) {
+	fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+}
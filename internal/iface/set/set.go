@@ -2,17 +2,20 @@ package set
 
 import (
 	"fmt"
-	"nctl/internal/iface/status"
+	"nctl/interfaces"
 	"nctl/internal/utils"
+	"nctl/internal/utils/snapshot"
 	"os"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	setUp    bool
-	setDown  bool
-	setReset bool
+	setUp     bool
+	setDown   bool
+	setReset  bool
+	setSave   bool
+	setDryRun bool
 )
 
 func SetC() *cobra.Command {
@@ -23,6 +26,12 @@ func SetC() *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			ifaceName := args[0]
 
+			// 没有任何设置时输出帮助
+			if cmd.Flags().NFlag() == 0 {
+				cmd.Help()
+				return
+			}
+
 			flagCount := 0
 			if setUp {
 				flagCount++
@@ -34,34 +43,62 @@ func SetC() *cobra.Command {
 				flagCount++
 			}
 
-			if flagCount == 0 {
-				cmd.Help()
-				return
-			}
-
 			// 检查是否存在状态关键字，并检查唯一性
 			if flagCount > 1 {
 				fmt.Fprintf(os.Stderr, "There can only be one state management (UP, DOWN, RESET)\n")
 				return
 			}
 
-			if setUp {
-				RunSet(ifaceName, true)
-			} else if setDown {
-				RunSet(ifaceName, false)
-			} else if setReset {
-				setResetFunc(ifaceName)
+			if setConfirmTimeout > 0 && setKeep {
+				fmt.Fprintf(os.Stderr, "--confirm-timeout cannot be used with --dhcp-keep\n")
+				return
+			}
+
+			ifaceUtils := utils.IfaceUtils()
+			if err := ifaceUtils.IsExistingIface(ifaceName); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return
+			}
+
+			// 计划阶段：根据当前状态计算所有改动，不修改任何配置
+			p, err := buildPlan(ifaceUtils, ifaceName, cmd)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return
 			}
 
-			// 有关 ip 地址的逻辑
-			runAddrs(ifaceName, cmd)
+			if setDryRun {
+				p.print(os.Stdout)
+				return
+			}
+			if len(p.steps) == 0 {
+				fmt.Printf("No changes for %s\n", ifaceName)
+				return
+			}
+
+			// 事务模式下先记录快照，改动完成后等待确认
+			var tx *snapshot.Transaction
+			if setConfirmTimeout > 0 {
+				if tx = beginTransaction(ifaceName); tx == nil {
+					return
+				}
+			}
+
+			// 执行阶段
+			err = p.run()
+			if tx != nil {
+				finishTransaction(tx, err)
+			}
 		},
 	}
 
 	// 状态设置
 	cmd.Flags().BoolVarP(&setUp, "up", "U", false, "Open the network interface")
 	cmd.Flags().BoolVarP(&setDown, "down", "D", false, "Shut down the network interface")
-	cmd.Flags().BoolVarP(&setReset, "reset", "R", false, "Reset network interface configuration")
+	cmd.Flags().BoolVarP(&setReset, "reset", "R", false, "Reset network interface configuration to the saved baseline, or DHCP if none")
+	cmd.Flags().DurationVar(&setConfirmTimeout, "confirm-timeout", 0, "Roll the changes back unless 'nctl confirm' is run within this time (e.g. 60s)")
+	cmd.Flags().BoolVar(&setSave, "save-baseline", false, "Save the current interface configuration as the reset baseline")
+	cmd.Flags().BoolVar(&setDryRun, "dry-run", false, "Print the planned changes without applying them")
 	// ip相关设置
 	cmd = setAddrs(cmd)
 	// 模式相关设置
@@ -72,18 +109,43 @@ func SetC() *cobra.Command {
 	return cmd
 }
 
-func RunSet(ifaceName string, enable bool) {
-	ifaceUtils := utils.IfaceUtils()
-	if err := ifaceUtils.IsExistingIface(ifaceName); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		return
+// 按执行顺序汇总所有设置的计划
+func buildPlan(ifaceUtils interfaces.Ifaces, ifaceName string, cmd *cobra.Command) (*plan, error) {
+	p := &plan{iface: ifaceName}
+
+	if setSave {
+		p.add("Saving baseline to "+baselinePath(ifaceName), func() error {
+			return saveBaseline(ifaceName)
+		})
 	}
 
-	// 处理 up 和 down 关键字，直接调用 status 命令的逻辑即可
-	status.RunStatus([]string{ifaceName}, enable)
-}
+	// 处理 up 和 down 关键字
+	if setUp {
+		p.add("Setting link UP", func() error { return ifaceUtils.SetLinkState(ifaceName, true) })
+	} else if setDown {
+		p.add("Setting link DOWN", func() error { return ifaceUtils.SetLinkState(ifaceName, false) })
+	} else if setReset {
+		if err := planReset(p, ifaceUtils); err != nil {
+			return nil, err
+		}
+	}
 
-// 处理 reset 关键字
-func setResetFunc(ifaceName string) {
+	// 工作模式需要在 ip 设置之前处理
+	if err := planMode(p, ifaceUtils, cmd); err != nil {
+		return nil, err
+	}
+	// 有关 ip 地址的逻辑
+	if err := planAddrs(p, ifaceUtils, cmd); err != nil {
+		return nil, err
+	}
+	// 模式相关的逻辑
+	if err := planModes(p, ifaceUtils, cmd); err != nil {
+		return nil, err
+	}
+	// 其他链路层设置
+	if err := planOthers(p, ifaceUtils, cmd); err != nil {
+		return nil, err
+	}
 
+	return p, nil
 }
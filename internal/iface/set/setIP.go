@@ -4,9 +4,10 @@ package set
 
 import (
 	"fmt"
-	"nctl/internal/utils"
+	"nctl/interfaces"
 	"net"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -16,15 +17,18 @@ var (
 	setDNS []string
 	setADD bool
 	setDEL bool
-	setGW  string
+	setGW  []string
+	// 网关的 metric，0 表示由系统决定
+	setGWMetric int
 )
 
 func setAddrs(cmd *cobra.Command) *cobra.Command {
 	cmd.Flags().StringSliceVarP(&setIP, "ip", "i", []string{}, "Set one or more IP addresses in CIDR format (e.g., 192.168.1.10/24)")
 	cmd.Flags().StringSliceVarP(&setDNS, "dns", "d", []string{}, "Set one or more DNS server addresses")
 	cmd.Flags().BoolVarP(&setADD, "add", "I", false, "Add IP/DNS to the interface instead of overwriting")
-	cmd.Flags().BoolVarP(&setDEL, "del", "S", false, "Delete IP/DNS from the interface")
-	cmd.Flags().StringVarP(&setGW, "gw", "g", "", "Set the default gateway (always overwrites)")
+	cmd.Flags().BoolVarP(&setDEL, "del", "S", false, "Delete IP/DNS/gateway from the interface")
+	cmd.Flags().StringSliceVarP(&setGW, "gw", "g", []string{}, "Set the default gateway, at most one per address family (replaces this interface's default of the same family)")
+	cmd.Flags().IntVar(&setGWMetric, "gw-metric", 0, "Metric of the default gateway set by --gw, lower is preferred (e.g. 100 for primary, 200 for backup uplink)")
 
 	return cmd
 }
@@ -37,12 +41,23 @@ func checkAddrs(cmd *cobra.Command) bool {
 		return false
 	}
 
-	// 如果指定了 --add 或 --del，但没有提供 --ip 或 --dns，则为错误
-	if (setADD || setDEL) && len(setIP) == 0 && len(setDNS) == 0 {
-		fmt.Fprintln(os.Stderr, "Error: The --add or --del flag must be used with --ip or --dns.")
+	// 如果指定了 --add 或 --del，但没有提供 --ip、--dns 或 --gw，则为错误
+	if (setADD || setDEL) && len(setIP) == 0 && len(setDNS) == 0 && len(setGW) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: The --add or --del flag must be used with --ip, --dns or --gw.")
 		cmd.Help()
 		return false
 	}
+
+	if setADD && len(setGW) > 0 {
+		fmt.Fprintln(os.Stderr, "Error: The --add flag cannot be used with --gw, use --gw-metric to keep several default gateways on different interfaces.")
+		cmd.Help()
+		return false
+	}
+
+	if setGWMetric < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --gw-metric must not be negative.")
+		return false
+	}
 	return true
 }
 
@@ -88,44 +103,42 @@ func parseDNSs(dnsStrs []string) ([]net.IP, error) {
 	return ips, nil
 }
 
-func runAddrs(ifaceName string, cmd *cobra.Command) {
-	if !checkAddrs(cmd) {
-		return
+// 判断地址列表中是否包含指定地址
+func containsIPNet(list []*net.IPNet, ipnet *net.IPNet) bool {
+	for _, item := range list {
+		if item.String() == ipnet.String() {
+			return true
+		}
 	}
+	return false
+}
 
-	ifaceUtils := utils.IfaceUtils()
-	if err := ifaceUtils.IsExistingIface(ifaceName); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		return
+func containsIP(list []net.IP, ip net.IP) bool {
+	for _, item := range list {
+		if item.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func planAddrs(p *plan, ifaceUtils interfaces.Ifaces, cmd *cobra.Command) error {
+	if len(setIP) == 0 && len(setDNS) == 0 && len(setGW) == 0 && !setADD && !setDEL {
+		return nil
+	}
+
+	if !checkAddrs(cmd) {
+		return fmt.Errorf("invalid address flags")
 	}
 
 	// 处理 ip
 	if len(setIP) > 0 {
 		ipnets, err := parseIPs(setIP)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing IP addresses: %v\n", err)
-			return
+			return fmt.Errorf("parsing IP addresses: %w", err)
 		}
-
-		if setADD {
-			fmt.Printf("Adding IP addresses to %s...\n", ifaceName)
-			for _, ipnet := range ipnets {
-				if err := ifaceUtils.AddIP(ifaceName, ipnet); err != nil {
-					fmt.Fprintf(os.Stderr, "Failed to add IP %s: %v\n", ipnet.String(), err)
-				}
-			}
-		} else if setDEL {
-			fmt.Printf("Deleting IP addresses from %s...\n", ifaceName)
-			for _, ipnet := range ipnets {
-				if err := ifaceUtils.DelIP(ifaceName, ipnet); err != nil {
-					fmt.Fprintf(os.Stderr, "Failed to delete IP %s: %v\n", ipnet.String(), err)
-				}
-			}
-		} else { // 默认：覆盖
-			fmt.Printf("Overwriting IP addresses on %s...\n", ifaceName)
-			if err := ifaceUtils.SetIPs(ifaceName, ipnets); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to set IPs: %v\n", err)
-			}
+		if err := planIPs(p, ifaceUtils, ipnets); err != nil {
+			return err
 		}
 	}
 
@@ -133,43 +146,187 @@ func runAddrs(ifaceName string, cmd *cobra.Command) {
 	if len(setDNS) > 0 {
 		dnsIPs, err := parseDNSs(setDNS)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing DNS addresses: %v\n", err)
-			return
+			return fmt.Errorf("parsing DNS addresses: %w", err)
+		}
+		planDNSs(p, ifaceUtils, dnsIPs)
+	}
+
+	// 处理网关，每个地址族独立覆盖
+	if len(setGW) > 0 {
+		gws, err := parseGateways(setGW)
+		if err != nil {
+			return err
+		}
+		for _, gw := range gws {
+			if err := planGateway(p, ifaceUtils, gw); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func planIPs(p *plan, ifaceUtils interfaces.Ifaces, ipnets []*net.IPNet) error {
+	// reset 和 mode 会先改变接口上的地址，此时无法预先计算差异，直接整体覆盖
+	if !setADD && !setDEL && (setReset || setMode != "") {
+		p.add(fmt.Sprintf("Replacing all IP addresses with %v", ipnets), func() error {
+			return ifaceUtils.SetIPs(p.iface, ipnets)
+		})
+		return nil
+	}
+
+	current, err := ifaceUtils.GetIPs(p.iface)
+	if err != nil {
+		return fmt.Errorf("reading current IP addresses: %w", err)
+	}
+
+	var toDel, toAdd []*net.IPNet
+	switch {
+	case setADD:
+		for _, ipnet := range ipnets {
+			if !containsIPNet(current, ipnet) {
+				toAdd = append(toAdd, ipnet)
+			}
 		}
+	case setDEL:
+		for _, ipnet := range ipnets {
+			if !containsIPNet(current, ipnet) {
+				return fmt.Errorf("IP %s is not configured on %s", ipnet.String(), p.iface)
+			}
+			toDel = append(toDel, ipnet)
+		}
+	default: // 默认：覆盖，只删除和增加有差异的地址
+		for _, ipnet := range current {
+			if !containsIPNet(ipnets, ipnet) {
+				toDel = append(toDel, ipnet)
+			}
+		}
+		for _, ipnet := range ipnets {
+			if !containsIPNet(current, ipnet) {
+				toAdd = append(toAdd, ipnet)
+			}
+		}
+	}
 
-		if setADD {
-			fmt.Printf("Adding DNS servers to %s...\n", ifaceName)
-			for _, ip := range dnsIPs {
-				if err := ifaceUtils.AddDNS(ifaceName, ip); err != nil {
-					fmt.Fprintf(os.Stderr, "Failed to add DNS %s: %v\n", ip.String(), err)
-				}
+	for _, ipnet := range toDel {
+		p.add("Removing IP "+ipnet.String(), func() error { return ifaceUtils.DelIP(p.iface, ipnet) })
+	}
+	for _, ipnet := range toAdd {
+		p.add("Adding IP "+ipnet.String(), func() error { return ifaceUtils.AddIP(p.iface, ipnet) })
+	}
+	return nil
+}
+
+func planDNSs(p *plan, ifaceUtils interfaces.Ifaces, dnsIPs []net.IP) {
+	current, err := ifaceUtils.GetDNSs(p.iface)
+	if err != nil {
+		// 无法读取当前 dns 时按原有的逐条增删执行
+		fmt.Fprintf(os.Stderr, "Warning: failed to read current DNS servers: %v\n", err)
+		for _, ip := range dnsIPs {
+			switch {
+			case setADD:
+				p.add("Adding DNS "+ip.String(), func() error { return ifaceUtils.AddDNS(p.iface, ip) })
+			case setDEL:
+				p.add("Deleting DNS "+ip.String(), func() error { return ifaceUtils.DelDNS(p.iface, ip) })
 			}
-		} else if setDEL {
-			fmt.Printf("Deleting DNS servers from %s...\n", ifaceName)
-			for _, ip := range dnsIPs {
-				if err := ifaceUtils.DelDNS(ifaceName, ip); err != nil {
-					fmt.Fprintf(os.Stderr, "Failed to delete DNS %s: %v\n", ip.String(), err)
-				}
+		}
+		if !setADD && !setDEL {
+			p.add(fmt.Sprintf("Setting DNS servers: (unknown) -> %v", dnsIPs), func() error {
+				return ifaceUtils.SetDNSs(p.iface, dnsIPs)
+			})
+		}
+		return
+	}
+
+	var next []net.IP
+	switch {
+	case setADD:
+		next = append(next, current...)
+		for _, ip := range dnsIPs {
+			if !containsIP(next, ip) {
+				next = append(next, ip)
 			}
-		} else { // 默认：覆盖
-			fmt.Printf("Overwriting DNS servers on %s...\n", ifaceName)
-			if err := ifaceUtils.SetDNSs(ifaceName, dnsIPs); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to set DNS servers: %v\n", err)
+		}
+	case setDEL:
+		for _, ip := range current {
+			if !containsIP(dnsIPs, ip) {
+				next = append(next, ip)
 			}
 		}
+	default:
+		next = dnsIPs
+	}
+
+	if fmt.Sprint(current) == fmt.Sprint(next) {
+		return
 	}
+	p.add(fmt.Sprintf("Setting DNS servers: %v -> %v", current, next), func() error {
+		return ifaceUtils.SetDNSs(p.iface, next)
+	})
+}
 
-	// 处理网关 (总是覆盖)
-	if setGW != "" {
-		ip := net.ParseIP(setGW)
+// 解析网关，每个地址族最多一个
+func parseGateways(gwStrs []string) ([]net.IP, error) {
+	var gws []net.IP
+	families := map[bool]bool{}
+	for _, s := range gwStrs {
+		ip := net.ParseIP(s)
 		if ip == nil {
-			fmt.Fprintf(os.Stderr, "Error: Invalid gateway address format: '%s'\n", setGW)
-			return
+			return nil, fmt.Errorf("invalid gateway address format: '%s'", s)
 		}
+		isV4 := ip.To4() != nil
+		if families[isV4] && !setDEL {
+			return nil, fmt.Errorf("only one default gateway per address family can be set")
+		}
+		families[isV4] = true
+		gws = append(gws, ip)
+	}
+	return gws, nil
+}
+
+func planGateway(p *plan, ifaceUtils interfaces.Ifaces, gw net.IP) error {
+	current, err := ifaceUtils.GetGateways(p.iface)
+	if err != nil {
+		return fmt.Errorf("reading current gateways: %w", err)
+	}
 
-		fmt.Printf("Setting default gateway on %s...\n", ifaceName)
-		if err := ifaceUtils.SetGateway(ifaceName, ip); err != nil {
-			fmt.Fprintf(os.Stderr, "Error setting gateway %s: %v\n", ip.String(), err)
+	var same []interfaces.Gateway
+	for _, old := range current {
+		if (old.IP.To4() == nil) == (gw.To4() == nil) {
+			same = append(same, old)
 		}
 	}
+
+	if setDEL {
+		for _, old := range same {
+			if old.IP.Equal(gw) {
+				p.add(fmt.Sprintf("Deleting default gateway %s on %s", gw, p.iface), func() error {
+					return ifaceUtils.DelGateway(p.iface, gw)
+				})
+				return nil
+			}
+		}
+		return fmt.Errorf("gateway %s is not configured on %s", gw, p.iface)
+	}
+
+	// reset 和 mode 会先改变网关，此时无法判断是否已经一致；未指定 metric 时不比较 metric
+	if !setReset && setMode == "" && len(same) == 1 && same[0].IP.Equal(gw) && (setGWMetric == 0 || same[0].Metric == setGWMetric) {
+		return nil
+	}
+
+	desc := fmt.Sprintf("Setting default gateway %s on %s", gw, p.iface)
+	if setGWMetric != 0 {
+		desc = fmt.Sprintf("Setting default gateway %s metric %d on %s", gw, setGWMetric, p.iface)
+	}
+	// SetGateway 只替换该接口上同一地址族的默认路由
+	var removed []string
+	for _, old := range same {
+		removed = append(removed, fmt.Sprintf("via %s metric %d", old.IP, old.Metric))
+	}
+	if len(removed) > 0 {
+		desc += fmt.Sprintf(" (replaces %s)", strings.Join(removed, ", "))
+	}
+
+	p.add(desc, func() error { return ifaceUtils.SetGateway(p.iface, gw, setGWMetric) })
+	return nil
 }
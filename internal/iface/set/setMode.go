@@ -1,19 +1,104 @@
 package set
 
-import "github.com/spf13/cobra"
+import (
+	"fmt"
+	"nctl/interfaces"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
 
 var (
 	setMode    string
 	setPromisc bool
 	setDuplex  string
 	setOnly    string
+	setKeep    bool
 )
 
 func setModes(cmd *cobra.Command) *cobra.Command {
 	cmd.Flags().StringVarP(&setMode, "mode", "M", "", "Network interface working mode (value: dhcp, local, ip)")
 	cmd.Flags().BoolVarP(&setPromisc, "promisc", "p", false, "Whether to enable promiscuous mode")
 	cmd.Flags().StringVarP(&setDuplex, "duplex", "x", "", "Full/half duplex mode of the network (value: half, full)")
-	cmd.Flags().StringVarP(&setOnly, "only", "o", "", "Use only IPv4 or IPv6 (value: 4, 6)")
+	cmd.Flags().BoolVar(&setKeep, "dhcp-keep", false, "Stay in the foreground and keep the DHCP lease renewed (with --mode dhcp)")
+	cmd.Flags().StringVarP(&setOnly, "only", "o", "", "4 disables IPv6 on the interface, 6 re-enables it and leaves IPv4 addresses alone (value: 4, 6)")
 
 	return cmd
 }
+
+// 将带单位的速率解析为 Mb/s，不带单位时默认为 Mb/s
+func parseSpeed(s string) (int, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, fmt.Errorf("empty speed")
+	}
+
+	// 各单位对应的 bit/s
+	units := map[byte]float64{'B': 1, 'K': 1e3, 'M': 1e6, 'G': 1e9}
+	mult := 1e6
+	if u, ok := units[s[len(s)-1]]; ok {
+		mult = u
+		s = s[:len(s)-1]
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid speed format: %s", s)
+	}
+
+	mbps := v * mult / 1e6
+	if mbps < 1 || mbps != float64(int(mbps)) {
+		return 0, fmt.Errorf("speed must be a whole number of Mb/s")
+	}
+	return int(mbps), nil
+}
+
+// 处理模式相关设置
+func planModes(p *plan, ifaceUtils interfaces.Ifaces, cmd *cobra.Command) error {
+	flags := cmd.Flags()
+
+	if flags.Changed("promisc") {
+		p.add(fmt.Sprintf("Setting promiscuous mode to %t", setPromisc), func() error {
+			return ifaceUtils.SetPromisc(p.iface, setPromisc)
+		})
+	}
+
+	// 速率和双工模式通过一次 ethtool 调用完成
+	if flags.Changed("duplex") || flags.Changed("speed") {
+		speed := 0
+		if flags.Changed("speed") {
+			var err error
+			if speed, err = parseSpeed(setSpeed); err != nil {
+				return err
+			}
+		}
+		if setDuplex != "" && setDuplex != "half" && setDuplex != "full" {
+			return fmt.Errorf("invalid duplex mode '%s' (value: half, full)", setDuplex)
+		}
+
+		var parts []string
+		if speed > 0 {
+			parts = append(parts, fmt.Sprintf("speed %dMb/s", speed))
+		}
+		if setDuplex != "" {
+			parts = append(parts, "duplex "+setDuplex)
+		}
+		p.add("Setting link "+strings.Join(parts, ", "), func() error {
+			return ifaceUtils.SetLinkMode(p.iface, speed, setDuplex)
+		})
+	}
+
+	if flags.Changed("only") {
+		switch setOnly {
+		case "4":
+			p.add("Disabling IPv6", func() error { return ifaceUtils.SetIPv6(p.iface, false) })
+		case "6":
+			// 没有按接口禁用 ipv4 的通用方式，这里只确保 ipv6 已启用，ipv4 地址保持不变
+			p.add("Enabling IPv6", func() error { return ifaceUtils.SetIPv6(p.iface, true) })
+		default:
+			return fmt.Errorf("invalid value '%s' for --only (value: 4, 6)", setOnly)
+		}
+	}
+	return nil
+}
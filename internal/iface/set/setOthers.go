@@ -1,6 +1,13 @@
 package set
 
-import "github.com/spf13/cobra"
+import (
+	"fmt"
+	"nctl/interfaces"
+	"net"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
 
 var (
 	setMAC   string
@@ -17,11 +24,72 @@ func setOthers(cmd *cobra.Command) *cobra.Command {
 	cmd.Flags().StringVarP(&setMAC, "mac", "m", "", "Network interface MAC address")
 	cmd.Flags().IntVarP(&setMTU, "mtu", "u", 0, "Set mtu value")
 	cmd.Flags().BoolVarP(&setARP, "arp", "a", false, "Whether to enable ARP")
-	cmd.Flags().IntVarP(&setQOS, "qos", "q", 0, "Set QOS value")
+	cmd.Flags().IntVarP(&setQOS, "qos", "q", 0, "Set QOS value (not supported yet)")
 	cmd.Flags().StringVarP(&setVlan, "vlan", "v", "", "Set VLAN tag of interface")
 	cmd.Flags().StringVarP(&setDesc, "desc", "c", "", "Set description of interface")
-	cmd.Flags().IntVarP(&setTTL, "ttl", "t", 0, "Set TTL value")
+	cmd.Flags().IntVarP(&setTTL, "ttl", "t", 0, "Set the TTL / hop limit of packets sent from the interface (IPv6 hop limit only on linux)")
 	cmd.Flags().StringVarP(&setSpeed, "speed", "s", "", "Set interface network rate, supporting units of B, K, M, G")
 
 	return cmd
 }
+
+// 处理其他链路层设置，速率在 planModes 中与双工模式一起处理
+func planOthers(p *plan, ifaceUtils interfaces.Ifaces, cmd *cobra.Command) error {
+	flags := cmd.Flags()
+
+	// 读取当前的 mac 和 mtu，用于在计划中展示变化
+	current, err := ifaceUtils.GetLinkDetails(p.iface)
+	if err != nil {
+		current = &interfaces.LinkDetails{}
+	}
+
+	if flags.Changed("mac") {
+		mac, err := net.ParseMAC(setMAC)
+		if err != nil {
+			return fmt.Errorf("invalid MAC address: %w", err)
+		}
+		p.add(fmt.Sprintf("Changing MAC address: %s -> %s", current.HardwareAddr, mac), func() error {
+			return ifaceUtils.SetMAC(p.iface, mac)
+		})
+	}
+
+	if flags.Changed("mtu") {
+		if setMTU <= 0 {
+			return fmt.Errorf("MTU must be a positive number, got %d", setMTU)
+		}
+		p.add(fmt.Sprintf("Changing MTU: %d -> %d", current.MTU, setMTU), func() error {
+			return ifaceUtils.SetMTU(p.iface, setMTU)
+		})
+	}
+
+	if flags.Changed("arp") {
+		p.add(fmt.Sprintf("Setting ARP to %t", setARP), func() error { return ifaceUtils.SetARP(p.iface, setARP) })
+	}
+
+	if flags.Changed("desc") {
+		p.add(fmt.Sprintf("Setting description to '%s'", setDesc), func() error { return ifaceUtils.SetAlias(p.iface, setDesc) })
+	}
+
+	if flags.Changed("vlan") {
+		id, err := strconv.Atoi(setVlan)
+		if err != nil || id < 1 || id > 4094 {
+			return fmt.Errorf("VLAN tag must be between 1 and 4094, got '%s'", setVlan)
+		}
+		p.add(fmt.Sprintf("Creating VLAN interface %s.%d", p.iface, id), func() error { return ifaceUtils.AddVLAN(p.iface, id) })
+	}
+
+	if flags.Changed("ttl") {
+		// 与各平台 SetTTL 接受的范围一致，避免在执行到一半时才失败
+		if setTTL < 1 || setTTL > 255 {
+			return fmt.Errorf("TTL must be between 1 and 255, got %d", setTTL)
+		}
+		// 各平台实际修改的范围见 SetTTL 的说明
+		p.add(fmt.Sprintf("Setting TTL / hop limit to %d", setTTL), func() error { return ifaceUtils.SetTTL(p.iface, setTTL) })
+	}
+
+	// 在执行任何改动之前拒绝
+	if flags.Changed("qos") {
+		return fmt.Errorf("--qos is not supported yet")
+	}
+	return nil
+}
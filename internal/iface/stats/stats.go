@@ -0,0 +1,195 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"nctl/interfaces"
+	"nctl/internal/utils"
+	"nctl/internal/utils/output"
+)
+
+var (
+	statsInterval time.Duration
+	statsCount    int
+)
+
+// 一个采样周期内的速率和增量
+type sample struct {
+	Time          time.Time `json:"time"`
+	Iface         string    `json:"iface"`
+	RxBytesPerSec float64   `json:"rx_bytes_per_sec"`
+	TxBytesPerSec float64   `json:"tx_bytes_per_sec"`
+	RxPktsPerSec  float64   `json:"rx_packets_per_sec"`
+	TxPktsPerSec  float64   `json:"tx_packets_per_sec"`
+	RxErrors      uint64    `json:"rx_errors"`
+	TxErrors      uint64    `json:"tx_errors"`
+	RxDropped     uint64    `json:"rx_dropped"`
+	TxDropped     uint64    `json:"tx_dropped"`
+}
+
+func Stats() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats [interface_name...]",
+		Short: "Show per-interface traffic rates, errors and drops",
+		Args:  cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := output.Format(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return
+			}
+			if format != output.Table && format != output.JSON {
+				fmt.Fprintf(cmd.ErrOrStderr(), "stats only supports table and json output\n")
+				return
+			}
+			if statsInterval <= 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "interval must be positive\n")
+				return
+			}
+
+			names := args
+			if len(names) == 0 {
+				ifaces, err := net.Interfaces()
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "failed to get interfaces: %v\n", err)
+					return
+				}
+				for _, iface := range ifaces {
+					names = append(names, iface.Name)
+				}
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			runStats(ctx, cmd, names, format)
+		},
+	}
+
+	cmd.Flags().DurationVarP(&statsInterval, "interval", "i", time.Second, "Sampling interval")
+	cmd.Flags().IntVarP(&statsCount, "count", "c", 0, "Stop after this many samples (0 means run until interrupted)")
+
+	return cmd
+}
+
+// 读取所有接口的计数，读取失败的接口跳过
+func readCounters(cmd *cobra.Command, ifaceUtils interfaces.Ifaces, names []string) map[string]interfaces.LinkStats {
+	counters := make(map[string]interfaces.LinkStats, len(names))
+	for _, name := range names {
+		d, err := ifaceUtils.GetLinkDetails(name)
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+			continue
+		}
+		counters[name] = d.Stats
+	}
+	return counters
+}
+
+func runStats(ctx context.Context, cmd *cobra.Command, names []string, format string) {
+	ifaceUtils := utils.IfaceUtils()
+
+	prev := readCounters(cmd, ifaceUtils, names)
+	prevTime := time.Now()
+	if len(prev) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	for n := 0; statsCount == 0 || n < statsCount; n++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cur := readCounters(cmd, ifaceUtils, names)
+		now := time.Now()
+		secs := now.Sub(prevTime).Seconds()
+
+		var samples []sample
+		for _, name := range names {
+			c, ok := cur[name]
+			p, okPrev := prev[name]
+			if !ok || !okPrev {
+				continue
+			}
+			samples = append(samples, sample{
+				Time:          now,
+				Iface:         name,
+				RxBytesPerSec: float64(delta(c.RxBytes, p.RxBytes)) / secs,
+				TxBytesPerSec: float64(delta(c.TxBytes, p.TxBytes)) / secs,
+				RxPktsPerSec:  float64(delta(c.RxPackets, p.RxPackets)) / secs,
+				TxPktsPerSec:  float64(delta(c.TxPackets, p.TxPackets)) / secs,
+				RxErrors:      delta(c.RxErrors, p.RxErrors),
+				TxErrors:      delta(c.TxErrors, p.TxErrors),
+				RxDropped:     delta(c.RxDropped, p.RxDropped),
+				TxDropped:     delta(c.TxDropped, p.TxDropped),
+			})
+		}
+		prev, prevTime = cur, now
+
+		if format == output.JSON {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			for _, s := range samples {
+				enc.Encode(s)
+			}
+		} else {
+			printTable(cmd.OutOrStdout(), samples)
+		}
+	}
+}
+
+// 计数器被重置（如接口重建）时返回 0
+func delta(cur, prev uint64) uint64 {
+	if cur < prev {
+		return 0
+	}
+	return cur - prev
+}
+
+// 清屏后重新输出表格
+func printTable(w io.Writer, samples []sample) {
+	fmt.Fprint(w, "\033[H\033[2J")
+	fmt.Fprintf(w, "Every %s: %s\n", statsInterval, time.Now().Format("2006-01-02 15:04:05"))
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.AppendHeader(table.Row{"INTERFACE", "RX/s", "TX/s", "RX PKT/s", "TX PKT/s", "RX ERR", "TX ERR", "RX DROP", "TX DROP"})
+	for _, s := range samples {
+		t.AppendRow([]interface{}{
+			s.Iface,
+			formatRate(s.RxBytesPerSec),
+			formatRate(s.TxBytesPerSec),
+			fmt.Sprintf("%.1f", s.RxPktsPerSec),
+			fmt.Sprintf("%.1f", s.TxPktsPerSec),
+			s.RxErrors,
+			s.TxErrors,
+			s.RxDropped,
+			s.TxDropped,
+		})
+	}
+	t.Render()
+}
+
+// 将字节速率格式化为 B/s、KB/s、MB/s、GB/s
+func formatRate(bytesPerSec float64) string {
+	units := []string{"B/s", "KB/s", "MB/s", "GB/s"}
+	i := 0
+	for bytesPerSec >= 1024 && i < len(units)-1 {
+		bytesPerSec /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", bytesPerSec, units[i])
+}
@@ -2,11 +2,9 @@ package status
 
 import (
 	"fmt"
-	"os/exec"
-	"runtime"
+	"nctl/internal/utils"
 
 	"github.com/spf13/cobra"
-	"github.com/vishvananda/netlink"
 )
 
 func Status() *cobra.Command {
@@ -69,18 +67,7 @@ func RunStatus(ifacesName []string, enable bool) {
 	}
 
 	for _, name := range ifacesName {
-		var err error
-		switch runtime.GOOS {
-		case "linux":
-			err = toggleLinuxInterface(name, enable)
-		case "windows":
-			err = toggleWindowsInterface(name, enable)
-		default:
-			fmt.Printf("OS %s does not support the %s operation on the interface %s\n", runtime.GOOS, name, action)
-			continue
-		}
-
-		if err != nil {
+		if err := Toggle(name, enable); err != nil {
 			fmt.Printf("operation %s failed on interface %s: %v\n", name, action, err)
 		} else {
 			fmt.Printf("interface %s has successfully %s\n", name, action)
@@ -88,37 +75,7 @@ func RunStatus(ifacesName []string, enable bool) {
 	}
 }
 
-// Linux 上操作网络接口
-func toggleLinuxInterface(name string, enable bool) error {
-	link, err := netlink.LinkByName(name)
-	if err != nil {
-		return fmt.Errorf("failed to find network interface %s: %v", name, err)
-	}
-
-	if enable {
-		err = netlink.LinkSetUp(link)
-	} else {
-		err = netlink.LinkSetDown(link)
-	}
-
-	if err != nil {
-		return fmt.Errorf("failed to set network interface %s status: %v", name, err)
-	}
-	return nil
-}
-
-// Windows 上操作网络接口
-func toggleWindowsInterface(name string, enable bool) error {
-	actionCmd := "DISABLED"
-	if enable {
-		actionCmd = "ENABLED"
-	}
-
-	// 注意：在 Windows 上执行此命令通常需要管理员权限。
-	cmd := exec.Command("netsh", "interface", "set", "interface", name, actionCmd)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("执行 netsh 命令失败: %v, 输出: %s", err, string(output))
-	}
-	return nil
+// 启用或禁用单个网络接口
+func Toggle(name string, enable bool) error {
+	return utils.IfaceUtils().SetLinkState(name, enable)
 }
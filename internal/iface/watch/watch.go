@@ -0,0 +1,126 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"nctl/internal/utils/output"
+)
+
+// 事件类型
+const (
+	typeLink  = "link"
+	typeAddr  = "addr"
+	typeRoute = "route"
+)
+
+var watchTypes []string
+
+// 一条接口变化事件
+type event struct {
+	Time   time.Time `json:"time"`
+	Type   string    `json:"type"`
+	Action string    `json:"action"`
+	Iface  string    `json:"iface"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// 事件过滤条件，为空表示不过滤
+type filter struct {
+	ifaces map[string]bool
+	types  map[string]bool
+}
+
+func (f *filter) match(e event) bool {
+	if len(f.ifaces) > 0 && !f.ifaces[e.Iface] {
+		return false
+	}
+	if len(f.types) > 0 && !f.types[e.Type] {
+		return false
+	}
+	return true
+}
+
+func Watch() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch [interface_name...]",
+		Short: "Stream link, address and route changes as they happen",
+		Args:  cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := output.Format(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return
+			}
+			if format != output.Table && format != output.JSON {
+				fmt.Fprintf(cmd.ErrOrStderr(), "watch only supports table and json output\n")
+				return
+			}
+
+			f := &filter{ifaces: map[string]bool{}, types: map[string]bool{}}
+			for _, arg := range args {
+				f.ifaces[arg] = true
+			}
+			for _, t := range watchTypes {
+				switch t {
+				case typeLink, typeAddr, typeRoute:
+					f.types[t] = true
+				default:
+					fmt.Fprintf(cmd.ErrOrStderr(), "invalid event type '%s' (value: link, addr, route)\n", t)
+					return
+				}
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			events := make(chan event)
+			errc := make(chan error, 1)
+			go func() {
+				errc <- subscribe(ctx, events)
+			}()
+
+			for {
+				select {
+				case e := <-events:
+					if f.match(e) {
+						printEvent(cmd.OutOrStdout(), e, format)
+					}
+				case err := <-errc:
+					if err != nil {
+						fmt.Fprintf(cmd.ErrOrStderr(), "watch failed: %v\n", err)
+					}
+					return
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&watchTypes, "type", "t", []string{}, "Only show events of the given types (value: link, addr, route)")
+
+	return cmd
+}
+
+// 输出一条事件，json 格式下每行一个对象
+func printEvent(w io.Writer, e event, format string) {
+	if format == output.JSON {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.Encode(e)
+		return
+	}
+
+	line := fmt.Sprintf("%s  %-5s  %-14s  %s", e.Time.Format("2006-01-02 15:04:05.000"), e.Type, e.Action, e.Iface)
+	if e.Detail != "" {
+		line += "  " + e.Detail
+	}
+	fmt.Fprintln(w, strings.TrimRight(line, " "))
+}
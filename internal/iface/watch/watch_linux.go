@@ -0,0 +1,172 @@
+//go:build linux
+
+package watch
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// 记录链路的上一次状态，用于判断变化
+type linkState struct {
+	name    string
+	up      bool
+	carrier bool
+	mtu     int
+	oper    string
+}
+
+func toLinkState(l netlink.Link) linkState {
+	attrs := l.Attrs()
+	return linkState{
+		name:    attrs.Name,
+		up:      attrs.Flags&net.FlagUp != 0,
+		carrier: attrs.RawFlags&unix.IFF_LOWER_UP != 0,
+		mtu:     attrs.MTU,
+		oper:    attrs.OperState.String(),
+	}
+}
+
+// 订阅 netlink 的链路、地址和路由变化，直到 ctx 结束
+func subscribe(ctx context.Context, events chan<- event) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	linkCh := make(chan netlink.LinkUpdate, 64)
+	addrCh := make(chan netlink.AddrUpdate, 64)
+	routeCh := make(chan netlink.RouteUpdate, 64)
+
+	// 记录当前的链路状态，地址和路由事件只携带接口编号
+	links := map[int]linkState{}
+	list, err := netlink.LinkList()
+	if err != nil {
+		return fmt.Errorf("failed to list links: %w", err)
+	}
+	for _, l := range list {
+		links[l.Attrs().Index] = toLinkState(l)
+	}
+
+	if err := netlink.LinkSubscribe(linkCh, done); err != nil {
+		return fmt.Errorf("failed to subscribe to link updates: %w", err)
+	}
+	if err := netlink.AddrSubscribe(addrCh, done); err != nil {
+		return fmt.Errorf("failed to subscribe to address updates: %w", err)
+	}
+	if err := netlink.RouteSubscribe(routeCh, done); err != nil {
+		return fmt.Errorf("failed to subscribe to route updates: %w", err)
+	}
+
+	name := func(index int) string {
+		if s, ok := links[index]; ok {
+			return s.name
+		}
+		return strconv.Itoa(index)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case u, ok := <-linkCh:
+			if !ok {
+				return fmt.Errorf("link subscription closed")
+			}
+			index := u.Link.Attrs().Index
+			if u.Header.Type == unix.RTM_DELLINK {
+				delete(links, index)
+				events <- event{Time: time.Now(), Type: typeLink, Action: "removed", Iface: u.Link.Attrs().Name}
+				continue
+			}
+			cur := toLinkState(u.Link)
+			prev, known := links[index]
+			links[index] = cur
+			for _, e := range linkEvents(prev, cur, known) {
+				events <- e
+			}
+		case u, ok := <-addrCh:
+			if !ok {
+				return fmt.Errorf("address subscription closed")
+			}
+			action := "removed"
+			if u.NewAddr {
+				action = "added"
+			}
+			events <- event{Time: time.Now(), Type: typeAddr, Action: action, Iface: name(u.LinkIndex), Detail: u.LinkAddress.String()}
+		case u, ok := <-routeCh:
+			if !ok {
+				return fmt.Errorf("route subscription closed")
+			}
+			events <- routeEvent(u, name(u.LinkIndex))
+		}
+	}
+}
+
+// 比较链路前后状态生成事件
+func linkEvents(prev, cur linkState, known bool) []event {
+	now := time.Now()
+	if !known {
+		return []event{{Time: now, Type: typeLink, Action: "added", Iface: cur.name, Detail: "state " + cur.oper}}
+	}
+
+	var events []event
+	if prev.up != cur.up {
+		action := "admin-down"
+		if cur.up {
+			action = "admin-up"
+		}
+		events = append(events, event{Time: now, Type: typeLink, Action: action, Iface: cur.name})
+	}
+	if prev.carrier != cur.carrier {
+		action := "carrier-down"
+		if cur.carrier {
+			action = "carrier-up"
+		}
+		events = append(events, event{Time: now, Type: typeLink, Action: action, Iface: cur.name})
+	}
+	if prev.mtu != cur.mtu {
+		events = append(events, event{Time: now, Type: typeLink, Action: "mtu-changed", Iface: cur.name, Detail: fmt.Sprintf("%d -> %d", prev.mtu, cur.mtu)})
+	}
+	if prev.name != cur.name {
+		events = append(events, event{Time: now, Type: typeLink, Action: "renamed", Iface: cur.name, Detail: prev.name + " -> " + cur.name})
+	}
+	if len(events) == 0 && prev.oper != cur.oper {
+		events = append(events, event{Time: now, Type: typeLink, Action: "state-changed", Iface: cur.name, Detail: prev.oper + " -> " + cur.oper})
+	}
+	return events
+}
+
+// 默认路由单独标记，便于过滤网关变化
+func routeEvent(u netlink.RouteUpdate, iface string) event {
+	isDefault := u.Dst == nil || (u.Dst.IP.IsUnspecified() && isZeroMask(u.Dst.Mask))
+
+	action := "removed"
+	if u.Type == unix.RTM_NEWROUTE {
+		action = "added"
+	}
+	if isDefault {
+		action = "default-" + action
+	}
+
+	detail := "default"
+	if u.Dst != nil && !isDefault {
+		detail = u.Dst.String()
+	}
+	if u.Gw != nil {
+		detail += " via " + u.Gw.String()
+	}
+	if u.Table != 0 && u.Table != unix.RT_TABLE_MAIN {
+		detail += fmt.Sprintf(" table %d", u.Table)
+	}
+	return event{Time: time.Now(), Type: typeRoute, Action: action, Iface: iface, Detail: detail}
+}
+
+func isZeroMask(mask net.IPMask) bool {
+	ones, _ := mask.Size()
+	return ones == 0
+}
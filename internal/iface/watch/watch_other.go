@@ -0,0 +1,13 @@
+//go:build !linux
+
+package watch
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+func subscribe(ctx context.Context, events chan<- event) error {
+	return fmt.Errorf("iface watch is not supported on %s", runtime.GOOS)
+}
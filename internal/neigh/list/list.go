@@ -0,0 +1,121 @@
+package list
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"nctl/interfaces"
+	"nctl/internal/utils"
+	"nctl/internal/utils/output"
+)
+
+var (
+	listFamily int
+	listIface  string
+	listState  string
+)
+
+// 结构化输出中的邻居信息，字段名保持稳定
+type neighRecord struct {
+	Family string  `json:"family" yaml:"family"`
+	IP     string  `json:"ip" yaml:"ip"`
+	MAC    *string `json:"mac" yaml:"mac"`
+	Iface  string  `json:"iface" yaml:"iface"`
+	State  string  `json:"state" yaml:"state"`
+	Router bool    `json:"router" yaml:"router"`
+}
+
+func List() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List ARP and NDP neighbor entries",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := output.Format(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return
+			}
+
+			filter := interfaces.NeighFilter{Family: listFamily, Iface: listIface}
+			if listState != "" {
+				if filter.State, err = interfaces.ParseNeighState(listState, interfaces.NeighStates); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+					return
+				}
+			}
+
+			neighs, err := utils.NeighUtils().ListNeighs(filter)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return
+			}
+
+			if err := printNeighs(cmd, format, neighs); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+			}
+		},
+	}
+
+	cmd.Flags().IntVarP(&listFamily, "family", "f", 0, "Only show entries of this address family (value: 4, 6)")
+	cmd.Flags().StringVarP(&listIface, "dev", "d", "", "Only show entries on this interface")
+	cmd.Flags().StringVarP(&listState, "state", "s", "", "Only show entries in this state (reachable, stale, failed, permanent ...)")
+
+	return cmd
+}
+
+func toRecord(n interfaces.Neigh) neighRecord {
+	record := neighRecord{
+		Family: "ipv" + strconv.Itoa(n.Family),
+		IP:     n.IP.String(),
+		Iface:  n.Iface,
+		State:  n.State,
+		Router: n.Router,
+	}
+	if n.MAC != nil {
+		mac := n.MAC.String()
+		record.MAC = &mac
+	}
+	return record
+}
+
+func orDash(s *string) string {
+	if s == nil {
+		return "-"
+	}
+	return *s
+}
+
+func printNeighs(cmd *cobra.Command, format string, neighs []interfaces.Neigh) error {
+	records := make([]neighRecord, 0, len(neighs))
+	for _, n := range neighs {
+		records = append(records, toRecord(n))
+	}
+
+	switch format {
+	case output.JSON, output.YAML:
+		return output.Write(cmd.OutOrStdout(), format, records)
+	case output.CSV:
+		var rows [][]string
+		for _, r := range records {
+			rows = append(rows, []string{r.Family, r.IP, orDash(r.MAC), r.Iface, r.State, strconv.FormatBool(r.Router)})
+		}
+		return output.WriteCSV(cmd.OutOrStdout(), []string{"family", "ip", "mac", "iface", "state", "router"}, rows)
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.AppendHeader(table.Row{"ADDRESS", "MAC", "DEVICE", "STATE", "ROUTER"})
+	for _, r := range records {
+		router := ""
+		if r.Router {
+			router = "yes"
+		}
+		t.AppendRow(table.Row{r.IP, orDash(r.MAC), r.Iface, r.State, router})
+	}
+	t.Render()
+	return nil
+}
@@ -0,0 +1,29 @@
+package neigh
+
+import (
+	"nctl/internal/neigh/list"
+	"nctl/internal/neigh/set"
+
+	"github.com/spf13/cobra"
+)
+
+var neighCmd = &cobra.Command{
+	Use:   "neigh",
+	Short: "Neighbor (ARP / NDP) table management",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// 注册所有 neigh 下的子命令
+func RegisterNeighCommands(rootCmd *cobra.Command) {
+	// 挂载 neigh 子命令
+	rootCmd.AddCommand(neighCmd)
+
+	// 挂载 neigh list 命令
+	neighCmd.AddCommand(list.List())
+	// 挂载 neigh add/del/flush 命令
+	neighCmd.AddCommand(set.Add())
+	neighCmd.AddCommand(set.Del())
+	neighCmd.AddCommand(set.Flush())
+}
@@ -0,0 +1,128 @@
+package set
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/spf13/cobra"
+
+	"nctl/interfaces"
+	"nctl/internal/utils"
+)
+
+func parseIP(s string) (net.IP, int, error) {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, 0, fmt.Errorf("invalid address '%s'", s)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4, 4, nil
+	}
+	return ip, 6, nil
+}
+
+func Add() *cobra.Command {
+	var mac, dev, state string
+	var router bool
+	cmd := &cobra.Command{
+		Use:   "add <address>",
+		Short: "Add a static neighbor entry or overwrite an existing one",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			n := &interfaces.Neigh{Iface: dev, Router: router}
+			var err error
+			if n.IP, n.Family, err = parseIP(args[0]); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			if n.MAC, err = net.ParseMAC(mac); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: invalid MAC address '%s'\n", mac)
+				return
+			}
+			if n.State, err = interfaces.ParseNeighState(state, interfaces.NeighAddStates); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			if err := utils.IfaceUtils().IsExistingIface(dev); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+
+			if err := utils.NeighUtils().AddNeigh(n); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Added neighbor %s lladdr %s on %s (%s)\n", n.IP, n.MAC, n.Iface, n.State)
+		},
+	}
+
+	cmd.Flags().StringVarP(&mac, "mac", "m", "", "Link layer address of the neighbor (required)")
+	cmd.Flags().StringVarP(&dev, "dev", "d", "", "Interface the neighbor is reachable on (required)")
+	cmd.Flags().StringVarP(&state, "state", "s", "permanent", "Entry state (value: permanent, noarp, reachable, stale)")
+	cmd.Flags().BoolVar(&router, "router", false, "Mark the neighbor as a router (ipv6 only)")
+	cmd.MarkFlagRequired("mac")
+	cmd.MarkFlagRequired("dev")
+
+	return cmd
+}
+
+func Del() *cobra.Command {
+	var dev string
+	cmd := &cobra.Command{
+		Use:   "del <address>",
+		Short: "Delete a neighbor entry",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			n := &interfaces.Neigh{Iface: dev}
+			var err error
+			if n.IP, n.Family, err = parseIP(args[0]); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+
+			if err := utils.NeighUtils().DelNeigh(n); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Deleted neighbor %s\n", n.IP)
+		},
+	}
+
+	cmd.Flags().StringVarP(&dev, "dev", "d", "", "Interface of the entry (default: every interface with this address)")
+
+	return cmd
+}
+
+func Flush() *cobra.Command {
+	var family int
+	var dev, state string
+	cmd := &cobra.Command{
+		Use:   "flush",
+		Short: "Delete dynamic neighbor entries",
+		Long: "Delete dynamic neighbor entries.\n\n" +
+			"Static entries (permanent, noarp) are kept unless --state selects them explicitly.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			filter := interfaces.NeighFilter{Family: family, Iface: dev}
+			if state != "" {
+				var err error
+				if filter.State, err = interfaces.ParseNeighState(state, interfaces.NeighStates); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+					return
+				}
+			}
+
+			count, err := utils.NeighUtils().FlushNeighs(filter)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Flushed %d neighbor entries\n", count)
+		},
+	}
+
+	cmd.Flags().IntVarP(&family, "family", "f", 0, "Only flush entries of this address family (value: 4, 6)")
+	cmd.Flags().StringVarP(&dev, "dev", "d", "", "Only flush entries on this interface")
+	cmd.Flags().StringVarP(&state, "state", "s", "", "Only flush entries in this state (reachable, stale, failed, permanent ...)")
+
+	return cmd
+}
@@ -0,0 +1,274 @@
+package dns
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// 支持查询的记录类型
+var queryTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"NS":    dnsmessage.TypeNS,
+	"PTR":   dnsmessage.TypePTR,
+	"SOA":   dnsmessage.TypeSOA,
+	"SRV":   dnsmessage.TypeSRV,
+	"TXT":   dnsmessage.TypeTXT,
+}
+
+var rcodeNames = map[dnsmessage.RCode]string{
+	dnsmessage.RCodeSuccess:        "NOERROR",
+	dnsmessage.RCodeFormatError:    "FORMERR",
+	dnsmessage.RCodeServerFailure:  "SERVFAIL",
+	dnsmessage.RCodeNameError:      "NXDOMAIN",
+	dnsmessage.RCodeNotImplemented: "NOTIMP",
+	dnsmessage.RCodeRefused:        "REFUSED",
+}
+
+// EDNS0 中声明的 UDP 报文大小
+const ednsUDPSize = 1232
+
+// 应答中的一条记录
+type Answer struct {
+	Name string
+	Type string
+	TTL  uint32
+	Data string
+}
+
+// 向一个服务器查询的结果
+type Result struct {
+	Server string
+	// udp 或 tcp，UDP 应答被截断后会改用 tcp 重试
+	Proto     string
+	Rcode     string
+	Latency   time.Duration
+	Truncated bool
+	Answers   []Answer
+}
+
+func typeName(t dnsmessage.Type) string {
+	return strings.TrimPrefix(t.String(), "Type")
+}
+
+func rcodeName(c dnsmessage.RCode) string {
+	if s, ok := rcodeNames[c]; ok {
+		return s
+	}
+	return strconv.Itoa(int(c))
+}
+
+// 解析 ip 或 ip:port，未指定端口时使用 53
+func serverAddr(s string) (string, error) {
+	if ip := net.ParseIP(strings.Trim(s, "[]")); ip != nil {
+		return net.JoinHostPort(ip.String(), "53"), nil
+	}
+	host, port, err := net.SplitHostPort(s)
+	if err != nil || net.ParseIP(host) == nil {
+		return "", fmt.Errorf("invalid DNS server '%s', ip or ip:port required", s)
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", fmt.Errorf("invalid DNS server port '%s'", port)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// 地址转换为 in-addr.arpa 或 ip6.arpa 形式的反向查询名称
+func reverseName(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", ip4[3], ip4[2], ip4[1], ip4[0])
+	}
+	digits := hex.EncodeToString(ip.To16())
+	var b strings.Builder
+	for i := len(digits) - 1; i >= 0; i-- {
+		b.WriteByte(digits[i])
+		b.WriteByte('.')
+	}
+	return b.String() + "ip6.arpa."
+}
+
+func buildQuery(id uint16, name dnsmessage.Name, qtype dnsmessage.Type) ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: name, Type: qtype, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	if err := b.StartAdditionals(); err != nil {
+		return nil, err
+	}
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(ednsUDPSize, dnsmessage.RCodeSuccess, false); err != nil {
+		return nil, err
+	}
+	if err := b.OPTResource(opt, dnsmessage.OPTResource{}); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+// 向 server 查询一条记录，UDP 应答被截断时自动改用 TCP
+func Query(server, name string, qtype dnsmessage.Type, useTCP bool, timeout time.Duration) (*Result, error) {
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name '%s': %w", name, err)
+	}
+	id := uint16(rand.Uint32())
+	query, err := buildQuery(id, qname, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Server: server, Proto: "udp"}
+	start := time.Now()
+	var resp []byte
+	if !useTCP {
+		resp, err = exchangeUDP(server, query, id, timeout)
+		if err != nil {
+			return nil, err
+		}
+		// header 第 3 字节的 TC 位
+		if len(resp) > 2 && resp[2]&0x02 != 0 {
+			result.Truncated = true
+			useTCP = true
+		}
+	}
+	if useTCP {
+		result.Proto = "tcp"
+		if resp, err = exchangeTCP(server, query, timeout); err != nil {
+			return nil, err
+		}
+	}
+	result.Latency = time.Since(start)
+
+	if err := parseResponse(resp, id, qname, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// 忽略 ID 不匹配的报文，直到超时
+func exchangeUDP(server string, query []byte, id uint16, timeout time.Duration) ([]byte, error) {
+	conn, err := net.DialTimeout("udp", server, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		if n >= 2 && binary.BigEndian.Uint16(buf) == id {
+			return buf[:n], nil
+		}
+	}
+}
+
+// TCP 报文前带 2 字节长度
+func exchangeTCP(server string, query []byte, timeout time.Duration) ([]byte, error) {
+	conn, err := net.DialTimeout("tcp", server, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	msg := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	if _, err := conn.Write(append(msg, query...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func parseResponse(resp []byte, id uint16, qname dnsmessage.Name, result *Result) error {
+	var p dnsmessage.Parser
+	header, err := p.Start(resp)
+	if err != nil {
+		return fmt.Errorf("malformed response: %w", err)
+	}
+	if header.ID != id || !header.Response {
+		return errors.New("response does not match the query")
+	}
+	questions, err := p.AllQuestions()
+	if err != nil {
+		return fmt.Errorf("malformed response: %w", err)
+	}
+	if len(questions) > 0 && !strings.EqualFold(questions[0].Name.String(), qname.String()) {
+		return errors.New("response does not match the query")
+	}
+	result.Rcode = rcodeName(header.RCode)
+
+	answers, err := p.AllAnswers()
+	if err != nil {
+		return fmt.Errorf("malformed response: %w", err)
+	}
+	for _, rr := range answers {
+		result.Answers = append(result.Answers, Answer{
+			Name: rr.Header.Name.String(),
+			Type: typeName(rr.Header.Type),
+			TTL:  rr.Header.TTL,
+			Data: formatBody(rr.Body),
+		})
+	}
+	return nil
+}
+
+// 按 dig 的写法描述记录内容
+func formatBody(body dnsmessage.ResourceBody) string {
+	switch r := body.(type) {
+	case *dnsmessage.AResource:
+		return net.IP(r.A[:]).String()
+	case *dnsmessage.AAAAResource:
+		return net.IP(r.AAAA[:]).String()
+	case *dnsmessage.CNAMEResource:
+		return r.CNAME.String()
+	case *dnsmessage.NSResource:
+		return r.NS.String()
+	case *dnsmessage.PTRResource:
+		return r.PTR.String()
+	case *dnsmessage.MXResource:
+		return fmt.Sprintf("%d %s", r.Pref, r.MX)
+	case *dnsmessage.SRVResource:
+		return fmt.Sprintf("%d %d %d %s", r.Priority, r.Weight, r.Port, r.Target)
+	case *dnsmessage.SOAResource:
+		return fmt.Sprintf("%s %s %d %d %d %d %d", r.NS, r.MBox, r.Serial, r.Refresh, r.Retry, r.Expire, r.MinTTL)
+	case *dnsmessage.TXTResource:
+		var parts []string
+		for _, s := range r.TXT {
+			parts = append(parts, strconv.Quote(s))
+		}
+		return strings.Join(parts, " ")
+	case *dnsmessage.UnknownResource:
+		return fmt.Sprintf(`\# %d %x`, len(r.Data), r.Data)
+	}
+	return ""
+}
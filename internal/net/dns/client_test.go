@@ -0,0 +1,299 @@
+package dns
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// 本地的 DNS 桩服务器，UDP 和 TCP 监听同一个端口
+type stubServer struct {
+	addr string
+	udp  net.PacketConn
+	tcp  net.Listener
+	// 根据查询生成应答，UDP 上可以依次发送多个报文
+	handle func(id uint16, q dnsmessage.Question, tcp bool) [][]byte
+}
+
+func newStub(t *testing.T, handle func(id uint16, q dnsmessage.Question, tcp bool) [][]byte) *stubServer {
+	t.Helper()
+	s := &stubServer{handle: handle}
+	// 随机端口上 TCP 可能已被占用，换一个端口重试
+	for i := 0; i < 10 && s.tcp == nil; i++ {
+		udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+		if err != nil {
+			udp.Close()
+			continue
+		}
+		s.udp, s.tcp, s.addr = udp, tcp, udp.LocalAddr().String()
+	}
+	if s.tcp == nil {
+		t.Fatal("failed to listen on a free udp/tcp port")
+	}
+	t.Cleanup(func() {
+		s.udp.Close()
+		s.tcp.Close()
+	})
+	go s.serveUDP(t)
+	go s.serveTCP(t)
+	return s
+}
+
+func (s *stubServer) question(t *testing.T, msg []byte) (uint16, dnsmessage.Question, bool) {
+	var p dnsmessage.Parser
+	h, err := p.Start(msg)
+	if err != nil {
+		t.Errorf("stub: malformed query: %v", err)
+		return 0, dnsmessage.Question{}, false
+	}
+	q, err := p.Question()
+	if err != nil {
+		t.Errorf("stub: malformed question: %v", err)
+		return 0, dnsmessage.Question{}, false
+	}
+	return h.ID, q, true
+}
+
+func (s *stubServer) serveUDP(t *testing.T) {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		id, q, ok := s.question(t, buf[:n])
+		if !ok {
+			continue
+		}
+		for _, resp := range s.handle(id, q, false) {
+			s.udp.WriteTo(resp, addr)
+		}
+	}
+}
+
+func (s *stubServer) serveTCP(t *testing.T) {
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			var length [2]byte
+			if _, err := io.ReadFull(conn, length[:]); err != nil {
+				return
+			}
+			msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+			if _, err := io.ReadFull(conn, msg); err != nil {
+				return
+			}
+			id, q, ok := s.question(t, msg)
+			if !ok {
+				return
+			}
+			for _, resp := range s.handle(id, q, true) {
+				conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(resp))), resp...))
+			}
+		}()
+	}
+}
+
+// 用 Builder 生成应答
+func reply(t *testing.T, h dnsmessage.Header, q dnsmessage.Question, answers ...dnsmessage.Resource) []byte {
+	t.Helper()
+	h.Response = true
+	b := dnsmessage.NewBuilder(nil, h)
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Question(q); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.StartAnswers(); err != nil {
+		t.Fatal(err)
+	}
+	for _, rr := range answers {
+		rr.Header.Class = dnsmessage.ClassINET
+		var err error
+		switch body := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			err = b.AResource(rr.Header, *body)
+		case *dnsmessage.AAAAResource:
+			err = b.AAAAResource(rr.Header, *body)
+		case *dnsmessage.MXResource:
+			err = b.MXResource(rr.Header, *body)
+		case *dnsmessage.TXTResource:
+			err = b.TXTResource(rr.Header, *body)
+		case *dnsmessage.SRVResource:
+			err = b.SRVResource(rr.Header, *body)
+		case *dnsmessage.PTRResource:
+			err = b.PTRResource(rr.Header, *body)
+		default:
+			t.Fatalf("unsupported body %T", body)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func rr(name string, typ dnsmessage.Type, ttl uint32, body dnsmessage.ResourceBody) dnsmessage.Resource {
+	return dnsmessage.Resource{Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: typ, TTL: ttl}, Body: body}
+}
+
+func TestQueryRecordTypes(t *testing.T) {
+	ptrName := reverseName(net.ParseIP("192.0.2.10"))
+	records := map[dnsmessage.Type][]dnsmessage.Resource{
+		dnsmessage.TypeA: {
+			rr("example.test.", dnsmessage.TypeA, 300, &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}}),
+			rr("example.test.", dnsmessage.TypeA, 300, &dnsmessage.AResource{A: [4]byte{192, 0, 2, 2}}),
+		},
+		dnsmessage.TypeAAAA: {
+			rr("example.test.", dnsmessage.TypeAAAA, 60, &dnsmessage.AAAAResource{AAAA: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}}),
+		},
+		dnsmessage.TypeMX: {
+			rr("example.test.", dnsmessage.TypeMX, 3600, &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mail.example.test.")}),
+		},
+		dnsmessage.TypeTXT: {
+			rr("example.test.", dnsmessage.TypeTXT, 120, &dnsmessage.TXTResource{TXT: []string{"v=spf1 -all", `say "hi"`}}),
+		},
+		dnsmessage.TypeSRV: {
+			rr("example.test.", dnsmessage.TypeSRV, 30, &dnsmessage.SRVResource{Priority: 1, Weight: 5, Port: 5060, Target: dnsmessage.MustNewName("sip.example.test.")}),
+		},
+		dnsmessage.TypePTR: {
+			rr(ptrName, dnsmessage.TypePTR, 86400, &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("host.example.test.")}),
+		},
+	}
+	stub := newStub(t, func(id uint16, q dnsmessage.Question, tcp bool) [][]byte {
+		return [][]byte{reply(t, dnsmessage.Header{ID: id}, q, records[q.Type]...)}
+	})
+
+	tests := []struct {
+		name  string
+		qtype dnsmessage.Type
+		want  []Answer
+	}{
+		{"example.test.", dnsmessage.TypeA, []Answer{
+			{"example.test.", "A", 300, "192.0.2.1"},
+			{"example.test.", "A", 300, "192.0.2.2"},
+		}},
+		{"example.test.", dnsmessage.TypeAAAA, []Answer{{"example.test.", "AAAA", 60, "2001:db8::1"}}},
+		{"example.test.", dnsmessage.TypeMX, []Answer{{"example.test.", "MX", 3600, "10 mail.example.test."}}},
+		{"example.test.", dnsmessage.TypeTXT, []Answer{{"example.test.", "TXT", 120, `"v=spf1 -all" "say \"hi\""`}}},
+		{"example.test.", dnsmessage.TypeSRV, []Answer{{"example.test.", "SRV", 30, "1 5 5060 sip.example.test."}}},
+		{ptrName, dnsmessage.TypePTR, []Answer{{ptrName, "PTR", 86400, "host.example.test."}}},
+	}
+	for _, tt := range tests {
+		t.Run(typeName(tt.qtype), func(t *testing.T) {
+			res, err := Query(stub.addr, tt.name, tt.qtype, false, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Rcode != "NOERROR" || res.Proto != "udp" || res.Truncated {
+				t.Fatalf("result = %+v, want NOERROR over udp", res)
+			}
+			if !reflect.DeepEqual(res.Answers, tt.want) {
+				t.Fatalf("answers = %v, want %v", res.Answers, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryNXDOMAIN(t *testing.T) {
+	stub := newStub(t, func(id uint16, q dnsmessage.Question, tcp bool) [][]byte {
+		return [][]byte{reply(t, dnsmessage.Header{ID: id, RCode: dnsmessage.RCodeNameError}, q)}
+	})
+	res, err := Query(stub.addr, "missing.test.", dnsmessage.TypeA, false, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rcode != "NXDOMAIN" || len(res.Answers) != 0 {
+		t.Fatalf("result = %+v, want NXDOMAIN without answers", res)
+	}
+}
+
+func TestQueryTruncatedFallsBackToTCP(t *testing.T) {
+	a := rr("big.test.", dnsmessage.TypeA, 60, &dnsmessage.AResource{A: [4]byte{198, 51, 100, 7}})
+	stub := newStub(t, func(id uint16, q dnsmessage.Question, tcp bool) [][]byte {
+		if !tcp {
+			return [][]byte{reply(t, dnsmessage.Header{ID: id, Truncated: true}, q)}
+		}
+		return [][]byte{reply(t, dnsmessage.Header{ID: id}, q, a)}
+	})
+	res, err := Query(stub.addr, "big.test.", dnsmessage.TypeA, false, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Proto != "tcp" || !res.Truncated {
+		t.Fatalf("result = %+v, want truncated udp retried over tcp", res)
+	}
+	if want := []Answer{{"big.test.", "A", 60, "198.51.100.7"}}; !reflect.DeepEqual(res.Answers, want) {
+		t.Fatalf("answers = %v, want %v", res.Answers, want)
+	}
+}
+
+func TestQueryIgnoresMismatchedID(t *testing.T) {
+	wrong := rr("id.test.", dnsmessage.TypeA, 60, &dnsmessage.AResource{A: [4]byte{203, 0, 113, 66}})
+	right := rr("id.test.", dnsmessage.TypeA, 60, &dnsmessage.AResource{A: [4]byte{203, 0, 113, 1}})
+	stub := newStub(t, func(id uint16, q dnsmessage.Question, tcp bool) [][]byte {
+		return [][]byte{
+			reply(t, dnsmessage.Header{ID: id + 1}, q, wrong),
+			reply(t, dnsmessage.Header{ID: id}, q, right),
+		}
+	})
+	res, err := Query(stub.addr, "id.test.", dnsmessage.TypeA, false, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Answers) != 1 || res.Answers[0].Data != "203.0.113.1" {
+		t.Fatalf("answers = %v, want only the reply with the query ID", res.Answers)
+	}
+}
+
+func TestRunQueriesDisagree(t *testing.T) {
+	answer := func(ip [4]byte) func(uint16, dnsmessage.Question, bool) [][]byte {
+		return func(id uint16, q dnsmessage.Question, tcp bool) [][]byte {
+			return [][]byte{reply(t, dnsmessage.Header{ID: id}, q, rr("cmp.test.", dnsmessage.TypeA, 60, &dnsmessage.AResource{A: ip}))}
+		}
+	}
+	good := answer([4]byte{192, 0, 2, 1})
+	a := newStub(t, good)
+	b := newStub(t, good)
+	c := newStub(t, answer([4]byte{192, 0, 2, 99}))
+
+	record := runQueries("cmp.test.", dnsmessage.TypeA, []string{a.addr, c.addr, b.addr}, false, time.Second)
+	if record.Consistent {
+		t.Fatal("Consistent = true, want false")
+	}
+	var agrees []bool
+	for _, r := range record.Results {
+		if r.Error != nil {
+			t.Fatalf("%s: %s", r.Server, *r.Error)
+		}
+		agrees = append(agrees, r.Agrees)
+	}
+	if want := []bool{true, false, true}; !reflect.DeepEqual(agrees, want) {
+		t.Fatalf("agrees = %v, want %v", agrees, want)
+	}
+
+	// 同样的应答在 TCP 上一致
+	record = runQueries("cmp.test.", dnsmessage.TypeA, []string{a.addr, b.addr}, true, time.Second)
+	if !record.Consistent || !record.Results[0].Agrees || !record.Results[1].Agrees || record.Results[0].Proto != "tcp" {
+		t.Fatalf("record = %+v, want consistent tcp results", record)
+	}
+}
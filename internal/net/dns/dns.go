@@ -1 +1,355 @@
 package dns
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"nctl/internal/utils"
+	"nctl/internal/utils/output"
+)
+
+var (
+	queryType    string
+	queryServers []string
+	queryIface   string
+	queryTCP     bool
+	queryTimeout time.Duration
+)
+
+// 结构化输出中的查询结果，字段名保持稳定
+type queryRecord struct {
+	Name       string         `json:"name" yaml:"name"`
+	Type       string         `json:"type" yaml:"type"`
+	Consistent bool           `json:"consistent" yaml:"consistent"`
+	Results    []serverRecord `json:"results" yaml:"results"`
+}
+
+type serverRecord struct {
+	Server    string         `json:"server" yaml:"server"`
+	Proto     string         `json:"proto" yaml:"proto"`
+	Rcode     *string        `json:"rcode" yaml:"rcode"`
+	LatencyMs *float64       `json:"latency_ms" yaml:"latency_ms"`
+	Truncated bool           `json:"truncated" yaml:"truncated"`
+	Agrees    bool           `json:"agrees" yaml:"agrees"`
+	Answers   []answerRecord `json:"answers" yaml:"answers"`
+	Error     *string        `json:"error" yaml:"error"`
+}
+
+type answerRecord struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+	TTL  uint32 `json:"ttl" yaml:"ttl"`
+	Data string `json:"data" yaml:"data"`
+}
+
+func Dns() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dns",
+		Short: "DNS resolver diagnostics",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	// 挂载 net dns query 命令
+	cmd.AddCommand(query())
+	return cmd
+}
+
+func query() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "query <name>",
+		Short: "Query DNS servers directly and compare their answers",
+		Long: "Query DNS servers directly and compare their answers.\n\n" +
+			"Without --server or --iface the servers in /etc/resolv.conf are used, falling back to the\n" +
+			"servers configured on every interface. An address as name is looked up as PTR.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := output.Format(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return
+			}
+
+			name, qtype, err := parseQuestion(args[0], queryType)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			servers, err := selectServers()
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+
+			record := runQueries(name, qtype, servers, queryTCP, queryTimeout)
+			if err := printQuery(cmd, format, record); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&queryType, "type", "t", "", "Record type (value: A, AAAA, CNAME, MX, NS, PTR, SOA, SRV, TXT) (default A, PTR for an address)")
+	cmd.Flags().StringSliceVarP(&queryServers, "server", "s", nil, "DNS server to query as ip or ip:port, can be repeated")
+	cmd.Flags().StringVarP(&queryIface, "iface", "i", "", "Query every DNS server configured on this interface")
+	cmd.Flags().BoolVar(&queryTCP, "tcp", false, "Query over TCP instead of UDP")
+	cmd.Flags().DurationVarP(&queryTimeout, "timeout", "w", 3*time.Second, "Time to wait for each server")
+
+	return cmd
+}
+
+// 得到完整的查询名称和类型
+func parseQuestion(name, t string) (string, dnsmessage.Type, error) {
+	if t == "" {
+		t = "A"
+		if net.ParseIP(name) != nil {
+			t = "PTR"
+		}
+	}
+	qtype, ok := queryTypes[strings.ToUpper(t)]
+	if !ok {
+		return "", 0, fmt.Errorf("unsupported record type '%s' (value: A, AAAA, CNAME, MX, NS, PTR, SOA, SRV, TXT)", t)
+	}
+	if ip := net.ParseIP(name); ip != nil && qtype == dnsmessage.TypePTR {
+		return reverseName(ip), qtype, nil
+	}
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name, qtype, nil
+}
+
+// 按 --server、--iface、系统配置的顺序确定要查询的服务器
+func selectServers() ([]string, error) {
+	var servers []string
+	switch {
+	case len(queryServers) > 0:
+		servers = queryServers
+	case queryIface != "":
+		ips, err := utils.IfaceUtils().GetDNSs(queryIface)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			servers = append(servers, ip.String())
+		}
+		if len(servers) == 0 {
+			return nil, fmt.Errorf("no DNS servers are configured on %s", queryIface)
+		}
+	default:
+		servers = SystemServers()
+		if len(servers) == 0 {
+			return nil, fmt.Errorf("no DNS servers found, use --server or --iface")
+		}
+	}
+
+	var addrs []string
+	for _, s := range servers {
+		addr, err := serverAddr(s)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// /etc/resolv.conf 中的服务器，不存在时使用所有接口上配置的服务器
+func SystemServers() []string {
+	var servers []string
+	if f, err := os.Open("/etc/resolv.conf"); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "nameserver" {
+				servers = append(servers, fields[1])
+			}
+		}
+		return servers
+	}
+
+	ifaces, _ := net.Interfaces()
+	seen := map[string]bool{}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		ips, _ := utils.IfaceUtils().GetDNSs(iface.Name)
+		for _, ip := range ips {
+			if !seen[ip.String()] {
+				seen[ip.String()] = true
+				servers = append(servers, ip.String())
+			}
+		}
+	}
+	return servers
+}
+
+// 并发查询所有服务器，并与多数服务器的应答比较
+func runQueries(name string, qtype dnsmessage.Type, servers []string, useTCP bool, timeout time.Duration) queryRecord {
+	record := queryRecord{Name: name, Type: typeName(qtype), Consistent: true, Results: make([]serverRecord, len(servers))}
+
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record.Results[i] = toServerRecord(server, name, qtype, useTCP, timeout)
+		}()
+	}
+	wg.Wait()
+
+	// 出错的服务器不参与比较
+	counts := map[string]int{}
+	keys := make([]string, len(servers))
+	for i, r := range record.Results {
+		if r.Error == nil {
+			keys[i] = answerKey(r)
+			counts[keys[i]]++
+		}
+	}
+	majority := ""
+	for key, n := range counts {
+		if n > counts[majority] || (n == counts[majority] && key < majority) {
+			majority = key
+		}
+	}
+	for i := range record.Results {
+		record.Results[i].Agrees = record.Results[i].Error == nil && keys[i] == majority
+	}
+	record.Consistent = len(counts) <= 1
+	return record
+}
+
+func toServerRecord(server, name string, qtype dnsmessage.Type, useTCP bool, timeout time.Duration) serverRecord {
+	record := serverRecord{Server: server, Proto: "udp", Answers: []answerRecord{}}
+	if useTCP {
+		record.Proto = "tcp"
+	}
+
+	result, err := Query(server, name, qtype, useTCP, timeout)
+	if err != nil {
+		msg := err.Error()
+		record.Error = &msg
+		return record
+	}
+
+	ms := float64(result.Latency.Microseconds()) / 1000
+	record.Proto = result.Proto
+	record.Rcode = &result.Rcode
+	record.LatencyMs = &ms
+	record.Truncated = result.Truncated
+	for _, a := range result.Answers {
+		record.Answers = append(record.Answers, answerRecord{Name: a.Name, Type: a.Type, TTL: a.TTL, Data: a.Data})
+	}
+	return record
+}
+
+// 比较时忽略 TTL 和记录顺序
+func answerKey(r serverRecord) string {
+	var parts []string
+	for _, a := range r.Answers {
+		parts = append(parts, a.Name+" "+a.Type+" "+a.Data)
+	}
+	sort.Strings(parts)
+	return *r.Rcode + "|" + strings.Join(parts, "|")
+}
+
+func printQuery(cmd *cobra.Command, format string, record queryRecord) error {
+	switch format {
+	case output.JSON, output.YAML:
+		return output.Write(cmd.OutOrStdout(), format, record)
+	case output.CSV:
+		var rows [][]string
+		for _, r := range record.Results {
+			latency := ""
+			if r.LatencyMs != nil {
+				latency = strconv.FormatFloat(*r.LatencyMs, 'f', 3, 64)
+			}
+			base := []string{r.Server, r.Proto, deref(r.Rcode), latency, strconv.FormatBool(r.Agrees)}
+			if r.Error != nil || len(r.Answers) == 0 {
+				rows = append(rows, append(base, "", "", "", "", deref(r.Error)))
+			}
+			for _, a := range r.Answers {
+				rows = append(rows, append(base, a.Name, a.Type, strconv.Itoa(int(a.TTL)), a.Data, ""))
+			}
+		}
+		return output.WriteCSV(cmd.OutOrStdout(), []string{"server", "proto", "rcode", "latency_ms", "agrees", "name", "type", "ttl", "data", "error"}, rows)
+	}
+
+	compare := len(record.Results) > 1
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	header := table.Row{"SERVER", "RCODE", "LATENCY", "NAME", "TYPE", "TTL", "DATA"}
+	if compare {
+		header = append(header, "AGREES")
+	}
+	t.AppendHeader(header)
+	for _, r := range record.Results {
+		server := r.Server
+		if r.Proto == "tcp" {
+			server += " (tcp)"
+		}
+		agrees := "yes"
+		if !r.Agrees {
+			agrees = "NO"
+		}
+
+		rows := []table.Row{}
+		switch {
+		case r.Error != nil:
+			rows = append(rows, table.Row{server, "ERROR", "-", "-", "-", "-", *r.Error})
+		case len(r.Answers) == 0:
+			rows = append(rows, table.Row{server, *r.Rcode, formatLatency(r.LatencyMs), "-", "-", "-", "-"})
+		}
+		for i, a := range r.Answers {
+			row := table.Row{"", "", "", a.Name, a.Type, a.TTL, a.Data}
+			if i == 0 {
+				row[0], row[1], row[2] = server, *r.Rcode, formatLatency(r.LatencyMs)
+			}
+			rows = append(rows, row)
+		}
+		for i, row := range rows {
+			if compare {
+				if i == 0 {
+					row = append(row, agrees)
+				} else {
+					row = append(row, "")
+				}
+			}
+			t.AppendRow(row)
+		}
+		t.AppendSeparator()
+	}
+	t.Render()
+
+	if compare && !record.Consistent {
+		fmt.Fprintf(cmd.OutOrStdout(), "Warning: servers disagree on %s %s\n", record.Name, record.Type)
+	}
+	return nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func formatLatency(ms *float64) string {
+	if ms == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.3f ms", *ms)
+}
@@ -0,0 +1,39 @@
+package net
+
+import (
+	"nctl/internal/net/dns"
+	"nctl/internal/net/port"
+	"nctl/internal/net/proxy"
+	"nctl/internal/net/status"
+	"nctl/internal/net/test"
+	"nctl/internal/net/vpn"
+
+	"github.com/spf13/cobra"
+)
+
+var netCmd = &cobra.Command{
+	Use:   "net",
+	Short: "Network diagnostics and services",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// 注册所有 net 下的子命令
+func RegisterNetCommands(rootCmd *cobra.Command) {
+	// 挂载 net 子命令
+	rootCmd.AddCommand(netCmd)
+
+	// 挂载 net port 命令
+	netCmd.AddCommand(port.Port())
+	// 挂载 net dns 系列命令
+	netCmd.AddCommand(dns.Dns())
+	// 挂载 net test 命令
+	netCmd.AddCommand(test.Test())
+	// 挂载 net status 命令
+	netCmd.AddCommand(status.Status())
+	// 挂载 net proxy 系列命令
+	netCmd.AddCommand(proxy.Proxy())
+	// 挂载 net vpn 系列命令
+	netCmd.AddCommand(vpn.Vpn())
+}
@@ -0,0 +1,275 @@
+package port
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"nctl/internal/utils/output"
+)
+
+// 端口探测的结果
+const (
+	StateOpen     = "open"
+	StateClosed   = "closed"
+	StateFiltered = "filtered"
+	// UDP 没有收到任何回应时无法区分开放和过滤
+	StateOpenFiltered = "open|filtered"
+)
+
+var (
+	checkUDP         bool
+	checkTimeout     time.Duration
+	checkConcurrency int
+)
+
+// 一个待探测的地址和端口
+type probe struct {
+	host string
+	ip   net.IP
+	port int
+}
+
+// 结构化输出中的探测结果，字段名保持稳定
+type checkRecord struct {
+	Host      string   `json:"host" yaml:"host"`
+	IP        string   `json:"ip" yaml:"ip"`
+	Port      int      `json:"port" yaml:"port"`
+	Proto     string   `json:"proto" yaml:"proto"`
+	State     string   `json:"state" yaml:"state"`
+	LatencyMs *float64 `json:"latency_ms" yaml:"latency_ms"`
+	Error     *string  `json:"error" yaml:"error"`
+}
+
+func Check() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check <host:ports>...",
+		Short: "Probe TCP or UDP ports on the given hosts",
+		Long: "Probe TCP or UDP ports on the given hosts and report open, closed or filtered.\n\n" +
+			"Ports are a comma separated list of ports and ranges, e.g. example.com:22,80,8000-8010 or [::1]:53.\n" +
+			"A UDP port that does not answer is reported as open|filtered.",
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := output.Format(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return
+			}
+			if checkConcurrency <= 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "invalid concurrency %d\n", checkConcurrency)
+				return
+			}
+
+			var probes []probe
+			for _, arg := range args {
+				p, err := parseTarget(arg)
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+					return
+				}
+				probes = append(probes, p...)
+			}
+
+			records := runProbes(probes, checkUDP, checkTimeout, checkConcurrency)
+			if err := printChecks(cmd, format, records); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&checkUDP, "udp", "u", false, "Probe UDP instead of TCP")
+	cmd.Flags().DurationVarP(&checkTimeout, "timeout", "w", 2*time.Second, "Time to wait for each port")
+	cmd.Flags().IntVarP(&checkConcurrency, "concurrency", "c", 50, "Maximum number of ports probed at the same time")
+
+	return cmd
+}
+
+// 解析 host:ports，ipv6 地址需要写在方括号中
+func parseTarget(s string) ([]probe, error) {
+	i := strings.LastIndex(s, ":")
+	if i <= 0 || i == len(s)-1 {
+		return nil, fmt.Errorf("invalid target '%s', host:ports required", s)
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(s[:i], "["), "]")
+	ports, err := parsePorts(s[i+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		addr, err := net.ResolveIPAddr("ip", host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+		}
+		ip = addr.IP
+	}
+
+	var probes []probe
+	for _, port := range ports {
+		probes = append(probes, probe{host: host, ip: ip, port: port})
+	}
+	return probes, nil
+}
+
+// 解析 22,80,8000-8010 形式的端口列表
+func parsePorts(s string) ([]int, error) {
+	var ports []int
+	for _, part := range strings.Split(s, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		last := first
+		if err == nil && isRange {
+			last, err = strconv.Atoi(hi)
+		}
+		if err != nil || first < 1 || last > 65535 || first > last {
+			return nil, fmt.Errorf("invalid port or range '%s' (value: 1-65535)", part)
+		}
+		for p := first; p <= last; p++ {
+			ports = append(ports, p)
+		}
+	}
+	return ports, nil
+}
+
+// 用固定数量的 worker 探测所有端口，结果保持输入顺序
+func runProbes(probes []probe, udp bool, timeout time.Duration, concurrency int) []checkRecord {
+	records := make([]checkRecord, len(probes))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	workers := min(concurrency, len(probes))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if udp {
+					records[i] = probeUDP(probes[i], timeout)
+				} else {
+					records[i] = probeTCP(probes[i], timeout)
+				}
+			}
+		}()
+	}
+	for i := range probes {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return records
+}
+
+func newRecord(p probe, proto string) checkRecord {
+	return checkRecord{Host: p.host, IP: p.ip.String(), Port: p.port, Proto: proto}
+}
+
+func (r *checkRecord) finish(state string, start time.Time, err error) {
+	r.State = state
+	if state == StateOpen || state == StateClosed {
+		ms := float64(time.Since(start).Microseconds()) / 1000
+		r.LatencyMs = &ms
+	}
+	if err != nil && state != StateClosed {
+		msg := err.Error()
+		r.Error = &msg
+	}
+}
+
+// 连接成功为开放，收到 RST 为关闭，超时或收到 ICMP 不可达为过滤
+func probeTCP(p probe, timeout time.Duration) checkRecord {
+	record := newRecord(p, "tcp")
+	addr := net.JoinHostPort(p.ip.String(), strconv.Itoa(p.port))
+
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	switch {
+	case err == nil:
+		conn.Close()
+		record.finish(StateOpen, start, nil)
+	case isRefused(err):
+		record.finish(StateClosed, start, err)
+	default:
+		record.finish(StateFiltered, start, err)
+	}
+	return record
+}
+
+// 收到任何回应为开放，收到 ICMP 端口不可达为关闭，超时无法确定
+func probeUDP(p probe, timeout time.Duration) checkRecord {
+	record := newRecord(p, "udp")
+	addr := net.JoinHostPort(p.ip.String(), strconv.Itoa(p.port))
+
+	start := time.Now()
+	conn, err := net.DialTimeout("udp", addr, timeout)
+	if err != nil {
+		record.finish(StateFiltered, start, err)
+		return record
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write([]byte{0}); err != nil {
+		record.finish(StateFiltered, start, err)
+		return record
+	}
+	buf := make([]byte, 512)
+	_, err = conn.Read(buf)
+	var netErr net.Error
+	switch {
+	case err == nil:
+		record.finish(StateOpen, start, nil)
+	case isRefused(err):
+		record.finish(StateClosed, start, err)
+	case errors.As(err, &netErr) && netErr.Timeout():
+		record.finish(StateOpenFiltered, start, nil)
+	default:
+		record.finish(StateFiltered, start, err)
+	}
+	return record
+}
+
+func printChecks(cmd *cobra.Command, format string, records []checkRecord) error {
+	switch format {
+	case output.JSON, output.YAML:
+		return output.Write(cmd.OutOrStdout(), format, records)
+	case output.CSV:
+		var rows [][]string
+		for _, r := range records {
+			latency := ""
+			if r.LatencyMs != nil {
+				latency = strconv.FormatFloat(*r.LatencyMs, 'f', 3, 64)
+			}
+			errMsg := ""
+			if r.Error != nil {
+				errMsg = *r.Error
+			}
+			rows = append(rows, []string{r.Host, r.IP, strconv.Itoa(r.Port), r.Proto, r.State, latency, errMsg})
+		}
+		return output.WriteCSV(cmd.OutOrStdout(), []string{"host", "ip", "port", "proto", "state", "latency_ms", "error"}, rows)
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.AppendHeader(table.Row{"HOST", "PORT", "PROTO", "STATE", "LATENCY"})
+	for _, r := range records {
+		host := r.Host
+		if host != r.IP {
+			host += " (" + r.IP + ")"
+		}
+		latency := "-"
+		if r.LatencyMs != nil {
+			latency = fmt.Sprintf("%.3f ms", *r.LatencyMs)
+		}
+		t.AppendRow(table.Row{host, r.Port, r.Proto, r.State, latency})
+	}
+	t.Render()
+	return nil
+}
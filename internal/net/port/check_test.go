@@ -0,0 +1,145 @@
+package port
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePorts(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []int
+		ok   bool
+	}{
+		{"22", []int{22}, true},
+		{"22,80,443", []int{22, 80, 443}, true},
+		{"8000-8003,22", []int{8000, 8001, 8002, 8003, 22}, true},
+		{"65535", []int{65535}, true},
+		{"0", nil, false},
+		{"65536", nil, false},
+		{"10-5", nil, false},
+		{"80,", nil, false},
+		{"http", nil, false},
+		{"1-2-3", nil, false},
+	}
+	for _, tt := range tests {
+		got, err := parsePorts(tt.in)
+		if (err == nil) != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePorts(%q) = %v, %v, want %v (ok %t)", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	probes, err := parseTarget("[::1]:53,80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(probes) != 2 || probes[0].host != "::1" || !probes[0].ip.Equal(net.IPv6loopback) || probes[0].port != 53 || probes[1].port != 80 {
+		t.Fatalf("parseTarget([::1]:53,80) = %+v", probes)
+	}
+
+	for _, bad := range []string{"127.0.0.1", "127.0.0.1:", ":80", "127.0.0.1:0"} {
+		if _, err := parseTarget(bad); err == nil {
+			t.Errorf("parseTarget(%q) was accepted", bad)
+		}
+	}
+}
+
+func loopback(port int) probe {
+	return probe{host: "127.0.0.1", ip: net.IPv4(127, 0, 0, 1), port: port}
+}
+
+// 监听后立即关闭，得到一个当前无人使用的端口
+func freePort(t *testing.T, network string) int {
+	t.Helper()
+	if network == "udp" {
+		c, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		return c.LocalAddr().(*net.UDPAddr).Port
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func tcpListener(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestProbeTCP(t *testing.T) {
+	open := probeTCP(loopback(tcpListener(t)), time.Second)
+	if open.State != StateOpen || open.LatencyMs == nil || open.Error != nil || open.Proto != "tcp" {
+		t.Fatalf("listening port: %+v", open)
+	}
+
+	closed := probeTCP(loopback(freePort(t, "tcp")), time.Second)
+	if closed.State != StateClosed || closed.LatencyMs == nil || closed.Error != nil {
+		t.Fatalf("freed port: %+v", closed)
+	}
+}
+
+func TestProbeUDP(t *testing.T) {
+	closed := probeUDP(loopback(freePort(t, "udp")), time.Second)
+	if closed.State != StateClosed || closed.Proto != "udp" {
+		t.Fatalf("freed port: %+v", closed)
+	}
+
+	// 监听但从不回复的端口无法与被过滤的端口区分
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	r := probeUDP(loopback(silent.LocalAddr().(*net.UDPAddr).Port), 200*time.Millisecond)
+	if r.State != StateOpenFiltered || r.LatencyMs != nil || r.Error != nil {
+		t.Fatalf("silent port: %+v", r)
+	}
+}
+
+func TestRunProbesKeepsOrder(t *testing.T) {
+	var probes []probe
+	var want []string
+	for i := 0; i < 12; i++ {
+		if i%3 == 0 {
+			probes = append(probes, loopback(tcpListener(t)))
+			want = append(want, StateOpen)
+		} else {
+			probes = append(probes, loopback(freePort(t, "tcp")))
+			want = append(want, StateClosed)
+		}
+	}
+
+	records := runProbes(probes, false, time.Second, 4)
+	if len(records) != len(probes) {
+		t.Fatalf("got %d records for %d probes", len(records), len(probes))
+	}
+	for i, r := range records {
+		if r.Port != probes[i].port || r.State != want[i] {
+			t.Fatalf("record %d = port %d %s, want port %d %s", i, r.Port, r.State, probes[i].port, want[i])
+		}
+	}
+}
@@ -1 +1,172 @@
 package port
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"nctl/interfaces"
+	"nctl/internal/utils"
+	"nctl/internal/utils/output"
+)
+
+var (
+	portListen bool
+	portTCP    bool
+	portUDP    bool
+	portNumber int
+)
+
+// 结构化输出中的套接字信息，字段名保持稳定
+type socketRecord struct {
+	Proto      string  `json:"proto" yaml:"proto"`
+	Family     string  `json:"family" yaml:"family"`
+	LocalAddr  string  `json:"local_addr" yaml:"local_addr"`
+	LocalPort  int     `json:"local_port" yaml:"local_port"`
+	RemoteAddr *string `json:"remote_addr" yaml:"remote_addr"`
+	RemotePort *int    `json:"remote_port" yaml:"remote_port"`
+	State      string  `json:"state" yaml:"state"`
+	PID        *int    `json:"pid" yaml:"pid"`
+	Command    *string `json:"command" yaml:"command"`
+}
+
+func Port() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "port",
+		Short: "List sockets with their owning process",
+		Long: "List TCP and UDP sockets with their owning process.\n\n" +
+			"Processes owned by other users are only shown when running as root (Administrator on Windows).",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := output.Format(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return
+			}
+			if portNumber < 0 || portNumber > 65535 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "invalid port %d\n", portNumber)
+				return
+			}
+
+			filter := interfaces.SocketFilter{TCP: portTCP, UDP: portUDP, Listen: portListen, Port: portNumber}
+			socks, err := utils.SocketUtils().ListSockets(filter)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return
+			}
+			sortSockets(socks)
+
+			if err := printSockets(cmd, format, socks); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&portListen, "listen", "l", false, "Only show listening TCP and unconnected UDP sockets")
+	cmd.Flags().BoolVarP(&portTCP, "tcp", "t", false, "Show TCP sockets (default: TCP and UDP)")
+	cmd.Flags().BoolVarP(&portUDP, "udp", "u", false, "Show UDP sockets (default: TCP and UDP)")
+	cmd.Flags().IntVarP(&portNumber, "port", "p", 0, "Only show sockets whose local or remote port is this port")
+
+	// 挂载 net port check 命令
+	cmd.AddCommand(Check())
+
+	return cmd
+}
+
+// 监听的套接字在前，再按协议、本端端口排列
+func sortSockets(socks []interfaces.Socket) {
+	sort.SliceStable(socks, func(i, j int) bool {
+		a, b := socks[i], socks[j]
+		if a.Listening() != b.Listening() {
+			return a.Listening()
+		}
+		if a.Proto != b.Proto {
+			return a.Proto < b.Proto
+		}
+		if a.LocalPort != b.LocalPort {
+			return a.LocalPort < b.LocalPort
+		}
+		return a.Family < b.Family
+	})
+}
+
+// 地址为空或未指定时用 * 表示
+func hostPort(ip net.IP, port int) string {
+	host := "*"
+	if ip != nil && !ip.IsUnspecified() {
+		host = ip.String()
+	}
+	p := "*"
+	if port != 0 {
+		p = strconv.Itoa(port)
+	}
+	return net.JoinHostPort(host, p)
+}
+
+func toRecord(s interfaces.Socket) socketRecord {
+	record := socketRecord{
+		Proto:     s.Proto,
+		Family:    "ipv" + strconv.Itoa(s.Family),
+		LocalAddr: s.LocalIP.String(),
+		LocalPort: s.LocalPort,
+		State:     s.State,
+	}
+	if s.RemotePort != 0 {
+		remote := s.RemoteIP.String()
+		record.RemoteAddr = &remote
+		record.RemotePort = &s.RemotePort
+	}
+	if s.PID != 0 {
+		record.PID = &s.PID
+	}
+	if s.Command != "" {
+		record.Command = &s.Command
+	}
+	return record
+}
+
+func printSockets(cmd *cobra.Command, format string, socks []interfaces.Socket) error {
+	records := make([]socketRecord, 0, len(socks))
+	for _, s := range socks {
+		records = append(records, toRecord(s))
+	}
+
+	switch format {
+	case output.JSON, output.YAML:
+		return output.Write(cmd.OutOrStdout(), format, records)
+	case output.CSV:
+		var rows [][]string
+		for i, r := range records {
+			pid := ""
+			if r.PID != nil {
+				pid = strconv.Itoa(*r.PID)
+			}
+			rows = append(rows, []string{
+				r.Proto, r.Family, hostPort(socks[i].LocalIP, socks[i].LocalPort),
+				hostPort(socks[i].RemoteIP, socks[i].RemotePort), r.State, pid, socks[i].Command,
+			})
+		}
+		return output.WriteCSV(cmd.OutOrStdout(), []string{"proto", "family", "local", "remote", "state", "pid", "command"}, rows)
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.AppendHeader(table.Row{"PROTO", "LOCAL", "REMOTE", "STATE", "PID", "PROCESS"})
+	for _, s := range socks {
+		pid := "-"
+		if s.PID != 0 {
+			pid = strconv.Itoa(s.PID)
+		}
+		command := s.Command
+		if command == "" {
+			command = "-"
+		}
+		t.AppendRow(table.Row{s.Proto, hostPort(s.LocalIP, s.LocalPort), hostPort(s.RemoteIP, s.RemotePort), s.State, pid, command})
+	}
+	t.Render()
+	return nil
+}
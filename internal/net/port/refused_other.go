@@ -0,0 +1,13 @@
+//go:build !windows
+
+package port
+
+import (
+	"errors"
+	"syscall"
+)
+
+// 对端回复 RST 或 ICMP 端口不可达
+func isRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
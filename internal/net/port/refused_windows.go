@@ -0,0 +1,14 @@
+//go:build windows
+
+package port
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// 对端回复 RST 或 ICMP 端口不可达，windows 上 UDP 收到端口不可达时返回 WSAECONNRESET
+func isRefused(err error) bool {
+	return errors.Is(err, windows.WSAECONNREFUSED) || errors.Is(err, windows.WSAECONNRESET)
+}
@@ -1 +1,242 @@
 package proxy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"nctl/interfaces"
+	"nctl/internal/utils"
+	"nctl/internal/utils/output"
+)
+
+// 当前进程环境变量中的设置，只读
+const scopeEnv = "env"
+
+// 结构化输出中的代理设置，字段名保持稳定
+type proxyRecord struct {
+	Scope   string   `json:"scope" yaml:"scope"`
+	HTTP    string   `json:"http" yaml:"http"`
+	HTTPS   string   `json:"https" yaml:"https"`
+	NoProxy []string `json:"no_proxy" yaml:"no_proxy"`
+	Source  string   `json:"source" yaml:"source"`
+}
+
+func Proxy() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "proxy",
+		Short: "Show, change and test the system proxy configuration",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	// 挂载 net proxy 系列命令
+	cmd.AddCommand(show())
+	cmd.AddCommand(set())
+	cmd.AddCommand(unset())
+	cmd.AddCommand(Test())
+	return cmd
+}
+
+func checkScope(scope string) error {
+	if scope != interfaces.ProxyScopeSystem && scope != interfaces.ProxyScopeUser {
+		return fmt.Errorf("unknown scope '%s' (value: system, user)", scope)
+	}
+	return nil
+}
+
+// 当前进程的代理环境变量，小写优先
+func envSettings() *interfaces.ProxySettings {
+	get := func(lower string) string {
+		if v := os.Getenv(lower); v != "" {
+			return v
+		}
+		return os.Getenv(strings.ToUpper(lower))
+	}
+	p := &interfaces.ProxySettings{HTTP: get("http_proxy"), HTTPS: get("https_proxy"), Source: "environment"}
+	for _, host := range strings.Split(get("no_proxy"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			p.NoProxy = append(p.NoProxy, host)
+		}
+	}
+	if p.Empty() {
+		p.Source = ""
+	}
+	return p
+}
+
+func show() *cobra.Command {
+	var scope string
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the proxy settings of every scope",
+		Long: "Show the proxy settings of the system and user scopes and of the current environment.\n\n" +
+			"On linux the system scope is /etc/environment and /etc/profile.d/nctl-proxy.sh and the user scope is\n" +
+			"a managed block in ~/.profile; on windows they are the WinINET settings under HKLM and HKCU.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := output.Format(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return
+			}
+			scopes := []string{interfaces.ProxyScopeSystem, interfaces.ProxyScopeUser, scopeEnv}
+			if scope != "" {
+				if scope != scopeEnv {
+					if err := checkScope(scope); err != nil {
+						fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+						return
+					}
+				}
+				scopes = []string{scope}
+			}
+
+			records := []proxyRecord{}
+			for _, s := range scopes {
+				p := envSettings()
+				if s != scopeEnv {
+					if p, err = utils.ProxyUtils().GetProxy(s); err != nil {
+						fmt.Fprintf(cmd.ErrOrStderr(), "Error: failed to read %s proxy settings: %v\n", s, err)
+						continue
+					}
+				}
+				noProxy := p.NoProxy
+				if noProxy == nil {
+					noProxy = []string{}
+				}
+				records = append(records, proxyRecord{Scope: s, HTTP: redact(p.HTTP), HTTPS: redact(p.HTTPS), NoProxy: noProxy, Source: p.Source})
+			}
+
+			switch format {
+			case output.Table:
+				printProxies(cmd, records)
+			case output.JSON, output.YAML:
+				err = output.Write(cmd.OutOrStdout(), format, records)
+			case output.CSV:
+				var rows [][]string
+				for _, r := range records {
+					rows = append(rows, []string{r.Scope, r.HTTP, r.HTTPS, strings.Join(r.NoProxy, ","), r.Source})
+				}
+				err = output.WriteCSV(cmd.OutOrStdout(), []string{"scope", "http", "https", "no_proxy", "source"}, rows)
+			}
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&scope, "scope", "s", "", "Only show one scope (value: system, user, env)")
+	return cmd
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func printProxies(cmd *cobra.Command, records []proxyRecord) {
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.AppendHeader(table.Row{"SCOPE", "HTTP", "HTTPS", "NO_PROXY", "SOURCE"})
+	for _, r := range records {
+		t.AppendRow(table.Row{r.Scope, orDash(r.HTTP), orDash(r.HTTPS), orDash(strings.Join(r.NoProxy, ",")), orDash(r.Source)})
+	}
+	t.Render()
+}
+
+func set() *cobra.Command {
+	var scope, httpProxy, httpsProxy string
+	var noProxy []string
+	cmd := &cobra.Command{
+		Use:   "set [proxy]",
+		Short: "Set the proxy of a scope, replacing the previous settings",
+		Long: "Set the proxy of a scope, replacing the previous settings.\n\n" +
+			"The positional proxy is used for both http and https unless --http or --https is given.\n" +
+			"A proxy without scheme is taken as http://, e.g. proxy.example.com:3128.\n" +
+			"On linux new sessions pick up the change; already running shells keep their environment.",
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkScope(scope); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			if len(args) == 1 {
+				if httpProxy == "" {
+					httpProxy = args[0]
+				}
+				if httpsProxy == "" {
+					httpsProxy = args[0]
+				}
+			}
+			if httpProxy == "" && httpsProxy == "" {
+				fmt.Fprintln(cmd.ErrOrStderr(), "Error: a proxy, --http or --https is required")
+				return
+			}
+
+			p := &interfaces.ProxySettings{}
+			var err error
+			for _, v := range []struct {
+				in  string
+				out *string
+			}{{httpProxy, &p.HTTP}, {httpsProxy, &p.HTTPS}} {
+				if v.in == "" {
+					continue
+				}
+				if *v.out, err = interfaces.ParseProxyURL(v.in); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+					return
+				}
+			}
+			for _, host := range noProxy {
+				host, err := interfaces.ParseNoProxy(host)
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+					return
+				}
+				if host != "" {
+					p.NoProxy = append(p.NoProxy, host)
+				}
+			}
+
+			if err := utils.ProxyUtils().SetProxy(scope, p); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Set %s proxy: http %s, https %s\n", scope, orDash(redact(p.HTTP)), orDash(redact(p.HTTPS)))
+		},
+	}
+
+	cmd.Flags().StringVarP(&scope, "scope", "s", interfaces.ProxyScopeSystem, "Where to write the settings (value: system, user)")
+	cmd.Flags().StringVar(&httpProxy, "http", "", "Proxy for http requests")
+	cmd.Flags().StringVar(&httpsProxy, "https", "", "Proxy for https requests")
+	cmd.Flags().StringSliceVarP(&noProxy, "no-proxy", "n", nil, "Hosts, domain suffixes or networks that bypass the proxy")
+	return cmd
+}
+
+func unset() *cobra.Command {
+	var scope string
+	cmd := &cobra.Command{
+		Use:   "unset",
+		Short: "Remove the proxy settings of a scope",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := checkScope(scope); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			if err := utils.ProxyUtils().UnsetProxy(scope); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed %s proxy settings\n", scope)
+		},
+	}
+
+	cmd.Flags().StringVarP(&scope, "scope", "s", interfaces.ProxyScopeSystem, "Which settings to remove (value: system, user)")
+	return cmd
+}
@@ -0,0 +1,254 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"nctl/interfaces"
+	"nctl/internal/utils"
+	"nctl/internal/utils/output"
+)
+
+var (
+	testProxy   string
+	testTimeout time.Duration
+)
+
+// 一次 CONNECT 测试的结果
+type ConnectResult struct {
+	// 实际连接的代理地址
+	Addr string
+	// 与代理建立连接（https 代理包括 TLS 握手）的耗时
+	Dial time.Duration
+	// 代理对 CONNECT 的应答
+	Status     int
+	StatusText string
+	// 从发送 CONNECT 到收到应答的耗时
+	Tunnel time.Duration
+}
+
+// 结构化输出中的测试结果，字段名保持稳定
+type testRecord struct {
+	Proxy      string   `json:"proxy" yaml:"proxy"`
+	Source     string   `json:"source" yaml:"source"`
+	Target     string   `json:"target" yaml:"target"`
+	Addr       string   `json:"addr" yaml:"addr"`
+	DialMs     *float64 `json:"dial_ms" yaml:"dial_ms"`
+	Status     *int     `json:"status" yaml:"status"`
+	StatusText string   `json:"status_text" yaml:"status_text"`
+	TunnelMs   *float64 `json:"tunnel_ms" yaml:"tunnel_ms"`
+	Error      *string  `json:"error" yaml:"error"`
+}
+
+func Test() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test [target]",
+		Short: "Open a CONNECT tunnel through the proxy to a target",
+		Long: "Open a CONNECT tunnel through the proxy to a target host:port, example.com:443 by default.\n\n" +
+			"Without --proxy the https proxy of the current environment is used, then the user and system scopes.\n" +
+			"Credentials in the proxy URL are sent as Proxy-Authorization. Exit status is 1 when the tunnel fails.",
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := output.Format(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				os.Exit(2)
+			}
+			target := "example.com:443"
+			if len(args) == 1 {
+				target = args[0]
+			}
+			if _, _, err := net.SplitHostPort(target); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: target must be host:port, got '%s'\n", target)
+				os.Exit(2)
+			}
+
+			proxy, source := testProxy, "--proxy"
+			if proxy == "" {
+				proxy, source = configuredProxy()
+			}
+			if proxy == "" {
+				fmt.Fprintln(cmd.ErrOrStderr(), "Error: no proxy is configured, use --proxy")
+				os.Exit(2)
+			}
+			if proxy, err = interfaces.ParseProxyURL(proxy); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				os.Exit(2)
+			}
+
+			record := testRecord{Proxy: redact(proxy), Source: source, Target: target}
+			result, connErr := Connect(proxy, target, testTimeout)
+			if result != nil {
+				record.Addr = result.Addr
+				if result.Dial > 0 {
+					dial := ms(result.Dial)
+					record.DialMs = &dial
+				}
+				if result.Status != 0 {
+					status, tunnel := result.Status, ms(result.Tunnel)
+					record.Status, record.StatusText, record.TunnelMs = &status, result.StatusText, &tunnel
+				}
+			}
+			if connErr != nil {
+				msg := connErr.Error()
+				record.Error = &msg
+			}
+
+			switch format {
+			case output.Table:
+				printTest(cmd, record)
+			case output.JSON, output.YAML:
+				err = output.Write(cmd.OutOrStdout(), format, record)
+			case output.CSV:
+				row := []string{record.Proxy, record.Source, record.Target, record.Addr, "", "", record.StatusText, "", ""}
+				if record.DialMs != nil {
+					row[4] = fmt.Sprintf("%.3f", *record.DialMs)
+				}
+				if record.Status != nil {
+					row[5], row[7] = fmt.Sprint(*record.Status), fmt.Sprintf("%.3f", *record.TunnelMs)
+				}
+				if record.Error != nil {
+					row[8] = *record.Error
+				}
+				err = output.WriteCSV(cmd.OutOrStdout(), []string{"proxy", "source", "target", "addr", "dial_ms", "status", "status_text", "tunnel_ms", "error"}, [][]string{row})
+			}
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				os.Exit(2)
+			}
+			if record.Error != nil {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&testProxy, "proxy", "x", "", "Proxy to test instead of the configured one")
+	cmd.Flags().DurationVarP(&testTimeout, "timeout", "w", 5*time.Second, "Time allowed for the whole test")
+	return cmd
+}
+
+// 按环境变量、用户、系统的顺序查找已配置的 https 代理，没有时使用 http 代理
+func configuredProxy() (string, string) {
+	candidates := []*interfaces.ProxySettings{envSettings()}
+	for _, scope := range []string{interfaces.ProxyScopeUser, interfaces.ProxyScopeSystem} {
+		if p, err := utils.ProxyUtils().GetProxy(scope); err == nil {
+			candidates = append(candidates, p)
+		}
+	}
+	for _, p := range candidates {
+		if p.HTTPS != "" {
+			return p.HTTPS, p.Source
+		}
+		if p.HTTP != "" {
+			return p.HTTP, p.Source
+		}
+	}
+	return "", ""
+}
+
+// 输出中隐藏代理 URL 中的密码
+func redact(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return proxy
+	}
+	return u.Redacted()
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// 通过 http 或 https 代理建立到 target 的 CONNECT 隧道，成功后立即关闭
+func Connect(proxy, target string, timeout time.Duration) (*ConnectResult, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("only http and https proxies support CONNECT, got %s", u.Scheme)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	result := &ConnectResult{Addr: u.Host}
+	start := time.Now()
+	var conn net.Conn
+	if u.Scheme == "https" {
+		d := &tls.Dialer{Config: &tls.Config{ServerName: u.Hostname()}}
+		conn, err = d.DialContext(ctx, "tcp", u.Host)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", u.Host)
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to connect to proxy %s: %w", u.Host, err)
+	}
+	defer conn.Close()
+	result.Addr = conn.RemoteAddr().String()
+	result.Dial = time.Since(start)
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	req := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", target, target)
+	if u.User != nil {
+		password, _ := u.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(u.User.Username() + ":" + password))
+		req += "Proxy-Authorization: Basic " + auth + "\r\n"
+	}
+	req += "\r\n"
+
+	start = time.Now()
+	if _, err := conn.Write([]byte(req)); err != nil {
+		return result, fmt.Errorf("failed to send CONNECT: %w", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		return result, fmt.Errorf("failed to read the proxy response: %w", err)
+	}
+	resp.Body.Close()
+	result.Tunnel = time.Since(start)
+	result.Status, result.StatusText = resp.StatusCode, http.StatusText(resp.StatusCode)
+	if len(resp.Status) > 4 {
+		result.StatusText = resp.Status[4:]
+	}
+
+	if resp.StatusCode/100 != 2 {
+		msg := fmt.Sprintf("proxy refused CONNECT to %s: %s", target, resp.Status)
+		if resp.StatusCode == http.StatusProxyAuthRequired {
+			msg += fmt.Sprintf(" (%s)", resp.Header.Get("Proxy-Authenticate"))
+		}
+		return result, fmt.Errorf("%s", msg)
+	}
+	return result, nil
+}
+
+func printTest(cmd *cobra.Command, r testRecord) {
+	w := cmd.OutOrStdout()
+	source := ""
+	if r.Source != "" {
+		source = " from " + r.Source
+	}
+	fmt.Fprintf(w, "proxy %s%s\n", r.Proxy, source)
+	if r.DialMs != nil {
+		fmt.Fprintf(w, "connected to %s in %.3f ms\n", r.Addr, *r.DialMs)
+	}
+	if r.Status != nil {
+		fmt.Fprintf(w, "CONNECT %s: %d %s in %.3f ms\n", r.Target, *r.Status, r.StatusText, *r.TunnelMs)
+	}
+	if r.Error != nil {
+		fmt.Fprintf(w, "FAIL: %s\n", *r.Error)
+		return
+	}
+	fmt.Fprintf(w, "OK: tunnel to %s established\n", r.Target)
+}
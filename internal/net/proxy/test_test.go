@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 本地的 CONNECT 代理，只应答 CONNECT 请求，不转发数据
+func connectProxy(t *testing.T, handle func(w http.ResponseWriter, r *http.Request)) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			t.Errorf("method = %s, want CONNECT", r.Method)
+		}
+		handle(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestConnectEstablished(t *testing.T) {
+	var host string
+	proxy := connectProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+		if auth := r.Header.Get("Proxy-Authorization"); auth != "" {
+			t.Errorf("unexpected Proxy-Authorization %q", auth)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	result, err := Connect(proxy, "example.com:443", 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host != "example.com:443" {
+		t.Fatalf("CONNECT host = %q", host)
+	}
+	if result.Status != http.StatusOK || result.StatusText != "OK" || result.Addr != strings.TrimPrefix(proxy, "http://") {
+		t.Fatalf("result = %+v", result)
+	}
+	if result.Dial <= 0 || result.Tunnel <= 0 {
+		t.Fatalf("result = %+v, want dial and tunnel times", result)
+	}
+}
+
+func TestConnectAuthRequired(t *testing.T) {
+	proxy := connectProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Proxy-Authenticate", `Basic realm="corp"`)
+		w.WriteHeader(http.StatusProxyAuthRequired)
+	})
+
+	result, err := Connect(proxy, "example.com:443", 2*time.Second)
+	if err == nil {
+		t.Fatal("407 was reported as success")
+	}
+	if result == nil || result.Status != http.StatusProxyAuthRequired {
+		t.Fatalf("result = %+v, want status 407", result)
+	}
+	if !strings.Contains(err.Error(), `Basic realm="corp"`) {
+		t.Fatalf("error %q does not include Proxy-Authenticate", err)
+	}
+}
+
+func TestConnectSendsBasicAuth(t *testing.T) {
+	var auth string
+	proxy := connectProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Proxy-Authorization")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// 用户名和密码在 URL 中经过百分号编码，发送时需要解码
+	withUser := strings.Replace(proxy, "http://", "http://al%20ice:p%40ss@", 1)
+	if _, err := Connect(withUser, "example.com:443", 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte("al ice:p@ss"))
+	if auth != want {
+		t.Fatalf("Proxy-Authorization = %q, want %q", auth, want)
+	}
+}
+
+func TestConnectRejectsSocks(t *testing.T) {
+	if _, err := Connect("socks5://127.0.0.1:1080", "example.com:443", time.Second); err == nil {
+		t.Fatal("socks5 proxy was accepted for CONNECT")
+	}
+}
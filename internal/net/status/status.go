@@ -1 +1,478 @@
 package status
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"nctl/interfaces"
+	"nctl/internal/iface/list"
+	"nctl/internal/net/dns"
+	"nctl/internal/net/test"
+	"nctl/internal/utils"
+	"nctl/internal/utils/output"
+)
+
+// 检查结果
+const (
+	StatusOK   = "OK"
+	StatusWarn = "WARN"
+	StatusFail = "FAIL"
+)
+
+// 检查的类别，按输出顺序排列
+const (
+	CategoryIface    = "interface"
+	CategoryGateway  = "gateway"
+	CategoryDNS      = "dns"
+	CategoryExternal = "external"
+)
+
+var (
+	statusTargets    []string
+	statusNoExternal bool
+	statusCount      int
+	statusTimeout    time.Duration
+)
+
+// 一项检查的结果，字段名保持稳定
+type Check struct {
+	Category string `json:"category" yaml:"category"`
+	Target   string `json:"target" yaml:"target"`
+	Status   string `json:"status" yaml:"status"`
+	Detail   string `json:"detail" yaml:"detail"`
+}
+
+type statusRecord struct {
+	Status string  `json:"status" yaml:"status"`
+	Checks []Check `json:"checks" yaml:"checks"`
+}
+
+func Status() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Check host connectivity and summarize the result",
+		Long: "Check host connectivity in one pass: interface state and addresses, default gateway reachability\n" +
+			"(neighbor resolution and ping), every configured DNS server and a set of external targets.\n\n" +
+			"External targets are probed like `net test`: host for ICMP, host:port for TCP and a URL for HTTP.\n" +
+			"Exit status is 1 when any check fails.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := output.Format(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				os.Exit(2)
+			}
+			if statusCount < 1 || statusTimeout <= 0 {
+				fmt.Fprintln(cmd.ErrOrStderr(), "Error: --count must be at least 1 and --timeout must be positive")
+				os.Exit(2)
+			}
+
+			infos, err := list.Collect(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				os.Exit(2)
+			}
+			targets := statusTargets
+			if statusNoExternal {
+				targets = nil
+			}
+
+			checks := Run(infos, targets)
+			record := statusRecord{Status: Overall(checks), Checks: checks}
+			switch format {
+			case output.Table:
+				printChecks(cmd, checks)
+			case output.JSON, output.YAML:
+				err = output.Write(cmd.OutOrStdout(), format, record)
+			case output.CSV:
+				var rows [][]string
+				for _, c := range checks {
+					rows = append(rows, []string{c.Category, c.Target, c.Status, c.Detail})
+				}
+				err = output.WriteCSV(cmd.OutOrStdout(), []string{"category", "target", "status", "detail"}, rows)
+			}
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				os.Exit(2)
+			}
+			if record.Status == StatusFail {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&statusTargets, "target", "t", []string{"1.1.1.1", "example.com:443"}, "External targets to probe (host, host:port or URL)")
+	cmd.Flags().BoolVar(&statusNoExternal, "no-external", false, "Skip the external reachability checks")
+	cmd.Flags().IntVarP(&statusCount, "count", "c", 3, "Number of probes per gateway and external target")
+	cmd.Flags().DurationVarP(&statusTimeout, "timeout", "w", 2*time.Second, "Time to wait for each probe or DNS query")
+
+	return cmd
+}
+
+// 所有检查中最差的结果
+func Overall(checks []Check) string {
+	status := StatusOK
+	for _, c := range checks {
+		switch c.Status {
+		case StatusFail:
+			return StatusFail
+		case StatusWarn:
+			status = StatusWarn
+		}
+	}
+	return status
+}
+
+// 先检查接口，再并发检查网关、DNS 和外部目标，结果按类别排列
+func Run(infos []list.InterfaceInfo, targets []string) []Check {
+	checks := checkIfaces(infos)
+
+	var (
+		wg                 sync.WaitGroup
+		gateway, dnsChecks []Check
+		external           = make([]Check, len(targets))
+	)
+	wg.Add(2 + len(targets))
+	go func() {
+		defer wg.Done()
+		gateway = checkGateways(infos)
+	}()
+	go func() {
+		defer wg.Done()
+		dnsChecks = checkDNS(infos)
+	}()
+	for i, t := range targets {
+		go func() {
+			defer wg.Done()
+			external[i] = checkTarget(t)
+		}()
+	}
+	wg.Wait()
+
+	checks = append(checks, gateway...)
+	checks = append(checks, dnsChecks...)
+	return append(checks, external...)
+}
+
+// 接口需要 up、有载波并且有地址；承载默认路由的接口出问题时为 FAIL，其他接口为 WARN
+func checkIfaces(infos []list.InterfaceInfo) []Check {
+	var checks []Check
+	usable := false
+	for _, info := range infos {
+		if info.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		bad := StatusWarn
+		if len(info.DefaultRoutes) > 0 {
+			bad = StatusFail
+		}
+
+		c := Check{Category: CategoryIface, Target: info.Name}
+		addrs := globalAddrs(info.IPAddresses)
+		switch {
+		case info.Flags&net.FlagUp == 0:
+			c.Status, c.Detail = bad, "administratively down"
+		case info.OperState != "" && !info.Carrier:
+			c.Status, c.Detail = bad, "up but no carrier"
+		case info.Master != "":
+			// bridge、bond 的成员接口不需要地址
+			c.Status, c.Detail = StatusOK, "up, member of "+info.Master
+		case len(addrs) == 0:
+			c.Status, c.Detail = bad, "up but no address"
+		default:
+			c.Status, c.Detail = StatusOK, "up, "+strings.Join(addrs, ", ")
+			usable = true
+		}
+		checks = append(checks, c)
+	}
+	if !usable {
+		checks = append(checks, Check{Category: CategoryIface, Target: "-", Status: StatusFail, Detail: "no interface is up with an address"})
+	}
+	return checks
+}
+
+// 不含 link-local 的地址
+func globalAddrs(ipnets []*net.IPNet) []string {
+	var addrs []string
+	for _, ipnet := range ipnets {
+		if !ipnet.IP.IsLinkLocalUnicast() {
+			addrs = append(addrs, ipnet.String())
+		}
+	}
+	return addrs
+}
+
+type gatewayTarget struct {
+	iface string
+	ip    net.IP
+}
+
+// 每个地址族实际使用的默认网关，路由表读取失败时退回到 utils.GetGW 的结果
+func defaultGateways(infos []list.InterfaceInfo) ([]gatewayTarget, bool) {
+	var gws []gatewayTarget
+	hasDefault := false
+	for _, info := range infos {
+		for _, d := range info.DefaultRoutes {
+			hasDefault = true
+			if d.Preferred && d.Gateway != nil {
+				gws = append(gws, gatewayTarget{iface: info.Name, ip: d.Gateway})
+			}
+		}
+	}
+	if hasDefault {
+		return gws, true
+	}
+
+	for _, info := range infos {
+		for _, gw := range []string{info.DefaultGatewayIPv4, info.DefaultGatewayIPv6} {
+			if ip := net.ParseIP(gw); ip != nil {
+				gws = append(gws, gatewayTarget{iface: info.Name, ip: ip})
+			}
+		}
+	}
+	return gws, len(gws) > 0
+}
+
+func checkGateways(infos []list.InterfaceInfo) []Check {
+	gws, ok := defaultGateways(infos)
+	if !ok {
+		return []Check{{Category: CategoryGateway, Target: "-", Status: StatusFail, Detail: "no default route"}}
+	}
+	if len(gws) == 0 {
+		return []Check{{Category: CategoryGateway, Target: "-", Status: StatusOK, Detail: "default route has no gateway"}}
+	}
+
+	checks := make([]Check, len(gws))
+	var wg sync.WaitGroup
+	for i, gw := range gws {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checks[i] = checkGateway(gw)
+		}()
+	}
+	wg.Wait()
+	return checks
+}
+
+// 网关不回应 ping 但能解析到 MAC 时只是 WARN，很多网关会丢弃 ICMP
+func checkGateway(gw gatewayTarget) Check {
+	c := Check{Category: CategoryGateway, Target: fmt.Sprintf("%s (%s)", gw.ip, gw.iface)}
+	opts := test.Options{Mode: test.ModeICMP, Count: statusCount, Interval: 200 * time.Millisecond, Timeout: statusTimeout, Iface: gw.iface}
+	stats, pingErr := test.Run(context.Background(), &test.Target{Host: gw.ip.String(), IP: gw.ip}, opts, func(test.Probe) {})
+	if pingErr != nil {
+		// 无法发送 ICMP 时用一个 UDP 报文触发邻居解析
+		triggerResolve(gw.ip, gw.iface)
+	}
+
+	mac, state := lookupNeigh(gw)
+	switch {
+	case mac == "":
+		c.Status, c.Detail = StatusFail, "link-layer address not resolved"
+		if state != "" {
+			c.Detail += " (" + state + ")"
+		}
+	case pingErr != nil:
+		c.Status, c.Detail = StatusWarn, fmt.Sprintf("resolved to %s, ping failed: %v", mac, pingErr)
+	case stats.Received == 0:
+		c.Status, c.Detail = StatusWarn, fmt.Sprintf("resolved to %s, no ping reply", mac)
+	case stats.Received < stats.Sent:
+		c.Status, c.Detail = StatusWarn, fmt.Sprintf("resolved to %s, %.0f%% loss, avg %.3f ms", mac, stats.Loss, ms(stats.Avg))
+	default:
+		c.Status, c.Detail = StatusOK, fmt.Sprintf("resolved to %s, avg %.3f ms", mac, ms(stats.Avg))
+	}
+	return c
+}
+
+func triggerResolve(ip net.IP, iface string) {
+	addr := &net.UDPAddr{IP: ip, Port: 9}
+	if ip.IsLinkLocalUnicast() {
+		addr.Zone = iface
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.Write([]byte{0})
+	time.Sleep(statusTimeout / 2)
+}
+
+// 网关在邻居表中的 MAC 和状态，解析失败时 MAC 为空
+func lookupNeigh(gw gatewayTarget) (string, string) {
+	family := 4
+	if gw.ip.To4() == nil {
+		family = 6
+	}
+	neighs, err := utils.NeighUtils().ListNeighs(interfaces.NeighFilter{Family: family, Iface: gw.iface})
+	if err != nil {
+		return "", ""
+	}
+	for _, n := range neighs {
+		if !n.IP.Equal(gw.ip) {
+			continue
+		}
+		if n.State == "FAILED" || n.State == "INCOMPLETE" || len(n.MAC) == 0 {
+			return "", n.State
+		}
+		return n.MAC.String(), n.State
+	}
+	return "", ""
+}
+
+type dnsTarget struct {
+	iface  string
+	server string
+}
+
+func (t dnsTarget) String() string {
+	if t.iface == "" {
+		return t.server
+	}
+	return fmt.Sprintf("%s (%s)", t.server, t.iface)
+}
+
+// 已启用的接口上配置的 dns 服务器；都没有时退回到 resolv.conf，
+// systemd-resolved 等主机上 resolv.conf 只有本地转发地址，不能反映上游服务器是否可达
+func dnsServers(infos []list.InterfaceInfo) []dnsTarget {
+	var targets []dnsTarget
+	for _, info := range infos {
+		if info.Flags&net.FlagLoopback != 0 || info.Flags&net.FlagUp == 0 {
+			continue
+		}
+		ips, err := utils.IfaceUtils().GetDNSs(info.Name)
+		if err != nil {
+			continue
+		}
+		for _, ip := range ips {
+			targets = append(targets, dnsTarget{iface: info.Name, server: ip.String()})
+		}
+	}
+	if len(targets) > 0 {
+		return targets
+	}
+
+	for _, s := range dns.SystemServers() {
+		targets = append(targets, dnsTarget{server: s})
+	}
+	return targets
+}
+
+// 向每个服务器查询根区的 NS 记录，只要有应答就说明服务器可达；
+// 部分服务器不可达时为 WARN，全部不可达时为 FAIL
+func checkDNS(infos []list.InterfaceInfo) []Check {
+	servers := dnsServers(infos)
+	if len(servers) == 0 {
+		return []Check{{Category: CategoryDNS, Target: "-", Status: StatusFail, Detail: "no DNS servers configured"}}
+	}
+
+	checks := make([]Check, len(servers))
+	failed := make([]bool, len(servers))
+	var wg sync.WaitGroup
+	for i, s := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checks[i] = Check{Category: CategoryDNS, Target: s.String()}
+			r, err := dns.Query(net.JoinHostPort(s.server, "53"), ".", dnsmessage.TypeNS, false, statusTimeout)
+			switch {
+			case err != nil:
+				failed[i] = true
+				checks[i].Detail = err.Error()
+			case r.Rcode == "NOERROR" || r.Rcode == "NXDOMAIN":
+				checks[i].Status, checks[i].Detail = StatusOK, fmt.Sprintf("answered in %.3f ms", ms(r.Latency))
+			default:
+				checks[i].Status, checks[i].Detail = StatusWarn, fmt.Sprintf("answered %s in %.3f ms", r.Rcode, ms(r.Latency))
+			}
+		}()
+	}
+	wg.Wait()
+
+	allFailed := true
+	for _, f := range failed {
+		allFailed = allFailed && f
+	}
+	for i := range checks {
+		if !failed[i] {
+			continue
+		}
+		if allFailed {
+			checks[i].Status = StatusFail
+		} else {
+			checks[i].Status = StatusWarn
+		}
+	}
+	return checks
+}
+
+// URL 使用 http 探测，host:port 使用 tcp，其余使用 icmp
+func checkTarget(target string) Check {
+	c := Check{Category: CategoryExternal, Target: target}
+	opts := test.Options{Mode: test.ModeICMP, Count: statusCount, Interval: 200 * time.Millisecond, Timeout: statusTimeout}
+	host := target
+	if strings.Contains(target, "://") {
+		opts.Mode = test.ModeHTTP
+	} else if h, p, err := net.SplitHostPort(target); err == nil {
+		var port int
+		if _, err := fmt.Sscan(p, &port); err != nil || port < 1 || port > 65535 {
+			c.Status, c.Detail = StatusFail, fmt.Sprintf("invalid port '%s'", p)
+			return c
+		}
+		host, opts.Mode, opts.Port = h, test.ModeTCP, port
+	}
+
+	t, err := test.ResolveTarget(host, opts)
+	if err != nil {
+		c.Status, c.Detail = StatusFail, err.Error()
+		return c
+	}
+	var lastErr error
+	stats, err := test.Run(context.Background(), t, opts, func(p test.Probe) {
+		if p.Err != nil {
+			lastErr = p.Err
+		}
+	})
+	switch {
+	case err != nil:
+		c.Status, c.Detail = StatusFail, err.Error()
+	case stats.Received == 0:
+		c.Status, c.Detail = StatusFail, fmt.Sprintf("%s %s: no reply", opts.Mode, t.IP)
+		if lastErr != nil {
+			c.Detail = fmt.Sprintf("%s %s: %v", opts.Mode, t.IP, lastErr)
+		}
+	case stats.Received < stats.Sent:
+		c.Status, c.Detail = StatusWarn, fmt.Sprintf("%s %s: %.0f%% loss, avg %.3f ms", opts.Mode, t.IP, stats.Loss, ms(stats.Avg))
+	default:
+		c.Status, c.Detail = StatusOK, fmt.Sprintf("%s %s: avg %.3f ms", opts.Mode, t.IP, ms(stats.Avg))
+	}
+	return c
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func printChecks(cmd *cobra.Command, checks []Check) {
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.AppendHeader(table.Row{"CHECK", "TARGET", "STATUS", "DETAIL"})
+	counts := map[string]int{}
+	for i, c := range checks {
+		if i > 0 && checks[i-1].Category != c.Category {
+			t.AppendSeparator()
+		}
+		t.AppendRow(table.Row{c.Category, c.Target, c.Status, c.Detail})
+		counts[c.Status]++
+	}
+	t.Render()
+	fmt.Fprintf(cmd.OutOrStdout(), "%s: %d ok, %d warn, %d fail\n", Overall(checks), counts[StatusOK], counts[StatusWarn], counts[StatusFail])
+}
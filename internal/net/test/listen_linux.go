@@ -0,0 +1,91 @@
+//go:build linux
+
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// 绑定到接口的 Control 函数，未指定接口时返回 nil
+func bindControl(iface string) func(network, address string, c syscall.RawConn) error {
+	if iface == "" {
+		return nil
+	}
+	return func(network, address string, c syscall.RawConn) error {
+		var serr error
+		err := c.Control(func(fd uintptr) {
+			serr = unix.SetsockoptString(int(fd), unix.SOL_SOCKET, unix.SO_BINDTODEVICE, iface)
+		})
+		if err != nil {
+			return err
+		}
+		return serr
+	}
+}
+
+// 优先使用无需特权的 datagram ICMP 套接字（受 net.ipv4.ping_group_range 控制），不允许时改用 raw 套接字
+func listenICMP(family int, src net.IP, iface string) (conn net.PacketConn, raw bool, err error) {
+	domain, proto, network := unix.AF_INET, unix.IPPROTO_ICMP, "ip4:icmp"
+	if family == 6 {
+		domain, proto, network = unix.AF_INET6, unix.IPPROTO_ICMPV6, "ip6:ipv6-icmp"
+	}
+
+	conn, err = listenDatagramICMP(domain, proto, src, iface)
+	if err == nil {
+		return conn, false, nil
+	}
+	if !errors.Is(err, unix.EACCES) && !errors.Is(err, unix.EPERM) && !errors.Is(err, unix.EPROTONOSUPPORT) {
+		return nil, false, err
+	}
+
+	address := ""
+	if src != nil {
+		address = src.String()
+	}
+	lc := net.ListenConfig{Control: bindControl(iface)}
+	conn, err = lc.ListenPacket(context.Background(), network, address)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open ICMP socket (unprivileged ICMP is not allowed and raw sockets need root): %w", err)
+	}
+	return conn, true, nil
+}
+
+func listenDatagramICMP(domain, proto int, src net.IP, iface string) (net.PacketConn, error) {
+	fd, err := unix.Socket(domain, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, proto)
+	if err != nil {
+		return nil, err
+	}
+	if iface != "" {
+		if err := unix.SetsockoptString(fd, unix.SOL_SOCKET, unix.SO_BINDTODEVICE, iface); err != nil {
+			unix.Close(fd)
+			return nil, err
+		}
+	}
+	if src != nil {
+		var sa unix.Sockaddr
+		if domain == unix.AF_INET {
+			sa4 := &unix.SockaddrInet4{}
+			copy(sa4.Addr[:], src.To4())
+			sa = sa4
+		} else {
+			sa6 := &unix.SockaddrInet6{}
+			copy(sa6.Addr[:], src.To16())
+			sa = sa6
+		}
+		if err := unix.Bind(fd, sa); err != nil {
+			unix.Close(fd)
+			return nil, err
+		}
+	}
+
+	f := os.NewFile(uintptr(fd), "icmp")
+	defer f.Close()
+	return net.FilePacketConn(f)
+}
@@ -0,0 +1,36 @@
+//go:build !linux
+
+package test
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"golang.org/x/net/icmp"
+)
+
+// 只有 linux 支持绑定到接口，其他平台通过源地址选择接口
+func bindControl(iface string) func(network, address string, c syscall.RawConn) error {
+	return nil
+}
+
+// 优先使用无需特权的 datagram ICMP 套接字，不支持时改用 raw 套接字（windows 上需要管理员权限）
+func listenICMP(family int, src net.IP, iface string) (conn net.PacketConn, raw bool, err error) {
+	udp, network, address := "udp4", "ip4:icmp", "0.0.0.0"
+	if family == 6 {
+		udp, network, address = "udp6", "ip6:ipv6-icmp", "::"
+	}
+	if src != nil {
+		address = src.String()
+	}
+
+	if c, err := icmp.ListenPacket(udp, address); err == nil {
+		return c, false, nil
+	}
+	c, err := icmp.ListenPacket(network, address)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open ICMP socket: %w", err)
+	}
+	return c, true, nil
+}
@@ -0,0 +1,372 @@
+package test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// 探测方式
+const (
+	ModeICMP = "icmp"
+	ModeTCP  = "tcp"
+	ModeHTTP = "http"
+	// 仅用于 trace
+	ModeUDP = "udp"
+)
+
+// 一次探测的参数
+type Options struct {
+	Mode string
+	// tcp 模式的端口
+	Port     int
+	Count    int
+	Interval time.Duration
+	Timeout  time.Duration
+	// 发送探测使用的接口，为空时由路由决定
+	Iface string
+	// 4 或 6，0 表示使用解析得到的第一个地址
+	Family int
+	// http 模式下不校验证书
+	Insecure bool
+}
+
+// 单次探测的结果
+type Probe struct {
+	Seq int
+	RTT time.Duration
+	// 为 nil 表示收到了回应
+	Err error
+	// http 模式的状态码
+	Status int
+}
+
+// 一组探测的统计，RTT 只统计收到回应的探测
+type Stats struct {
+	Sent     int
+	Received int
+	Loss     float64
+	Min      time.Duration
+	Avg      time.Duration
+	Max      time.Duration
+	Mdev     time.Duration
+}
+
+// 探测的目标
+type Target struct {
+	Host string
+	IP   net.IP
+	// http 模式请求的地址
+	URL string
+}
+
+// 解析探测目标，http 模式下 host 可以是完整的 URL
+func ResolveTarget(host string, opts Options) (*Target, error) {
+	target := &Target{Host: host}
+	if opts.Mode == ModeHTTP {
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+		u, err := url.Parse(host)
+		if err != nil || u.Hostname() == "" {
+			return nil, fmt.Errorf("invalid URL '%s'", target.Host)
+		}
+		target.URL = u.String()
+		target.Host = u.Hostname()
+	}
+
+	ip, err := resolve(target.Host, opts.Family)
+	if err != nil {
+		return nil, err
+	}
+	target.IP = ip
+	return target, nil
+}
+
+func resolve(host string, family int) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		if family != 0 && (ip.To4() != nil) != (family == 4) {
+			return nil, fmt.Errorf("%s is not an ipv%d address", host, family)
+		}
+		return ip, nil
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, ip := range ips {
+		if family == 0 || (ip.To4() != nil) == (family == 4) {
+			return ip, nil
+		}
+	}
+	return nil, fmt.Errorf("%s has no ipv%d address", host, family)
+}
+
+// 接口上与目标地址族相同的第一个非 link-local 地址
+func SourceAddr(iface string, dst net.IP) (net.IP, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	var linkLocal net.IP
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || (ipnet.IP.To4() != nil) != (dst.To4() != nil) {
+			continue
+		}
+		if ipnet.IP.IsLinkLocalUnicast() {
+			linkLocal = ipnet.IP
+			continue
+		}
+		return ipnet.IP, nil
+	}
+	// 目标本身是 link-local 地址时才使用 link-local 源地址
+	if linkLocal != nil && dst.IsLinkLocalUnicast() {
+		return linkLocal, nil
+	}
+	return nil, fmt.Errorf("interface %s has no address of the same family as %s", iface, dst)
+}
+
+// 按 Count 和 Interval 依次探测，每次探测完成后调用 onProbe，ctx 取消时提前结束
+func Run(ctx context.Context, target *Target, opts Options, onProbe func(Probe)) (Stats, error) {
+	var src net.IP
+	if opts.Iface != "" {
+		var err error
+		if src, err = SourceAddr(opts.Iface, target.IP); err != nil {
+			return Stats{}, err
+		}
+	}
+
+	var send func(seq int) Probe
+	switch opts.Mode {
+	case ModeICMP:
+		p, err := newPinger(target.IP, src, opts)
+		if err != nil {
+			return Stats{}, err
+		}
+		defer p.Close()
+		send = p.ping
+	case ModeTCP:
+		send = func(seq int) Probe { return tcpPing(seq, target.IP, src, opts) }
+	case ModeHTTP:
+		client := httpClient(target.IP, src, opts)
+		send = func(seq int) Probe { return httpGet(seq, client, target.URL, opts) }
+	default:
+		return Stats{}, fmt.Errorf("unsupported mode '%s' (value: icmp, tcp, http)", opts.Mode)
+	}
+
+	var probes []Probe
+	for seq := 1; opts.Count <= 0 || seq <= opts.Count; seq++ {
+		if seq > 1 {
+			select {
+			case <-ctx.Done():
+				return summarize(probes), nil
+			case <-time.After(opts.Interval):
+			}
+		}
+		p := send(seq)
+		probes = append(probes, p)
+		if onProbe != nil {
+			onProbe(p)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return summarize(probes), nil
+}
+
+// mdev 与 ping 的算法一致
+func summarize(probes []Probe) Stats {
+	s := Stats{Sent: len(probes)}
+	var sum, sum2 float64
+	for _, p := range probes {
+		if p.Err != nil {
+			continue
+		}
+		s.Received++
+		if s.Min == 0 || p.RTT < s.Min {
+			s.Min = p.RTT
+		}
+		if p.RTT > s.Max {
+			s.Max = p.RTT
+		}
+		sum += float64(p.RTT)
+		sum2 += float64(p.RTT) * float64(p.RTT)
+	}
+	if s.Sent > 0 {
+		s.Loss = float64(s.Sent-s.Received) * 100 / float64(s.Sent)
+	}
+	if s.Received > 0 {
+		avg := sum / float64(s.Received)
+		s.Avg = time.Duration(avg)
+		s.Mdev = time.Duration(math.Sqrt(math.Max(sum2/float64(s.Received)-avg*avg, 0)))
+	}
+	return s
+}
+
+func dialer(src net.IP, opts Options) *net.Dialer {
+	d := &net.Dialer{Timeout: opts.Timeout, Control: bindControl(opts.Iface)}
+	if src != nil {
+		d.LocalAddr = &net.TCPAddr{IP: src}
+	}
+	return d
+}
+
+// 以完成三次握手的耗时作为 RTT
+func tcpPing(seq int, ip, src net.IP, opts Options) Probe {
+	addr := net.JoinHostPort(ip.String(), strconv.Itoa(opts.Port))
+	start := time.Now()
+	conn, err := dialer(src, opts).Dial("tcp", addr)
+	if err != nil {
+		return Probe{Seq: seq, Err: err}
+	}
+	rtt := time.Since(start)
+	conn.Close()
+	return Probe{Seq: seq, RTT: rtt}
+}
+
+// 每次探测都建立新连接，连接固定到已解析的地址
+func httpClient(ip, src net.IP, opts Options) *http.Client {
+	d := dialer(src, opts)
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			_, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			return d.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		},
+		DisableKeepAlives: true,
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: opts.Insecure},
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+		// 只探测给定的地址，不跟随重定向
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// 以收到完整应答的耗时作为 RTT，任何状态码都视为收到回应
+func httpGet(seq int, client *http.Client, url string, opts Options) Probe {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return Probe{Seq: seq, Err: err}
+	}
+	req.Header.Set("User-Agent", "nctl")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return Probe{Seq: seq, Err: err}
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20)); err != nil {
+		return Probe{Seq: seq, Err: err, Status: resp.StatusCode}
+	}
+	return Probe{Seq: seq, RTT: time.Since(start), Status: resp.StatusCode}
+}
+
+// ICMP echo 探测，整个探测过程共用一个套接字
+type pinger struct {
+	conn net.PacketConn
+	dst  net.Addr
+	// datagram 套接字由内核改写 echo 的 ID，只能按序号匹配
+	raw   bool
+	proto int
+	id    int
+	opts  Options
+}
+
+func newPinger(ip, src net.IP, opts Options) (*pinger, error) {
+	family := 4
+	if ip.To4() == nil {
+		family = 6
+	}
+	conn, raw, err := listenICMP(family, src, opts.Iface)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pinger{conn: conn, raw: raw, proto: 1, id: os.Getpid() & 0xffff, opts: opts}
+	if family == 6 {
+		p.proto = 58
+	}
+	if raw {
+		p.dst = &net.IPAddr{IP: ip}
+	} else {
+		p.dst = &net.UDPAddr{IP: ip}
+	}
+	return p, nil
+}
+
+func (p *pinger) Close() error {
+	return p.conn.Close()
+}
+
+func (p *pinger) ping(seq int) Probe {
+	var typ icmp.Type = ipv4.ICMPTypeEcho
+	var reply icmp.Type = ipv4.ICMPTypeEchoReply
+	if p.proto == 58 {
+		typ, reply = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+	msg := icmp.Message{Type: typ, Body: &icmp.Echo{ID: p.id, Seq: seq & 0xffff, Data: []byte("nctl-ping-0123456789abcdefghijklmnopqrstuvwxyz")}}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return Probe{Seq: seq, Err: err}
+	}
+
+	start := time.Now()
+	deadline := start.Add(p.opts.Timeout)
+	if _, err := p.conn.WriteTo(b, p.dst); err != nil {
+		return Probe{Seq: seq, Err: err}
+	}
+	p.conn.SetReadDeadline(deadline)
+
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := p.conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return Probe{Seq: seq, Err: errors.New("request timed out")}
+			}
+			return Probe{Seq: seq, Err: err}
+		}
+		data := buf[:n]
+		// windows 上的 raw ipv4 套接字会带上 IP 头
+		if p.proto == 1 && len(data) > 20 && data[0]>>4 == 4 {
+			data = data[int(data[0]&0x0f)*4:]
+		}
+		m, err := icmp.ParseMessage(p.proto, data)
+		if err != nil || m.Type != reply {
+			continue
+		}
+		echo, ok := m.Body.(*icmp.Echo)
+		if !ok || echo.Seq != seq&0xffff || (p.raw && echo.ID != p.id) {
+			continue
+		}
+		return Probe{Seq: seq, RTT: time.Since(start)}
+	}
+}
@@ -0,0 +1,181 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func loopbackOptions(mode string, port int) Options {
+	return Options{Mode: mode, Port: port, Count: 3, Interval: time.Millisecond, Timeout: 2 * time.Second}
+}
+
+func TestRunTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	var seqs []int
+	target := &Target{Host: "127.0.0.1", IP: net.IPv4(127, 0, 0, 1)}
+	stats, err := Run(context.Background(), target, loopbackOptions(ModeTCP, ln.Addr().(*net.TCPAddr).Port), func(p Probe) {
+		if p.Err != nil {
+			t.Errorf("probe %d: %v", p.Seq, p.Err)
+		}
+		seqs = append(seqs, p.Seq)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Sent != 3 || stats.Received != 3 || stats.Loss != 0 {
+		t.Fatalf("stats = %+v, want 3/3 received", stats)
+	}
+	if len(seqs) != 3 || seqs[0] != 1 || seqs[2] != 3 {
+		t.Fatalf("seqs = %v, want [1 2 3]", seqs)
+	}
+	if stats.Min <= 0 || stats.Min > stats.Avg || stats.Avg > stats.Max {
+		t.Fatalf("stats = %+v, want 0 < min <= avg <= max", stats)
+	}
+}
+
+func TestRunTCPClosedPort(t *testing.T) {
+	// 关闭监听后端口不再有人使用，连接会被拒绝
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	target := &Target{Host: "127.0.0.1", IP: net.IPv4(127, 0, 0, 1)}
+	stats, err := Run(context.Background(), target, loopbackOptions(ModeTCP, port), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Sent != 3 || stats.Received != 0 || stats.Loss != 100 {
+		t.Fatalf("stats = %+v, want 100%% loss", stats)
+	}
+	if stats.Min != 0 || stats.Avg != 0 || stats.Max != 0 || stats.Mdev != 0 {
+		t.Fatalf("stats = %+v, want no RTT without replies", stats)
+	}
+}
+
+func TestRunHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "nctl" {
+			t.Errorf("User-Agent = %q", r.Header.Get("User-Agent"))
+		}
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		path   string
+		status int
+	}{
+		{"/", http.StatusOK},
+		// 任何状态码都视为收到回应
+		{"/missing", http.StatusNotFound},
+	} {
+		opts := loopbackOptions(ModeHTTP, 0)
+		target, err := ResolveTarget(srv.URL+tt.path, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var statuses []int
+		stats, err := Run(context.Background(), target, opts, func(p Probe) {
+			if p.Err != nil {
+				t.Errorf("%s probe %d: %v", tt.path, p.Seq, p.Err)
+			}
+			statuses = append(statuses, p.Status)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Received != 3 || stats.Loss != 0 {
+			t.Fatalf("%s: stats = %+v, want 3 received", tt.path, stats)
+		}
+		for _, s := range statuses {
+			if s != tt.status {
+				t.Fatalf("%s: statuses = %v, want %d", tt.path, statuses, tt.status)
+			}
+		}
+	}
+}
+
+func TestRunICMPLoopback(t *testing.T) {
+	conn, _, err := listenICMP(4, nil, "")
+	if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES) {
+		t.Skipf("ICMP sockets are not allowed: %v", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	target := &Target{Host: "127.0.0.1", IP: net.IPv4(127, 0, 0, 1)}
+	stats, err := Run(context.Background(), target, loopbackOptions(ModeICMP, 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Received != 3 || stats.Loss != 0 {
+		t.Fatalf("stats = %+v, want 3 received", stats)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	ms := time.Millisecond
+	lost := errors.New("request timed out")
+	tests := []struct {
+		name   string
+		probes []Probe
+		want   Stats
+	}{
+		{"empty", nil, Stats{}},
+		{"all lost", []Probe{{Err: lost}, {Err: lost}}, Stats{Sent: 2, Loss: 100}},
+		{"single", []Probe{{RTT: 5 * ms}}, Stats{Sent: 1, Received: 1, Min: 5 * ms, Avg: 5 * ms, Max: 5 * ms}},
+		{
+			// 平均值 20ms，方差 ((10-20)²+(30-20)²)/2 = 100ms²
+			"mixed",
+			[]Probe{{RTT: 10 * ms}, {Err: lost}, {RTT: 30 * ms}, {Err: lost}},
+			Stats{Sent: 4, Received: 2, Loss: 50, Min: 10 * ms, Avg: 20 * ms, Max: 30 * ms, Mdev: 10 * ms},
+		},
+		{
+			"uniform",
+			[]Probe{{RTT: 7 * ms}, {RTT: 7 * ms}, {RTT: 7 * ms}},
+			Stats{Sent: 3, Received: 3, Min: 7 * ms, Avg: 7 * ms, Max: 7 * ms},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarize(tt.probes)
+			// mdev 经过浮点开方，允许 1µs 的误差
+			if d := got.Mdev - tt.want.Mdev; d < -time.Microsecond || d > time.Microsecond {
+				t.Fatalf("mdev = %v, want %v", got.Mdev, tt.want.Mdev)
+			}
+			got.Mdev = tt.want.Mdev
+			if got != tt.want {
+				t.Fatalf("summarize = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
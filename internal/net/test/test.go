@@ -1 +1,193 @@
 package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"nctl/internal/utils/output"
+)
+
+var testOpts Options
+
+// 结构化输出中的探测结果，字段名保持稳定
+type testRecord struct {
+	Target   string        `json:"target" yaml:"target"`
+	IP       string        `json:"ip" yaml:"ip"`
+	Mode     string        `json:"mode" yaml:"mode"`
+	Probes   []probeRecord `json:"probes" yaml:"probes"`
+	Sent     int           `json:"sent" yaml:"sent"`
+	Received int           `json:"received" yaml:"received"`
+	LossPct  float64       `json:"loss_percent" yaml:"loss_percent"`
+	MinMs    *float64      `json:"min_ms" yaml:"min_ms"`
+	AvgMs    *float64      `json:"avg_ms" yaml:"avg_ms"`
+	MaxMs    *float64      `json:"max_ms" yaml:"max_ms"`
+	MdevMs   *float64      `json:"mdev_ms" yaml:"mdev_ms"`
+}
+
+type probeRecord struct {
+	Seq    int      `json:"seq" yaml:"seq"`
+	RTTMs  *float64 `json:"rtt_ms" yaml:"rtt_ms"`
+	Status *int     `json:"status,omitempty" yaml:"status,omitempty"`
+	Error  *string  `json:"error" yaml:"error"`
+}
+
+func Test() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test <host>",
+		Short: "Probe a host with ICMP echo, TCP connect or HTTP GET",
+		Long: "Probe a host with ICMP echo, TCP connect or HTTP GET and report round trip statistics.\n\n" +
+			"ICMP uses unprivileged datagram sockets where the system allows them and raw sockets otherwise.\n" +
+			"In http mode the host can be a full URL such as https://example.com/health.\n" +
+			"Exit status is 1 when no probe was answered.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := output.Format(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				os.Exit(2)
+			}
+			if testOpts.Timeout <= 0 || testOpts.Interval < 0 {
+				fmt.Fprintln(cmd.ErrOrStderr(), "Error: --timeout must be positive and --interval must not be negative")
+				os.Exit(2)
+			}
+
+			target, err := ResolveTarget(args[0], testOpts)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				os.Exit(2)
+			}
+
+			// Ctrl-C 时停止探测并输出统计
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			record := testRecord{Target: args[0], IP: target.IP.String(), Mode: testOpts.Mode, Probes: []probeRecord{}}
+			table := format == output.Table
+			if table {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\n", describeTarget(target, testOpts))
+			}
+			stats, err := Run(ctx, target, testOpts, func(p Probe) {
+				pr := toProbeRecord(p)
+				record.Probes = append(record.Probes, pr)
+				if table {
+					fmt.Fprintln(cmd.OutOrStdout(), describeProbe(target, p))
+				}
+			})
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				os.Exit(2)
+			}
+			fillStats(&record, stats)
+
+			switch format {
+			case output.Table:
+				printSummary(cmd, target, stats)
+			case output.JSON, output.YAML:
+				err = output.Write(cmd.OutOrStdout(), format, record)
+			case output.CSV:
+				err = writeCSV(cmd, record)
+			}
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				os.Exit(2)
+			}
+			if stats.Received == 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&testOpts.Mode, "mode", "m", ModeICMP, "Probe type (value: icmp, tcp, http)")
+	cmd.Flags().IntVarP(&testOpts.Port, "port", "p", 80, "Destination port in tcp mode")
+	cmd.Flags().IntVarP(&testOpts.Count, "count", "c", 4, "Number of probes, 0 means until interrupted")
+	cmd.Flags().DurationVarP(&testOpts.Interval, "interval", "i", time.Second, "Time between probes")
+	cmd.Flags().DurationVarP(&testOpts.Timeout, "timeout", "w", 2*time.Second, "Time to wait for each probe")
+	cmd.Flags().StringVarP(&testOpts.Iface, "iface", "I", "", "Send probes from this interface")
+	cmd.Flags().IntVarP(&testOpts.Family, "family", "f", 0, "Address family to use when the host resolves to both (value: 4, 6)")
+	cmd.Flags().BoolVarP(&testOpts.Insecure, "insecure", "k", false, "Do not verify TLS certificates in http mode")
+
+	// 挂载 net test trace 命令
+	cmd.AddCommand(Trace())
+	return cmd
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func describeTarget(t *Target, opts Options) string {
+	switch opts.Mode {
+	case ModeTCP:
+		return fmt.Sprintf("TCP connect %s (%s) port %d", t.Host, t.IP, opts.Port)
+	case ModeHTTP:
+		return fmt.Sprintf("HTTP GET %s (%s)", t.URL, t.IP)
+	}
+	return fmt.Sprintf("PING %s (%s)", t.Host, t.IP)
+}
+
+func describeProbe(t *Target, p Probe) string {
+	if p.Err != nil {
+		return fmt.Sprintf("seq=%d %s: %v", p.Seq, t.IP, p.Err)
+	}
+	if p.Status != 0 {
+		return fmt.Sprintf("seq=%d %s: status=%d time=%.3f ms", p.Seq, t.IP, p.Status, ms(p.RTT))
+	}
+	return fmt.Sprintf("seq=%d %s: time=%.3f ms", p.Seq, t.IP, ms(p.RTT))
+}
+
+func toProbeRecord(p Probe) probeRecord {
+	pr := probeRecord{Seq: p.Seq}
+	if p.Status != 0 {
+		status := p.Status
+		pr.Status = &status
+	}
+	if p.Err != nil {
+		msg := p.Err.Error()
+		pr.Error = &msg
+		return pr
+	}
+	rtt := ms(p.RTT)
+	pr.RTTMs = &rtt
+	return pr
+}
+
+func fillStats(r *testRecord, s Stats) {
+	r.Sent, r.Received, r.LossPct = s.Sent, s.Received, s.Loss
+	if s.Received == 0 {
+		return
+	}
+	values := []float64{ms(s.Min), ms(s.Avg), ms(s.Max), ms(s.Mdev)}
+	r.MinMs, r.AvgMs, r.MaxMs, r.MdevMs = &values[0], &values[1], &values[2], &values[3]
+}
+
+func printSummary(cmd *cobra.Command, t *Target, s Stats) {
+	w := cmd.OutOrStdout()
+	fmt.Fprintf(w, "\n--- %s statistics ---\n", t.Host)
+	fmt.Fprintf(w, "%d probes sent, %d received, %.1f%% loss\n", s.Sent, s.Received, s.Loss)
+	if s.Received > 0 {
+		fmt.Fprintf(w, "rtt min/avg/max/mdev = %.3f/%.3f/%.3f/%.3f ms\n", ms(s.Min), ms(s.Avg), ms(s.Max), ms(s.Mdev))
+	}
+}
+
+func writeCSV(cmd *cobra.Command, r testRecord) error {
+	var rows [][]string
+	for _, p := range r.Probes {
+		rtt, status, errMsg := "", "", ""
+		if p.RTTMs != nil {
+			rtt = fmt.Sprintf("%.3f", *p.RTTMs)
+		}
+		if p.Status != nil {
+			status = fmt.Sprint(*p.Status)
+		}
+		if p.Error != nil {
+			errMsg = *p.Error
+		}
+		rows = append(rows, []string{r.Target, r.IP, r.Mode, fmt.Sprint(p.Seq), rtt, status, errMsg})
+	}
+	return output.WriteCSV(cmd.OutOrStdout(), []string{"target", "ip", "mode", "seq", "rtt_ms", "status", "error"}, rows)
+}
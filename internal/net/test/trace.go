@@ -0,0 +1,398 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"nctl/internal/utils/output"
+)
+
+var (
+	traceOpts    TraceOptions
+	traceNumeric bool
+)
+
+// 结构化输出中的 trace 结果，字段名保持稳定
+type traceRecord struct {
+	Target   string      `json:"target" yaml:"target"`
+	IP       string      `json:"ip" yaml:"ip"`
+	Mode     string      `json:"mode" yaml:"mode"`
+	FirstTTL int         `json:"first_ttl" yaml:"first_ttl"`
+	MaxTTL   int         `json:"max_ttl" yaml:"max_ttl"`
+	Size     int         `json:"size" yaml:"size"`
+	Reached  bool        `json:"reached" yaml:"reached"`
+	Hops     []hopRecord `json:"hops" yaml:"hops"`
+}
+
+type hopRecord struct {
+	TTL     int           `json:"ttl" yaml:"ttl"`
+	Replies []replyRecord `json:"replies" yaml:"replies"`
+}
+
+type replyRecord struct {
+	From   *string  `json:"from" yaml:"from"`
+	Name   *string  `json:"name" yaml:"name"`
+	RTTMs  *float64 `json:"rtt_ms" yaml:"rtt_ms"`
+	Result string   `json:"result" yaml:"result"`
+	Code   string   `json:"code,omitempty" yaml:"code,omitempty"`
+	MTU    int      `json:"mtu,omitempty" yaml:"mtu,omitempty"`
+}
+
+// 结构化输出中的路径 MTU 发现结果
+type pmtuRecord struct {
+	Target string       `json:"target" yaml:"target"`
+	IP     string       `json:"ip" yaml:"ip"`
+	Mode   string       `json:"mode" yaml:"mode"`
+	PMTU   *int         `json:"pmtu" yaml:"pmtu"`
+	Steps  []stepRecord `json:"steps" yaml:"steps"`
+	Error  *string      `json:"error" yaml:"error"`
+}
+
+type stepRecord struct {
+	Size        int `json:"size" yaml:"size"`
+	replyRecord `yaml:",inline"`
+}
+
+func Trace() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trace <host>",
+		Short: "Trace the route to a host hop by hop",
+		Long: "Trace the route to a host by sending probes with increasing TTL and reporting every hop that answers.\n\n" +
+			"Linux defaults to UDP probes and reads ICMP errors from the socket error queue, so no privileges are needed;\n" +
+			"Windows supports ICMP probes over ipv4 only.\n" +
+			"With --pmtu the don't-fragment bit is set and the path MTU is discovered instead of listing hops.\n" +
+			"Exit status is 1 when the host was not reached.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := output.Format(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				os.Exit(2)
+			}
+			if err := checkTraceOptions(traceOpts); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				os.Exit(2)
+			}
+			ip, err := resolve(args[0], traceOpts.Family)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				os.Exit(2)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			if traceOpts.PMTU {
+				os.Exit(runPMTU(ctx, cmd, format, args[0], ip))
+			}
+			os.Exit(runTrace(ctx, cmd, format, args[0], ip))
+		},
+	}
+
+	cmd.Flags().StringVarP(&traceOpts.Mode, "mode", "m", defaultTraceMode, "Probe type (value: udp, icmp)")
+	cmd.Flags().IntVarP(&traceOpts.Port, "port", "p", 33434, "First destination port in udp mode, incremented for every probe")
+	cmd.Flags().IntVar(&traceOpts.FirstTTL, "first-ttl", 1, "TTL of the first hop to probe")
+	cmd.Flags().IntVar(&traceOpts.MaxTTL, "max-ttl", 30, "Maximum number of hops")
+	cmd.Flags().IntVarP(&traceOpts.Queries, "queries", "q", 3, "Number of probes per hop")
+	cmd.Flags().DurationVarP(&traceOpts.Timeout, "timeout", "w", 2*time.Second, "Time to wait for each probe")
+	cmd.Flags().StringVarP(&traceOpts.Iface, "iface", "I", "", "Send probes from this interface")
+	cmd.Flags().IntVarP(&traceOpts.Family, "family", "f", 0, "Address family to use when the host resolves to both (value: 4, 6)")
+	cmd.Flags().IntVarP(&traceOpts.Size, "size", "s", 60, "Probe packet size in bytes including the IP header")
+	cmd.Flags().BoolVarP(&traceNumeric, "numeric", "n", false, "Do not resolve hop addresses to names")
+	cmd.Flags().BoolVar(&traceOpts.PMTU, "pmtu", false, "Discover the path MTU with don't-fragment probes")
+
+	return cmd
+}
+
+func checkTraceOptions(o TraceOptions) error {
+	switch {
+	case o.Mode != ModeUDP && o.Mode != ModeICMP:
+		return fmt.Errorf("unsupported mode '%s' (value: udp, icmp)", o.Mode)
+	case o.FirstTTL < 1 || o.MaxTTL > 255 || o.FirstTTL > o.MaxTTL:
+		return fmt.Errorf("--first-ttl and --max-ttl must satisfy 1 <= first <= max <= 255")
+	case o.Queries < 1:
+		return fmt.Errorf("--queries must be at least 1")
+	case o.Timeout <= 0:
+		return fmt.Errorf("--timeout must be positive")
+	case o.Port < 1 || o.Port > 65535:
+		return fmt.Errorf("invalid port %d", o.Port)
+	case o.Size > 65535:
+		return fmt.Errorf("--size must not exceed 65535")
+	}
+	return nil
+}
+
+func runTrace(ctx context.Context, cmd *cobra.Command, format, host string, ip net.IP) int {
+	if format == output.Table {
+		fmt.Fprintf(cmd.OutOrStdout(), "trace to %s (%s), %d hops max, %d byte packets\n", host, ip, traceOpts.MaxTTL, traceOpts.Size)
+	}
+
+	// 每一跳完成后就开始反向解析，trace 结束时等待所有解析完成
+	names := newNameCache(!traceNumeric)
+	var hops []Hop
+	reached, err := RunTrace(ctx, ip, traceOpts, func(h Hop) {
+		hops = append(hops, h)
+		for _, r := range h.Replies {
+			names.lookup(r.From)
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+		return 2
+	}
+	names.wait()
+
+	record := traceRecord{
+		Target:   host,
+		IP:       ip.String(),
+		Mode:     traceOpts.Mode,
+		FirstTTL: traceOpts.FirstTTL,
+		MaxTTL:   traceOpts.MaxTTL,
+		Size:     traceOpts.Size,
+		Reached:  reached,
+		Hops:     []hopRecord{},
+	}
+	for _, h := range hops {
+		hr := hopRecord{TTL: h.TTL}
+		for _, r := range h.Replies {
+			hr.Replies = append(hr.Replies, toReplyRecord(r, names))
+		}
+		record.Hops = append(record.Hops, hr)
+	}
+
+	switch format {
+	case output.Table:
+		printHops(cmd, record)
+	case output.JSON, output.YAML:
+		err = output.Write(cmd.OutOrStdout(), format, record)
+	case output.CSV:
+		var rows [][]string
+		for _, h := range record.Hops {
+			for i, r := range h.Replies {
+				rows = append(rows, append([]string{record.Target, record.IP, fmt.Sprint(h.TTL), fmt.Sprint(i + 1)}, replyFields(r)...))
+			}
+		}
+		err = output.WriteCSV(cmd.OutOrStdout(), []string{"target", "ip", "ttl", "probe", "from", "name", "rtt_ms", "result", "code", "mtu"}, rows)
+	}
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+		return 2
+	}
+	if !reached {
+		return 1
+	}
+	return 0
+}
+
+func runPMTU(ctx context.Context, cmd *cobra.Command, format, host string, ip net.IP) int {
+	table := format == output.Table
+	if table {
+		fmt.Fprintf(cmd.OutOrStdout(), "path MTU discovery to %s (%s)\n", host, ip)
+	}
+
+	names := newNameCache(false)
+	record := pmtuRecord{Target: host, IP: ip.String(), Mode: traceOpts.Mode, Steps: []stepRecord{}}
+	pmtu, err := DiscoverPMTU(ctx, ip, traceOpts, func(s PMTUStep) {
+		step := stepRecord{Size: s.Size, replyRecord: toReplyRecord(s.Reply, names)}
+		record.Steps = append(record.Steps, step)
+		if table {
+			fmt.Fprintln(cmd.OutOrStdout(), describeStep(step))
+		}
+	})
+	if err != nil {
+		msg := err.Error()
+		record.Error = &msg
+	} else {
+		record.PMTU = &pmtu
+	}
+
+	var werr error
+	switch format {
+	case output.Table:
+		if err == nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "path MTU to %s is %d\n", host, pmtu)
+		}
+	case output.JSON, output.YAML:
+		werr = output.Write(cmd.OutOrStdout(), format, record)
+	case output.CSV:
+		var rows [][]string
+		for _, s := range record.Steps {
+			rows = append(rows, append([]string{record.Target, record.IP, fmt.Sprint(s.Size)}, replyFields(s.replyRecord)...))
+		}
+		werr = output.WriteCSV(cmd.OutOrStdout(), []string{"target", "ip", "size", "from", "name", "rtt_ms", "result", "code", "mtu"}, rows)
+	}
+	if werr != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", werr)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func toReplyRecord(r HopReply, names *nameCache) replyRecord {
+	rr := replyRecord{Result: r.Kind, Code: r.Code, MTU: r.MTU}
+	if r.From != nil {
+		from := r.From.String()
+		rr.From = &from
+		if name := names.get(r.From); name != "" {
+			rr.Name = &name
+		}
+	}
+	if r.Kind != replyTimeout {
+		rtt := ms(r.RTT)
+		rr.RTTMs = &rtt
+	}
+	return rr
+}
+
+func replyFields(r replyRecord) []string {
+	from, name, rtt, mtu := "", "", "", ""
+	if r.From != nil {
+		from = *r.From
+	}
+	if r.Name != nil {
+		name = *r.Name
+	}
+	if r.RTTMs != nil {
+		rtt = fmt.Sprintf("%.3f", *r.RTTMs)
+	}
+	if r.MTU > 0 {
+		mtu = fmt.Sprint(r.MTU)
+	}
+	return []string{from, name, rtt, r.Result, r.Code, mtu}
+}
+
+func describeStep(s stepRecord) string {
+	line := fmt.Sprintf("size=%d: %s", s.Size, s.Result)
+	if s.From != nil && s.Result != replyReached {
+		line += " from " + *s.From
+	}
+	if s.MTU > 0 {
+		line += fmt.Sprintf(" mtu=%d", s.MTU)
+	}
+	if s.RTTMs != nil {
+		line += fmt.Sprintf(" time=%.3f ms", *s.RTTMs)
+	}
+	return line
+}
+
+// 同一跳中来自同一地址的回应合并为一行，超时的探测显示为 *
+func printHops(cmd *cobra.Command, r traceRecord) {
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.AppendHeader(table.Row{"HOP", "ADDRESS", "NAME", "RTT", "NOTE"})
+	for _, h := range r.Hops {
+		var order []string
+		groups := map[string][]replyRecord{}
+		for _, rr := range h.Replies {
+			key := "*"
+			if rr.From != nil {
+				key = *rr.From
+			}
+			if _, ok := groups[key]; !ok {
+				order = append(order, key)
+			}
+			groups[key] = append(groups[key], rr)
+		}
+
+		for i, addr := range order {
+			var rtts, notes []string
+			name := ""
+			for _, rr := range groups[addr] {
+				if rr.Name != nil {
+					name = *rr.Name
+				}
+				if rr.RTTMs == nil {
+					rtts = append(rtts, "*")
+				} else {
+					rtts = append(rtts, fmt.Sprintf("%.3f ms", *rr.RTTMs))
+				}
+				note := rr.Code
+				if rr.MTU > 0 {
+					note = fmt.Sprintf("%s mtu=%d", note, rr.MTU)
+				}
+				if note != "" && !contains(notes, note) {
+					notes = append(notes, note)
+				}
+			}
+			hop := ""
+			if i == 0 {
+				hop = fmt.Sprint(h.TTL)
+			}
+			t.AppendRow(table.Row{hop, addr, name, strings.Join(rtts, "  "), strings.Join(notes, " ")})
+		}
+	}
+	t.Render()
+	if !r.Reached {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s was not reached\n", r.Target)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// 并发的反向解析，每个地址只解析一次
+type nameCache struct {
+	enabled bool
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	names   map[string]string
+}
+
+func newNameCache(enabled bool) *nameCache {
+	return &nameCache{enabled: enabled, names: map[string]string{}}
+}
+
+func (c *nameCache) lookup(ip net.IP) {
+	if !c.enabled || ip == nil {
+		return
+	}
+	key := ip.String()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.names[key]; ok {
+		return
+	}
+	c.names[key] = ""
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		names, err := net.DefaultResolver.LookupAddr(ctx, key)
+		if err != nil || len(names) == 0 {
+			return
+		}
+		c.mu.Lock()
+		c.names[key] = strings.TrimSuffix(names[0], ".")
+		c.mu.Unlock()
+	}()
+}
+
+func (c *nameCache) wait() {
+	c.wg.Wait()
+}
+
+func (c *nameCache) get(ip net.IP) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.names[ip.String()]
+}
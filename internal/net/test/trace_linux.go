@@ -0,0 +1,395 @@
+//go:build linux
+
+package test
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"golang.org/x/sys/unix"
+)
+
+// linux 上默认与 traceroute 一样使用 UDP
+const defaultTraceMode = ModeUDP
+
+// sock_extended_err 中的 ee_origin
+const (
+	eeOriginLocal = 1
+	eeOriginICMP  = 2
+	eeOriginICMP6 = 3
+)
+
+// struct sock_extended_err 的大小
+const sizeofSockExtendedErr = int(unsafe.Sizeof(unix.SockExtendedErr{}))
+
+// 通过 IP_RECVERR 从套接字的错误队列读取 ICMP 错误，UDP 模式无需特权
+type linuxProber struct {
+	fd     int
+	family int
+	dst    net.IP
+	mode   string
+	// ICMP 模式下 datagram 套接字不可用时使用 raw 套接字
+	raw  bool
+	port int
+	id   int
+	// 绑定的出接口，为空时按路由选择
+	iface string
+}
+
+func newProber(mode string, dst, src net.IP, opts TraceOptions) (prober, error) {
+	p := &linuxProber{family: 4, dst: dst, mode: mode, port: opts.Port, id: os.Getpid() & 0xffff, iface: opts.Iface}
+	domain, icmpProto := unix.AF_INET, unix.IPPROTO_ICMP
+	if dst.To4() == nil {
+		p.family, domain, icmpProto = 6, unix.AF_INET6, unix.IPPROTO_ICMPV6
+	}
+
+	var err error
+	switch mode {
+	case ModeUDP:
+		p.fd, err = unix.Socket(domain, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.IPPROTO_UDP)
+	case ModeICMP:
+		p.fd, err = unix.Socket(domain, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, icmpProto)
+		if errors.Is(err, unix.EACCES) || errors.Is(err, unix.EPERM) {
+			p.raw = true
+			p.fd, err = unix.Socket(domain, unix.SOCK_RAW|unix.SOCK_CLOEXEC, icmpProto)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported mode '%s' (value: udp, icmp)", mode)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s socket: %w", mode, err)
+	}
+
+	if err := p.setup(src, opts); err != nil {
+		unix.Close(p.fd)
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *linuxProber) setup(src net.IP, opts TraceOptions) error {
+	if opts.Iface != "" {
+		if err := unix.SetsockoptString(p.fd, unix.SOL_SOCKET, unix.SO_BINDTODEVICE, opts.Iface); err != nil {
+			return fmt.Errorf("failed to bind to %s: %w", opts.Iface, err)
+		}
+	}
+	if src != nil {
+		if err := unix.Bind(p.fd, p.sockaddr(src, 0)); err != nil {
+			return fmt.Errorf("failed to bind to %s: %w", src, err)
+		}
+	}
+
+	// PMTU 探测时设置 DF 位并忽略已缓存的路径 MTU，否则允许分片
+	pmtudisc := unix.IP_PMTUDISC_DONT
+	if opts.PMTU {
+		pmtudisc = unix.IP_PMTUDISC_PROBE
+	}
+	if p.family == 4 {
+		if err := unix.SetsockoptInt(p.fd, unix.SOL_IP, unix.IP_RECVERR, 1); err != nil {
+			return err
+		}
+		return unix.SetsockoptInt(p.fd, unix.SOL_IP, unix.IP_MTU_DISCOVER, pmtudisc)
+	}
+	if err := unix.SetsockoptInt(p.fd, unix.SOL_IPV6, unix.IPV6_RECVERR, 1); err != nil {
+		return err
+	}
+	return unix.SetsockoptInt(p.fd, unix.SOL_IPV6, unix.IPV6_MTU_DISCOVER, pmtudisc)
+}
+
+func (p *linuxProber) sockaddr(ip net.IP, port int) unix.Sockaddr {
+	if p.family == 4 {
+		sa := &unix.SockaddrInet4{Port: port}
+		copy(sa.Addr[:], ip.To4())
+		return sa
+	}
+	sa := &unix.SockaddrInet6{Port: port}
+	copy(sa.Addr[:], ip.To16())
+	return sa
+}
+
+func (p *linuxProber) Close() error {
+	return unix.Close(p.fd)
+}
+
+// 载荷中带上标记和序号，用于在 ICMP 错误引用的原始报文中识别探测
+func (p *linuxProber) packet(seq, size int) []byte {
+	// IP 头加 UDP 头或 ICMP 头
+	overhead := 28
+	if p.family == 6 {
+		overhead = 48
+	}
+	payload := make([]byte, max(size-overhead, len(traceMarker)+2))
+	copy(payload, traceMarker)
+	binary.BigEndian.PutUint16(payload[len(traceMarker):], uint16(seq))
+	if p.mode == ModeUDP {
+		return payload
+	}
+
+	var typ icmp.Type = ipv4.ICMPTypeEcho
+	if p.family == 6 {
+		typ = ipv6.ICMPTypeEchoRequest
+	}
+	msg := icmp.Message{Type: typ, Body: &icmp.Echo{ID: p.id, Seq: seq & 0xffff, Data: payload}}
+	b, _ := msg.Marshal(nil)
+	return b
+}
+
+func (p *linuxProber) probe(ttl, seq, size int, timeout time.Duration) (HopReply, error) {
+	var err error
+	if p.family == 4 {
+		err = unix.SetsockoptInt(p.fd, unix.SOL_IP, unix.IP_TTL, ttl)
+	} else {
+		err = unix.SetsockoptInt(p.fd, unix.SOL_IPV6, unix.IPV6_UNICAST_HOPS, ttl)
+	}
+	if err != nil {
+		return HopReply{}, err
+	}
+	p.drain()
+
+	// UDP 模式下每个探测使用不同的目的端口
+	port := 0
+	if p.mode == ModeUDP {
+		port = p.port + seq
+	}
+	start := time.Now()
+	if err := unix.Sendto(p.fd, p.packet(seq, size), 0, p.sockaddr(p.dst, port)); err != nil {
+		if errors.Is(err, unix.EMSGSIZE) {
+			// 超过了本机出接口的 MTU
+			return HopReply{Kind: replyTooBig, MTU: p.localMTU()}, nil
+		}
+		return HopReply{}, err
+	}
+
+	deadline := start.Add(timeout)
+	buf := make([]byte, 65536)
+	oob := make([]byte, 512)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return HopReply{Kind: replyTimeout}, nil
+		}
+		fds := []unix.PollFd{{Fd: int32(p.fd), Events: unix.POLLIN | unix.POLLERR}}
+		n, err := unix.Poll(fds, int(remaining.Milliseconds())+1)
+		if err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			return HopReply{}, err
+		}
+		if n == 0 {
+			continue
+		}
+
+		if fds[0].Revents&unix.POLLERR != 0 {
+			reply, ok := p.readError(buf, oob, seq, port)
+			if ok {
+				reply.RTT = time.Since(start)
+				return reply, nil
+			}
+			continue
+		}
+		if fds[0].Revents&unix.POLLIN != 0 {
+			from, ok := p.readReply(buf, seq)
+			if ok {
+				return HopReply{Kind: replyReached, From: from, RTT: time.Since(start)}, nil
+			}
+		}
+	}
+}
+
+// 丢弃上一个探测超时后才到达的错误和应答
+func (p *linuxProber) drain() {
+	buf := make([]byte, 65536)
+	oob := make([]byte, 512)
+	for {
+		if _, _, _, _, err := unix.Recvmsg(p.fd, buf, oob, unix.MSG_ERRQUEUE|unix.MSG_DONTWAIT); err != nil {
+			break
+		}
+	}
+	for {
+		if _, _, err := unix.Recvfrom(p.fd, buf, unix.MSG_DONTWAIT); err != nil {
+			break
+		}
+	}
+}
+
+// 读取错误队列中的一条 ICMP 错误，与当前探测无关时返回 false
+func (p *linuxProber) readError(buf, oob []byte, seq, port int) (HopReply, bool) {
+	n, oobn, _, from, err := unix.Recvmsg(p.fd, buf, oob, unix.MSG_ERRQUEUE|unix.MSG_DONTWAIT)
+	if err != nil {
+		return HopReply{}, false
+	}
+	// UDP 按原始报文的目的端口匹配，ICMP 按原始 echo 请求中的序号匹配
+	if p.mode == ModeUDP {
+		if sa, ok := from.(*unix.SockaddrInet4); ok && sa.Port != port {
+			return HopReply{}, false
+		}
+		if sa, ok := from.(*unix.SockaddrInet6); ok && sa.Port != port {
+			return HopReply{}, false
+		}
+	} else if n >= 8 && int(binary.BigEndian.Uint16(buf[6:8])) != seq&0xffff {
+		return HopReply{}, false
+	}
+
+	ee, rest := extendedErr(oob[:oobn])
+	if ee == nil {
+		return HopReply{}, false
+	}
+	reply := HopReply{From: offender(rest)}
+	switch ee.Origin {
+	case eeOriginLocal:
+		if ee.Errno != uint32(unix.EMSGSIZE) {
+			return HopReply{}, false
+		}
+		reply.Kind, reply.MTU = replyTooBig, int(ee.Info)
+	case eeOriginICMP:
+		reply.Kind, reply.Code = classifyICMP4(ee.Type, ee.Code)
+		if reply.Kind == replyTooBig {
+			reply.MTU = int(ee.Info)
+		}
+	case eeOriginICMP6:
+		reply.Kind, reply.Code = classifyICMP6(ee.Type, ee.Code)
+		if reply.Kind == replyTooBig {
+			reply.MTU = int(ee.Info)
+		}
+	default:
+		return HopReply{}, false
+	}
+	return reply, true
+}
+
+// 从控制消息中取出 IP_RECVERR 或 IPV6_RECVERR 的 sock_extended_err 及其后的数据
+func extendedErr(oob []byte) (*unix.SockExtendedErr, []byte) {
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, nil
+	}
+	for _, m := range msgs {
+		if !(m.Header.Level == unix.SOL_IP && m.Header.Type == unix.IP_RECVERR) &&
+			!(m.Header.Level == unix.SOL_IPV6 && m.Header.Type == unix.IPV6_RECVERR) {
+			continue
+		}
+		if len(m.Data) < sizeofSockExtendedErr {
+			continue
+		}
+		return (*unix.SockExtendedErr)(unsafe.Pointer(&m.Data[0])), m.Data[sizeofSockExtendedErr:]
+	}
+	return nil, nil
+}
+
+// 正常的应答：ICMP echo reply，或者 UDP 目的端口上的服务直接回复了数据
+func (p *linuxProber) readReply(buf []byte, seq int) (net.IP, bool) {
+	n, from, err := unix.Recvfrom(p.fd, buf, unix.MSG_DONTWAIT)
+	if err != nil {
+		return nil, false
+	}
+	ip := sockaddrIP(from)
+	if p.mode == ModeUDP {
+		return ip, ip.Equal(p.dst)
+	}
+
+	data := buf[:n]
+	proto := 58
+	if p.family == 4 {
+		proto = 1
+		// raw ipv4 套接字收到的报文带 IP 头
+		if p.raw && len(data) > 20 {
+			data = data[int(data[0]&0x0f)*4:]
+		}
+	}
+	m, err := icmp.ParseMessage(proto, data)
+	if err != nil || (m.Type != ipv4.ICMPTypeEchoReply && m.Type != ipv6.ICMPTypeEchoReply) {
+		return nil, false
+	}
+	echo, ok := m.Body.(*icmp.Echo)
+	if !ok || echo.Seq != seq&0xffff || (p.raw && echo.ID != p.id) {
+		return nil, false
+	}
+	return ip, true
+}
+
+// 发送时本机返回 EMSGSIZE 后的出接口 MTU：内核同时把本地错误放入错误队列，ee_info 即为 MTU；
+// 读取不到时再查询路由 MTU
+func (p *linuxProber) localMTU() int {
+	buf := make([]byte, 65536)
+	oob := make([]byte, 512)
+	for {
+		_, oobn, _, _, err := unix.Recvmsg(p.fd, buf, oob, unix.MSG_ERRQUEUE|unix.MSG_DONTWAIT)
+		if err != nil {
+			break
+		}
+		if ee, _ := extendedErr(oob[:oobn]); ee != nil && ee.Origin == eeOriginLocal && ee.Errno == uint32(unix.EMSGSIZE) {
+			return int(ee.Info)
+		}
+	}
+	return p.routeMTU()
+}
+
+// 本机到目标的路由 MTU，IP_MTU 只能在已连接的套接字上查询，探测用的套接字未连接，这里另开一个
+func (p *linuxProber) routeMTU() int {
+	domain := unix.AF_INET
+	if p.family == 6 {
+		domain = unix.AF_INET6
+	}
+	fd, err := unix.Socket(domain, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.IPPROTO_UDP)
+	if err != nil {
+		return 0
+	}
+	defer unix.Close(fd)
+	if p.iface != "" {
+		if err := unix.SetsockoptString(fd, unix.SOL_SOCKET, unix.SO_BINDTODEVICE, p.iface); err != nil {
+			return 0
+		}
+	}
+	// UDP 的 connect 只选择路由，不发送报文
+	if err := unix.Connect(fd, p.sockaddr(p.dst, 9)); err != nil {
+		return 0
+	}
+
+	var mtu int
+	if p.family == 4 {
+		mtu, err = unix.GetsockoptInt(fd, unix.SOL_IP, unix.IP_MTU)
+	} else {
+		mtu, err = unix.GetsockoptInt(fd, unix.SOL_IPV6, unix.IPV6_MTU)
+	}
+	if err != nil {
+		return 0
+	}
+	return mtu
+}
+
+func sockaddrIP(sa unix.Sockaddr) net.IP {
+	switch a := sa.(type) {
+	case *unix.SockaddrInet4:
+		return net.IP(append([]byte(nil), a.Addr[:]...))
+	case *unix.SockaddrInet6:
+		return net.IP(append([]byte(nil), a.Addr[:]...))
+	}
+	return nil
+}
+
+// sock_extended_err 之后是发出 ICMP 错误的地址（SO_EE_OFFENDER）
+func offender(b []byte) net.IP {
+	if len(b) < 2 {
+		return nil
+	}
+	switch binary.NativeEndian.Uint16(b) {
+	case unix.AF_INET:
+		if len(b) >= 8 {
+			return net.IP(append([]byte(nil), b[4:8]...))
+		}
+	case unix.AF_INET6:
+		if len(b) >= 24 {
+			return net.IP(append([]byte(nil), b[8:24]...))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+//go:build linux
+
+package test
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vishvananda/netlink"
+)
+
+func TestLocalMTUWithoutQueuedError(t *testing.T) {
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skip("no loopback interface")
+	}
+	p, err := newProber(ModeUDP, net.ParseIP("127.0.0.1").To4(), nil, TraceOptions{Port: 33434, PMTU: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	// 错误队列为空时查询路由 MTU，IP_MTU 不超过 IP 报文的最大长度
+	if got, want := p.(*linuxProber).localMTU(), min(lo.MTU, 65535); got != want {
+		t.Fatalf("localMTU = %d, want %d", got, want)
+	}
+}
+
+func TestProbeTooBigForLocalLink(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("creating a veth pair requires root")
+	}
+	veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "nctl-trace0", MTU: 1280}, PeerName: "nctl-trace1"}
+	if err := netlink.LinkAdd(veth); err != nil {
+		t.Skipf("veth is not available: %v", err)
+	}
+	defer netlink.LinkDel(veth)
+	addr, _ := netlink.ParseAddr("10.99.1.1/24")
+	if err := netlink.AddrAdd(veth, addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := netlink.LinkSetUp(veth); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := newProber(ModeUDP, net.ParseIP("10.99.1.2").To4(), nil, TraceOptions{Port: 33434, PMTU: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	reply, err := p.probe(64, 1, 1400, 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Kind != replyTooBig || reply.MTU != 1280 {
+		t.Fatalf("reply = %+v, want too-big with MTU 1280", reply)
+	}
+}
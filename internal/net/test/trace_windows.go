@@ -0,0 +1,161 @@
+//go:build windows
+
+package test
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// windows 上只能通过 ICMP API 发送指定 TTL 的探测
+const defaultTraceMode = ModeICMP
+
+var (
+	modiphlpapi         = windows.NewLazySystemDLL("iphlpapi.dll")
+	procIcmpCreateFile  = modiphlpapi.NewProc("IcmpCreateFile")
+	procIcmpCloseHandle = modiphlpapi.NewProc("IcmpCloseHandle")
+	procIcmpSendEcho2Ex = modiphlpapi.NewProc("IcmpSendEcho2Ex")
+)
+
+// IP_OPTION_INFORMATION
+type ipOptionInformation struct {
+	Ttl         uint8
+	Tos         uint8
+	Flags       uint8
+	OptionsSize uint8
+	OptionsData uintptr
+}
+
+// ICMP_ECHO_REPLY
+type icmpEchoReply struct {
+	Address       [4]byte
+	Status        uint32
+	RoundTripTime uint32
+	DataSize      uint16
+	Reserved      uint16
+	Data          uintptr
+	Options       ipOptionInformation
+}
+
+// IP_STATUS 中用到的值
+const (
+	ipSuccess             = 0
+	ipDestNetUnreachable  = 11002
+	ipDestHostUnreachable = 11003
+	ipDestProtUnreachable = 11004
+	ipDestPortUnreachable = 11005
+	ipPacketTooBig        = 11009
+	ipReqTimedOut         = 11010
+	ipTTLExpiredTransit   = 11013
+	ipTTLExpiredReassem   = 11014
+	ipDestProhibited      = 11018
+
+	// IP_OPTION_INFORMATION.Flags 中的 DF 位
+	ipFlagDF = 0x2
+)
+
+type windowsProber struct {
+	handle windows.Handle
+	dst    net.IP
+	src    net.IP
+	df     bool
+}
+
+func newProber(mode string, dst, src net.IP, opts TraceOptions) (prober, error) {
+	if mode != ModeICMP {
+		return nil, fmt.Errorf("mode '%s' is not supported on windows, use --mode icmp", mode)
+	}
+	if dst.To4() == nil {
+		return nil, errors.New("trace over ipv6 is not supported on windows")
+	}
+	h, _, err := procIcmpCreateFile.Call()
+	if windows.Handle(h) == windows.InvalidHandle {
+		return nil, fmt.Errorf("IcmpCreateFile failed: %w", err)
+	}
+	return &windowsProber{handle: windows.Handle(h), dst: dst.To4(), src: src.To4(), df: opts.PMTU}, nil
+}
+
+func (p *windowsProber) Close() error {
+	procIcmpCloseHandle.Call(uintptr(p.handle))
+	return nil
+}
+
+func (p *windowsProber) probe(ttl, seq, size int, timeout time.Duration) (HopReply, error) {
+	payload := make([]byte, max(size-28, len(traceMarker)+2))
+	copy(payload, traceMarker)
+	binary.BigEndian.PutUint16(payload[len(traceMarker):], uint16(seq))
+
+	opts := ipOptionInformation{Ttl: uint8(ttl)}
+	if p.df {
+		opts.Flags = ipFlagDF
+	}
+	// 应答缓冲区需要容纳一个 ICMP_ECHO_REPLY、回显的数据和 8 字节的 ICMP 错误
+	reply := make([]byte, int(unsafe.Sizeof(icmpEchoReply{}))+len(payload)+8+64)
+
+	var src, dst uint32
+	if p.src != nil {
+		src = *(*uint32)(unsafe.Pointer(&p.src[0]))
+	}
+	dst = *(*uint32)(unsafe.Pointer(&p.dst[0]))
+
+	start := time.Now()
+	n, _, err := procIcmpSendEcho2Ex.Call(
+		uintptr(p.handle), 0, 0, 0,
+		uintptr(src), uintptr(dst),
+		uintptr(unsafe.Pointer(&payload[0])), uintptr(len(payload)),
+		uintptr(unsafe.Pointer(&opts)),
+		uintptr(unsafe.Pointer(&reply[0])), uintptr(len(reply)),
+		uintptr(timeout.Milliseconds()),
+	)
+	rtt := time.Since(start)
+
+	r := (*icmpEchoReply)(unsafe.Pointer(&reply[0]))
+	status := r.Status
+	if n == 0 {
+		// 没有应答时错误码就是 IP_STATUS
+		var errno windows.Errno
+		if !errors.As(err, &errno) {
+			return HopReply{}, fmt.Errorf("IcmpSendEcho2Ex failed: %w", err)
+		}
+		status = uint32(errno)
+		if status == ipReqTimedOut {
+			return HopReply{Kind: replyTimeout}, nil
+		}
+	}
+
+	var from net.IP
+	if r.Address != [4]byte{} {
+		from = net.IPv4(r.Address[0], r.Address[1], r.Address[2], r.Address[3])
+	}
+	out := HopReply{From: from, RTT: rtt}
+	switch status {
+	case ipSuccess:
+		out.Kind = replyReached
+	case ipTTLExpiredTransit, ipTTLExpiredReassem:
+		out.Kind = replyTTL
+	case ipPacketTooBig:
+		// windows 不返回下一跳的 MTU，由二分查找确定
+		out.Kind, out.Code = replyTooBig, "!F"
+	case ipDestNetUnreachable:
+		out.Kind, out.Code = replyUnreach, "!N"
+	case ipDestHostUnreachable:
+		out.Kind, out.Code = replyUnreach, "!H"
+	case ipDestProtUnreachable:
+		out.Kind, out.Code = replyUnreach, "!P"
+	case ipDestPortUnreachable:
+		out.Kind = replyReached
+	case ipDestProhibited:
+		out.Kind, out.Code = replyUnreach, "!X"
+	case ipReqTimedOut:
+		out = HopReply{Kind: replyTimeout}
+	default:
+		return HopReply{}, fmt.Errorf("IcmpSendEcho2Ex failed with status %d", status)
+	}
+	return out, nil
+}
@@ -0,0 +1,253 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"nctl/interfaces"
+	"nctl/internal/utils"
+)
+
+// 探测载荷的开头，后跟 2 字节序号
+const traceMarker = "NCTL"
+
+// 单个 trace 探测的结果类型
+const (
+	replyTimeout = "timeout"
+	replyTTL     = "ttl-exceeded"
+	replyReached = "reached"
+	replyUnreach = "unreachable"
+	replyTooBig  = "too-big"
+)
+
+// trace 的参数
+type TraceOptions struct {
+	// udp 或 icmp
+	Mode string
+	// udp 模式的起始目的端口，每个探测加 1
+	Port     int
+	FirstTTL int
+	MaxTTL   int
+	// 每一跳的探测次数
+	Queries int
+	Timeout time.Duration
+	Iface   string
+	Family  int
+	// 探测报文的大小，包含 IP 头
+	Size int
+	// 设置 DF 位，用于路径 MTU 发现
+	PMTU bool
+}
+
+// 一次 trace 探测的回应
+type HopReply struct {
+	// 发出回应的地址，超时时为 nil
+	From net.IP
+	RTT  time.Duration
+	Kind string
+	// 不可达的类型，与 traceroute 相同的 !H、!N、!P 等
+	Code string
+	// too-big 时报告的下一跳 MTU，未知时为 0
+	MTU int
+}
+
+// 一跳的所有探测
+type Hop struct {
+	TTL     int
+	Replies []HopReply
+}
+
+// 路径 MTU 发现中的一步
+type PMTUStep struct {
+	Size  int
+	Reply HopReply
+}
+
+// 各平台实现的探测器，每次发送一个指定 TTL 和大小的报文并等待回应
+type prober interface {
+	probe(ttl, seq, size int, timeout time.Duration) (HopReply, error)
+	Close() error
+}
+
+func openProber(ip net.IP, opts TraceOptions) (prober, error) {
+	var src net.IP
+	if opts.Iface != "" {
+		var err error
+		if src, err = SourceAddr(opts.Iface, ip); err != nil {
+			return nil, err
+		}
+	}
+	return newProber(opts.Mode, ip, src, opts)
+}
+
+// 从 FirstTTL 开始逐跳探测，每一跳完成后调用 onHop，到达目标或收到不可达时结束
+func RunTrace(ctx context.Context, ip net.IP, opts TraceOptions, onHop func(Hop)) (bool, error) {
+	p, err := openProber(ip, opts)
+	if err != nil {
+		return false, err
+	}
+	defer p.Close()
+
+	seq := 0
+	for ttl := opts.FirstTTL; ttl <= opts.MaxTTL; ttl++ {
+		hop := Hop{TTL: ttl}
+		done := false
+		for q := 0; q < opts.Queries; q++ {
+			if ctx.Err() != nil {
+				return false, nil
+			}
+			reply, err := p.probe(ttl, seq, opts.Size, opts.Timeout)
+			seq++
+			if err != nil {
+				return false, err
+			}
+			hop.Replies = append(hop.Replies, reply)
+			if reply.Kind == replyReached || reply.Kind == replyUnreach {
+				done = true
+			}
+		}
+		onHop(hop)
+		if done {
+			return hop.reached(), nil
+		}
+	}
+	return false, nil
+}
+
+func (h Hop) reached() bool {
+	for _, r := range h.Replies {
+		if r.Kind == replyReached {
+			return true
+		}
+	}
+	return false
+}
+
+// 设置 DF 位发送不同大小的报文来确定路径 MTU：先按收到的 too-big 报告逐步降低，
+// 没有报告（ICMP 被过滤）时在最小 MTU 和上一次失败的大小之间二分查找
+func DiscoverPMTU(ctx context.Context, ip net.IP, opts TraceOptions, onStep func(PMTUStep)) (int, error) {
+	opts.PMTU = true
+	p, err := openProber(ip, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer p.Close()
+
+	minMTU := 68
+	if ip.To4() == nil {
+		minMTU = 1280
+	}
+	lo, hi := 0, startMTU(ip, opts.Iface)
+	tryHi := true
+	seq := 0
+	for lo < hi {
+		size := hi
+		switch {
+		case tryHi:
+		case lo == 0:
+			size = minMTU
+		default:
+			size = (lo + hi + 1) / 2
+		}
+
+		var reply HopReply
+		for q := 0; q < opts.Queries; q++ {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			reply, err = p.probe(opts.MaxTTL, seq, size, opts.Timeout)
+			seq++
+			if err != nil {
+				return 0, err
+			}
+			if reply.Kind != replyTimeout {
+				break
+			}
+		}
+		onStep(PMTUStep{Size: size, Reply: reply})
+
+		switch {
+		case reply.Kind == replyReached:
+			lo, tryHi = size, false
+		case reply.Kind == replyTooBig && reply.MTU > 0 && reply.MTU < size:
+			hi, tryHi = reply.MTU, true
+		case reply.Kind == replyTooBig || reply.Kind == replyTimeout:
+			if size <= minMTU {
+				return 0, fmt.Errorf("no reply from %s even with %d byte packets", ip, size)
+			}
+			hi, tryHi = size-1, false
+		case reply.Kind == replyTTL:
+			return 0, fmt.Errorf("%s is more than %d hops away", ip, opts.MaxTTL)
+		default:
+			return 0, fmt.Errorf("%s is unreachable (%s from %s)", ip, reply.Code, reply.From)
+		}
+	}
+	if lo == 0 {
+		return 0, errors.New("could not determine the path MTU")
+	}
+	return lo, nil
+}
+
+// 探测的起始大小：出接口的 MTU，无法确定时为 1500
+func startMTU(ip net.IP, iface string) int {
+	if iface == "" {
+		lookup, err := utils.RouteUtils().LookupRoute(interfaces.RouteQuery{Dst: ip})
+		if err == nil {
+			iface = lookup.Route.Iface
+		}
+	}
+	if ifi, err := net.InterfaceByName(iface); err == nil && ifi.MTU > 0 {
+		return ifi.MTU
+	}
+	return 1500
+}
+
+// ICMP 错误的类型和代码对应的结果，端口不可达说明 UDP 探测已到达目标
+func classifyICMP4(typ, code uint8) (string, string) {
+	switch typ {
+	case 11:
+		return replyTTL, ""
+	case 3:
+		switch code {
+		case 0:
+			return replyUnreach, "!N"
+		case 1:
+			return replyUnreach, "!H"
+		case 2:
+			return replyUnreach, "!P"
+		case 3:
+			return replyReached, ""
+		case 4:
+			return replyTooBig, "!F"
+		case 9, 10, 13:
+			return replyUnreach, "!X"
+		}
+		return replyUnreach, fmt.Sprintf("!<%d>", code)
+	}
+	return replyUnreach, fmt.Sprintf("!<%d/%d>", typ, code)
+}
+
+func classifyICMP6(typ, code uint8) (string, string) {
+	switch typ {
+	case 3:
+		return replyTTL, ""
+	case 2:
+		return replyTooBig, "!F"
+	case 1:
+		switch code {
+		case 0:
+			return replyUnreach, "!N"
+		case 1:
+			return replyUnreach, "!X"
+		case 3:
+			return replyUnreach, "!H"
+		case 4:
+			return replyReached, ""
+		}
+		return replyUnreach, fmt.Sprintf("!<%d>", code)
+	}
+	return replyUnreach, fmt.Sprintf("!<%d/%d>", typ, code)
+}
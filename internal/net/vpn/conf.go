@@ -0,0 +1,455 @@
+package vpn
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"nctl/interfaces"
+	"nctl/internal/utils"
+)
+
+// wg-quick 在未指定 MTU 时使用的值，导出时省略
+const defaultMTU = 1420
+
+// wg-quick 配置文件的内容
+type QuickConf struct {
+	PrivateKey string
+	ListenPort int
+	FwMark     uint32
+	Addresses  []*net.IPNet
+	DNS        []net.IP
+	MTU        int
+	// 空为 auto，off 表示不添加路由，也可以是路由表编号
+	Table string
+	Peers []QuickPeer
+}
+
+type QuickPeer struct {
+	PublicKey    string
+	PresharedKey string
+	// 保留原始的 host:port，导入时再解析
+	Endpoint            string
+	AllowedIPs          []*net.IPNet
+	PersistentKeepalive int
+}
+
+// 解析 wg-quick 配置，不支持的键作为警告返回
+func ParseQuickConf(r io.Reader) (*QuickConf, []string, error) {
+	conf := &QuickConf{}
+	var warnings []string
+	var section string
+	var peer *QuickPeer
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			switch section {
+			case "interface":
+			case "peer":
+				conf.Peers = append(conf.Peers, QuickPeer{})
+				peer = &conf.Peers[len(conf.Peers)-1]
+			default:
+				return nil, nil, fmt.Errorf("line %d: unknown section [%s]", n, section)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("line %d: expected key = value", n)
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		var err error
+		switch section {
+		case "interface":
+			warning := ""
+			warning, err = conf.set(key, value)
+			if warning != "" {
+				warnings = append(warnings, fmt.Sprintf("line %d: %s", n, warning))
+			}
+		case "peer":
+			err = peer.set(key, value)
+		default:
+			err = fmt.Errorf("%s outside of a section", key)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	for i, p := range conf.Peers {
+		if p.PublicKey == "" {
+			return nil, nil, fmt.Errorf("peer %d has no PublicKey", i+1)
+		}
+	}
+	return conf, warnings, nil
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+func (c *QuickConf) set(key, value string) (string, error) {
+	var err error
+	switch key {
+	case "privatekey":
+		if _, err = interfaces.ParseWireGuardKey(value); err == nil {
+			c.PrivateKey = value
+		}
+	case "listenport":
+		c.ListenPort, err = strconv.Atoi(value)
+		if err == nil {
+			err = parseListenPort(c.ListenPort)
+		}
+	case "fwmark":
+		c.FwMark, err = parseFwMark(value)
+	case "address":
+		var ipnets []*net.IPNet
+		ipnets, err = parseAddresses(splitList(value))
+		c.Addresses = append(c.Addresses, ipnets...)
+	case "dns":
+		// 非地址的项是搜索域，wg-quick 交给 resolvconf 处理
+		var domains []string
+		for _, s := range splitList(value) {
+			if ip := net.ParseIP(s); ip != nil {
+				c.DNS = append(c.DNS, ip)
+			} else {
+				domains = append(domains, s)
+			}
+		}
+		if len(domains) > 0 {
+			return fmt.Sprintf("DNS search domains %s are ignored", strings.Join(domains, ", ")), nil
+		}
+	case "mtu":
+		c.MTU, err = strconv.Atoi(value)
+	case "table":
+		c.Table = strings.ToLower(value)
+		if c.Table == "auto" {
+			c.Table = ""
+		}
+		if _, convErr := strconv.Atoi(c.Table); c.Table != "" && c.Table != "off" && convErr != nil {
+			return fmt.Sprintf("named table %s is not supported, routes go to the main table", value), nil
+		}
+	case "preup", "postup", "predown", "postdown", "saveconfig":
+		return fmt.Sprintf("%s is ignored", key), nil
+	default:
+		return fmt.Sprintf("unknown key %s is ignored", key), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid %s '%s'", key, value)
+	}
+	return "", nil
+}
+
+func (p *QuickPeer) set(key, value string) error {
+	var err error
+	switch key {
+	case "publickey":
+		if _, err = interfaces.ParseWireGuardKey(value); err == nil {
+			p.PublicKey = value
+		}
+	case "presharedkey":
+		if _, err = interfaces.ParseWireGuardKey(value); err == nil {
+			p.PresharedKey = value
+		}
+	case "endpoint":
+		if _, _, err = net.SplitHostPort(value); err == nil {
+			p.Endpoint = value
+		}
+	case "allowedips":
+		var ipnets []*net.IPNet
+		ipnets, err = parseAllowedIPs(splitList(value))
+		p.AllowedIPs = append(p.AllowedIPs, ipnets...)
+	case "persistentkeepalive":
+		if value == "off" {
+			p.PersistentKeepalive = 0
+		} else if p.PersistentKeepalive, err = strconv.Atoi(value); err == nil && (p.PersistentKeepalive < 0 || p.PersistentKeepalive > 65535) {
+			err = fmt.Errorf("out of range")
+		}
+	default:
+		return fmt.Errorf("unknown peer key %s", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s '%s'", key, value)
+	}
+	return nil
+}
+
+// 按 wg-quick 的格式输出
+func (c *QuickConf) Write(w io.Writer) error {
+	joinNets := func(ipnets []*net.IPNet) string {
+		var list []string
+		for _, ipnet := range ipnets {
+			list = append(list, ipnet.String())
+		}
+		return strings.Join(list, ", ")
+	}
+
+	var b strings.Builder
+	b.WriteString("[Interface]\n")
+	if c.PrivateKey != "" {
+		fmt.Fprintf(&b, "PrivateKey = %s\n", c.PrivateKey)
+	}
+	if c.ListenPort != 0 {
+		fmt.Fprintf(&b, "ListenPort = %d\n", c.ListenPort)
+	}
+	if c.FwMark != 0 {
+		fmt.Fprintf(&b, "FwMark = 0x%x\n", c.FwMark)
+	}
+	if len(c.Addresses) > 0 {
+		fmt.Fprintf(&b, "Address = %s\n", joinNets(c.Addresses))
+	}
+	if len(c.DNS) > 0 {
+		var list []string
+		for _, ip := range c.DNS {
+			list = append(list, ip.String())
+		}
+		fmt.Fprintf(&b, "DNS = %s\n", strings.Join(list, ", "))
+	}
+	if c.MTU != 0 {
+		fmt.Fprintf(&b, "MTU = %d\n", c.MTU)
+	}
+	if c.Table != "" {
+		fmt.Fprintf(&b, "Table = %s\n", c.Table)
+	}
+	for _, p := range c.Peers {
+		fmt.Fprintf(&b, "\n[Peer]\nPublicKey = %s\n", p.PublicKey)
+		if p.PresharedKey != "" {
+			fmt.Fprintf(&b, "PresharedKey = %s\n", p.PresharedKey)
+		}
+		if len(p.AllowedIPs) > 0 {
+			fmt.Fprintf(&b, "AllowedIPs = %s\n", joinNets(p.AllowedIPs))
+		}
+		if p.Endpoint != "" {
+			fmt.Fprintf(&b, "Endpoint = %s\n", p.Endpoint)
+		}
+		if p.PersistentKeepalive != 0 {
+			fmt.Fprintf(&b, "PersistentKeepalive = %d\n", p.PersistentKeepalive)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// 由配置文件生成内核配置，对端全部替换
+func (c *QuickConf) wireGuardConfig() (*interfaces.WireGuardConfig, error) {
+	cfg := &interfaces.WireGuardConfig{FwMark: &c.FwMark, ReplacePeers: true}
+	if c.PrivateKey != "" {
+		cfg.PrivateKey = &c.PrivateKey
+	}
+	if c.ListenPort != 0 {
+		cfg.ListenPort = &c.ListenPort
+	}
+	for _, p := range c.Peers {
+		keepalive := time.Duration(p.PersistentKeepalive) * time.Second
+		pc := interfaces.WireGuardPeerConfig{
+			PublicKey:           p.PublicKey,
+			PersistentKeepalive: &keepalive,
+			ReplaceAllowedIPs:   true,
+			AllowedIPs:          p.AllowedIPs,
+		}
+		if p.PresharedKey != "" {
+			pc.PresharedKey = &p.PresharedKey
+		}
+		if p.Endpoint != "" {
+			addr, err := parseEndpoint(p.Endpoint)
+			if err != nil {
+				return nil, err
+			}
+			pc.Endpoint = addr
+		}
+		cfg.Peers = append(cfg.Peers, pc)
+	}
+	return cfg, nil
+}
+
+func importConf() *cobra.Command {
+	var name string
+	var noRoutes bool
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Create or update a WireGuard interface from a wg-quick .conf file",
+		Long: "Create or update a WireGuard interface from a wg-quick .conf file and bring it up.\n\n" +
+			"The interface is named after the file (wg0.conf -> wg0) unless --name is given. Existing peers that\n" +
+			"are not in the file are removed. A route through the interface is added for every allowed ip except\n" +
+			"default routes, which need policy routing; PreUp, PostUp, PreDown, PostDown and SaveConfig are ignored.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if name == "" {
+				name = strings.TrimSuffix(filepath.Base(args[0]), ".conf")
+			}
+			f, err := os.Open(args[0])
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			conf, warnings, err := ParseQuickConf(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %s: %v\n", args[0], err)
+				return
+			}
+			for _, w := range warnings {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s: %s\n", args[0], w)
+			}
+			cfg, err := conf.wireGuardConfig()
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+
+			wg := utils.WireGuardUtils()
+			created := false
+			if err := utils.IfaceUtils().IsExistingIface(name); err != nil {
+				if err := wg.CreateWireGuard(name); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+					return
+				}
+				created = true
+			}
+			if err := wg.ConfigureWireGuard(name, cfg); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				if created {
+					discard(cmd, wg, name)
+				}
+				return
+			}
+			if err := setupLink(name, conf.Addresses, conf.MTU, true); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				if created {
+					discard(cmd, wg, name)
+				}
+				return
+			}
+			// resolved 不可用时不影响隧道本身
+			if len(conf.DNS) > 0 {
+				if err := utils.IfaceUtils().SetDNSs(name, conf.DNS); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to set DNS servers of %s: %v\n", name, err)
+				}
+			}
+			if !noRoutes && conf.Table != "off" {
+				addRoutes(cmd, name, conf)
+			}
+
+			action := "Updated"
+			if created {
+				action = "Created"
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s WireGuard interface %s from %s with %d peer(s)\n", action, name, args[0], len(conf.Peers))
+		},
+	}
+
+	cmd.Flags().StringVarP(&name, "name", "n", "", "Interface name (default: the file name without .conf)")
+	cmd.Flags().BoolVar(&noRoutes, "no-routes", false, "Do not add routes for the allowed ips")
+	return cmd
+}
+
+// 为 allowed ips 添加经过接口的路由，已存在时替换
+func addRoutes(cmd *cobra.Command, name string, conf *QuickConf) {
+	table, _ := strconv.Atoi(conf.Table)
+	for _, p := range conf.Peers {
+		for _, dst := range p.AllowedIPs {
+			if ones, _ := dst.Mask.Size(); ones == 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: skipped default route %s, it needs policy routing (see 'nctl route rule')\n", dst)
+				continue
+			}
+			family := 4
+			if dst.IP.To4() == nil {
+				family = 6
+			}
+			r := &interfaces.Route{Family: family, Dst: dst, Iface: name, Table: table}
+			if err := utils.RouteUtils().ReplaceRoute(r); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %v\n", err)
+			}
+		}
+	}
+}
+
+func exportConf() *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "export <name>",
+		Short: "Write the configuration of a WireGuard interface as a wg-quick .conf file",
+		Long: "Write the configuration of a WireGuard interface, including addresses, DNS servers and MTU, as a\n" +
+			"wg-quick .conf file. The output contains the private key; files are created with mode 0600.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			name := args[0]
+			devices, err := utils.WireGuardUtils().ListWireGuards(name)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			d := devices[0]
+			conf := &QuickConf{PrivateKey: d.PrivateKey, ListenPort: d.ListenPort, FwMark: d.FwMark}
+			if conf.Addresses, err = utils.IfaceUtils().GetIPs(name); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			// 没有 resolved 时不导出 DNS
+			conf.DNS, _ = utils.IfaceUtils().GetDNSs(name)
+			if ifi, err := net.InterfaceByName(name); err == nil && ifi.MTU != defaultMTU {
+				conf.MTU = ifi.MTU
+			}
+			for _, p := range d.Peers {
+				qp := QuickPeer{PublicKey: p.PublicKey, PresharedKey: p.PresharedKey, AllowedIPs: p.AllowedIPs,
+					PersistentKeepalive: int(p.PersistentKeepalive / time.Second)}
+				if p.Endpoint != nil {
+					qp.Endpoint = p.Endpoint.String()
+				}
+				conf.Peers = append(conf.Peers, qp)
+			}
+			if conf.PrivateKey == "" {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s has no private key\n", name)
+			}
+
+			if file == "" {
+				err = conf.Write(cmd.OutOrStdout())
+			} else {
+				err = writeConf(file, conf)
+			}
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			if file != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "Exported %s to %s\n", name, file)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Write to a file instead of standard output")
+	return cmd
+}
+
+func writeConf(path string, conf *QuickConf) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := conf.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,129 @@
+package vpn
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	testPrivateKey = "QUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUFBQUE="
+	testPeerKey    = "QkJCQkJCQkJCQkJCQkJCQkJCQkJCQkJCQkJCQkJCQkI="
+	testPSK        = "Q0NDQ0NDQ0NDQ0NDQ0NDQ0NDQ0NDQ0NDQ0NDQ0NDQ0M="
+)
+
+func TestQuickConfRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		want     string
+		warnings int
+	}{
+		{
+			name: "multiple address and allowed ips lines",
+			in: `[Interface]
+PrivateKey = ` + testPrivateKey + `
+Address = 10.0.0.2/24
+Address = fd00::2/64, 10.0.1.2/24
+ListenPort = 51820
+
+[Peer]
+PublicKey = ` + testPeerKey + `
+AllowedIPs = 10.0.0.0/24
+AllowedIPs = fd00::/64,192.168.0.0/16
+Endpoint = vpn.example.com:51820
+PersistentKeepalive = 25
+`,
+			want: `[Interface]
+PrivateKey = ` + testPrivateKey + `
+ListenPort = 51820
+Address = 10.0.0.2/24, fd00::2/64, 10.0.1.2/24
+
+[Peer]
+PublicKey = ` + testPeerKey + `
+AllowedIPs = 10.0.0.0/24, fd00::/64, 192.168.0.0/16
+Endpoint = vpn.example.com:51820
+PersistentKeepalive = 25
+`,
+		},
+		{
+			name: "dns with search domains",
+			in: `[Interface]
+PrivateKey = ` + testPrivateKey + `
+DNS = 10.0.0.1, corp.example.com, fd00::1
+MTU = 1380
+`,
+			want: `[Interface]
+PrivateKey = ` + testPrivateKey + `
+DNS = 10.0.0.1, fd00::1
+MTU = 1380
+`,
+			warnings: 1,
+		},
+		{
+			name: "table off",
+			in: `[Interface]
+PrivateKey = ` + testPrivateKey + `
+FwMark = 51820
+Table = off
+PostUp = ip rule add table 200
+
+[Peer]
+PublicKey = ` + testPeerKey + `
+PresharedKey = ` + testPSK + `
+AllowedIPs = 0.0.0.0/0, ::/0
+`,
+			want: `[Interface]
+PrivateKey = ` + testPrivateKey + `
+FwMark = 0xca6c
+Table = off
+
+[Peer]
+PublicKey = ` + testPeerKey + `
+PresharedKey = ` + testPSK + `
+AllowedIPs = 0.0.0.0/0, ::/0
+`,
+			warnings: 1,
+		},
+		{
+			name: "table auto is omitted",
+			in: `[Interface]
+Table = auto
+`,
+			want: "[Interface]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, warnings, err := ParseQuickConf(strings.NewReader(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(warnings) != tt.warnings {
+				t.Fatalf("warnings = %v, want %d", warnings, tt.warnings)
+			}
+
+			var buf bytes.Buffer
+			if err := conf.Write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Fatalf("Write:\n%s\nwant:\n%s", buf.String(), tt.want)
+			}
+
+			// 写出的文件再次解析得到相同的配置
+			again, warnings, err := ParseQuickConf(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(warnings) != 0 {
+				t.Fatalf("warnings on the written file: %v", warnings)
+			}
+			if !reflect.DeepEqual(again, conf) {
+				t.Fatalf("round trip = %+v, want %+v", again, conf)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package vpn
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"nctl/interfaces"
+	"nctl/internal/utils"
+	"nctl/internal/utils/output"
+)
+
+// 结构化输出中的接口，字段名保持稳定，不包含私钥
+type deviceRecord struct {
+	Name       string       `json:"name" yaml:"name"`
+	PublicKey  string       `json:"public_key" yaml:"public_key"`
+	ListenPort int          `json:"listen_port" yaml:"listen_port"`
+	FwMark     uint32       `json:"fwmark" yaml:"fwmark"`
+	Peers      []peerRecord `json:"peers" yaml:"peers"`
+}
+
+type peerRecord struct {
+	PublicKey    string   `json:"public_key" yaml:"public_key"`
+	PresharedKey bool     `json:"preshared_key" yaml:"preshared_key"`
+	Endpoint     string   `json:"endpoint" yaml:"endpoint"`
+	AllowedIPs   []string `json:"allowed_ips" yaml:"allowed_ips"`
+	Keepalive    int      `json:"keepalive" yaml:"keepalive"`
+	// 从未握手时为 null
+	LatestHandshake *time.Time `json:"latest_handshake" yaml:"latest_handshake"`
+	RxBytes         uint64     `json:"rx_bytes" yaml:"rx_bytes"`
+	TxBytes         uint64     `json:"tx_bytes" yaml:"tx_bytes"`
+}
+
+func toRecord(d interfaces.WireGuardDevice) deviceRecord {
+	r := deviceRecord{Name: d.Name, PublicKey: d.PublicKey, ListenPort: d.ListenPort, FwMark: d.FwMark, Peers: []peerRecord{}}
+	for _, p := range d.Peers {
+		pr := peerRecord{
+			PublicKey:    p.PublicKey,
+			PresharedKey: p.PresharedKey != "",
+			Keepalive:    int(p.PersistentKeepalive / time.Second),
+			AllowedIPs:   []string{},
+			RxBytes:      p.RxBytes,
+			TxBytes:      p.TxBytes,
+		}
+		if p.Endpoint != nil {
+			pr.Endpoint = p.Endpoint.String()
+		}
+		for _, ipnet := range p.AllowedIPs {
+			pr.AllowedIPs = append(pr.AllowedIPs, ipnet.String())
+		}
+		if !p.LastHandshake.IsZero() {
+			t := p.LastHandshake
+			pr.LatestHandshake = &t
+		}
+		r.Peers = append(r.Peers, pr)
+	}
+	return r
+}
+
+func show() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show [name]",
+		Short: "Show WireGuard interfaces with the handshake and transfer of every peer",
+		Long: "Show WireGuard interfaces with the handshake and transfer of every peer.\n\n" +
+			"Without a name every WireGuard interface is shown. Private and preshared keys are never printed,\n" +
+			"use 'nctl net vpn wg export' to get the full configuration.",
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := output.Format(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return
+			}
+			name := ""
+			if len(args) == 1 {
+				name = args[0]
+			}
+			devices, err := utils.WireGuardUtils().ListWireGuards(name)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+
+			records := []deviceRecord{}
+			for _, d := range devices {
+				records = append(records, toRecord(d))
+			}
+			switch format {
+			case output.Table:
+				if len(records) == 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), "No WireGuard interfaces")
+				}
+				for i, r := range records {
+					if i > 0 {
+						fmt.Fprintln(cmd.OutOrStdout())
+					}
+					printDevice(cmd, r)
+				}
+			case output.JSON, output.YAML:
+				err = output.Write(cmd.OutOrStdout(), format, records)
+			case output.CSV:
+				// 每个对端一行，没有对端的接口也输出一行
+				var rows [][]string
+				for _, r := range records {
+					base := []string{r.Name, r.PublicKey, fmt.Sprint(r.ListenPort), fmt.Sprintf("0x%x", r.FwMark)}
+					if len(r.Peers) == 0 {
+						rows = append(rows, append(base, "", "", "", "", "", "", ""))
+					}
+					for _, p := range r.Peers {
+						handshake := ""
+						if p.LatestHandshake != nil {
+							handshake = p.LatestHandshake.Format(time.RFC3339)
+						}
+						rows = append(rows, append(append([]string{}, base...), p.PublicKey, p.Endpoint, strings.Join(p.AllowedIPs, " "),
+							fmt.Sprint(p.Keepalive), handshake, fmt.Sprint(p.RxBytes), fmt.Sprint(p.TxBytes)))
+					}
+				}
+				err = output.WriteCSV(cmd.OutOrStdout(), []string{"interface", "public_key", "listen_port", "fwmark",
+					"peer", "endpoint", "allowed_ips", "keepalive", "latest_handshake", "rx_bytes", "tx_bytes"}, rows)
+			}
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+			}
+		},
+	}
+}
+
+func printDevice(cmd *cobra.Command, r deviceRecord) {
+	w := cmd.OutOrStdout()
+	fmt.Fprintf(w, "interface: %s\n", r.Name)
+	fmt.Fprintf(w, "  public key: %s\n", orDash(r.PublicKey))
+	fmt.Fprintln(w, "  private key: (hidden)")
+	if r.ListenPort != 0 {
+		fmt.Fprintf(w, "  listening port: %d\n", r.ListenPort)
+	}
+	if r.FwMark != 0 {
+		fmt.Fprintf(w, "  fwmark: 0x%x\n", r.FwMark)
+	}
+	if len(r.Peers) == 0 {
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.AppendHeader(table.Row{"PEER", "ENDPOINT", "ALLOWED IPS", "LATEST HANDSHAKE", "RX", "TX", "KEEPALIVE"})
+	for _, p := range r.Peers {
+		keepalive := "off"
+		if p.Keepalive > 0 {
+			keepalive = fmt.Sprintf("%ds", p.Keepalive)
+		}
+		t.AppendRow(table.Row{p.PublicKey, orDash(p.Endpoint), orDash(strings.Join(p.AllowedIPs, ", ")),
+			handshakeAgo(p.LatestHandshake), humanBytes(p.RxBytes), humanBytes(p.TxBytes), keepalive})
+	}
+	t.Render()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func handshakeAgo(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	d := time.Since(*t).Round(time.Second)
+	if d < 0 {
+		d = 0
+	}
+	return d.String() + " ago"
+}
+
+// 与 wg show 一样使用 1024 进制
+func humanBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	v, i := float64(n)/unit, 0
+	for v >= unit && i < 4 {
+		v /= unit
+		i++
+	}
+	return fmt.Sprintf("%.2f %ciB", v, "KMGTP"[i])
+}
@@ -1 +1,467 @@
 package vpn
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"nctl/interfaces"
+	"nctl/internal/iface/status"
+	"nctl/internal/utils"
+)
+
+func Vpn() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "vpn",
+		Short: "Manage VPN interfaces",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	// 挂载 net vpn wg 系列命令
+	cmd.AddCommand(Wg())
+	return cmd
+}
+
+func Wg() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "wg",
+		Short: "Create and configure WireGuard interfaces",
+		Long: "Create and configure WireGuard interfaces through netlink.\n\n" +
+			"Keys are base64 strings as printed by 'wg genkey'; key files may be '-' to read standard input.\n" +
+			"Configurations can be imported from and exported to wg-quick .conf files.",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	cmd.AddCommand(create())
+	cmd.AddCommand(show())
+	cmd.AddCommand(set())
+	cmd.AddCommand(peer())
+	cmd.AddCommand(importConf())
+	cmd.AddCommand(exportConf())
+	cmd.AddCommand(genkey())
+	cmd.AddCommand(pubkey())
+	return cmd
+}
+
+// 从文件读取密钥，- 表示标准输入
+func readKeyFile(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		line, rerr := bufio.NewReader(os.Stdin).ReadString('\n')
+		data, err = []byte(line), rerr
+		if len(line) > 0 {
+			err = nil
+		}
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read key from %s: %w", path, err)
+	}
+	key := strings.TrimSpace(string(data))
+	if _, err := interfaces.ParseWireGuardKey(key); err != nil {
+		return "", fmt.Errorf("invalid key in %s", path)
+	}
+	return key, nil
+}
+
+// 解析 host:port 形式的对端地址，域名在这里解析一次
+func parseEndpoint(s string) (*net.UDPAddr, error) {
+	if _, _, err := net.SplitHostPort(s); err != nil {
+		return nil, fmt.Errorf("endpoint must be host:port, got '%s'", s)
+	}
+	addr, err := net.ResolveUDPAddr("udp", s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve endpoint '%s': %w", s, err)
+	}
+	return addr, nil
+}
+
+// 解析 allowed ips，单个地址视为主机网段
+func parseAllowedIPs(list []string) ([]*net.IPNet, error) {
+	var ipnets []*net.IPNet
+	for _, s := range list {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		if _, ipnet, err := net.ParseCIDR(s); err == nil {
+			ipnets = append(ipnets, ipnet)
+			continue
+		}
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid allowed ip '%s'", s)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			ipnets = append(ipnets, &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)})
+		} else {
+			ipnets = append(ipnets, &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)})
+		}
+	}
+	return ipnets, nil
+}
+
+// 解析接口地址，保留主机部分
+func parseAddresses(list []string) ([]*net.IPNet, error) {
+	var ipnets []*net.IPNet
+	for _, s := range list {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		ip, ipnet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address '%s', CIDR required", s)
+		}
+		ipnet.IP = ip
+		if ip4 := ip.To4(); ip4 != nil {
+			ipnet.IP = ip4
+		}
+		ipnets = append(ipnets, ipnet)
+	}
+	return ipnets, nil
+}
+
+// fwmark 接受十进制、0x 开头的十六进制或 off
+func parseFwMark(s string) (uint32, error) {
+	if s == "off" {
+		return 0, nil
+	}
+	v, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid fwmark '%s'", s)
+	}
+	return uint32(v), nil
+}
+
+func parseListenPort(port int) error {
+	if port < 0 || port > 65535 {
+		return fmt.Errorf("invalid listen port %d", port)
+	}
+	return nil
+}
+
+func create() *cobra.Command {
+	var keyFile, fwmark string
+	var listenPort, mtu int
+	var addresses []string
+	var down bool
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a WireGuard interface and bring it up",
+		Long: "Create a WireGuard interface, set its private key, listen port and addresses, and bring it up.\n\n" +
+			"Without --private-key-file a new private key is generated. The public key is printed so it can be\n" +
+			"given to the peers.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			name := args[0]
+			cfg := &interfaces.WireGuardConfig{}
+			var err error
+			key := ""
+			if keyFile != "" {
+				key, err = readKeyFile(keyFile)
+			} else {
+				key, err = interfaces.GenerateWireGuardKey()
+			}
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			cfg.PrivateKey = &key
+			if cmd.Flags().Changed("listen-port") {
+				if err := parseListenPort(listenPort); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+					return
+				}
+				cfg.ListenPort = &listenPort
+			}
+			if fwmark != "" {
+				mark, err := parseFwMark(fwmark)
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+					return
+				}
+				cfg.FwMark = &mark
+			}
+			ipnets, err := parseAddresses(addresses)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+
+			wg := utils.WireGuardUtils()
+			if err := wg.CreateWireGuard(name); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			if err := wg.ConfigureWireGuard(name, cfg); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				discard(cmd, wg, name)
+				return
+			}
+			if err := setupLink(name, ipnets, mtu, !down); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				discard(cmd, wg, name)
+				return
+			}
+
+			pub, _ := interfaces.WireGuardPublicKey(key)
+			fmt.Fprintf(cmd.OutOrStdout(), "Created WireGuard interface %s\npublic key: %s\n", name, pub)
+		},
+	}
+
+	cmd.Flags().StringVar(&keyFile, "private-key-file", "", "Read the private key from a file instead of generating one")
+	cmd.Flags().IntVarP(&listenPort, "listen-port", "p", 0, "UDP port to listen on (random by default)")
+	cmd.Flags().StringVar(&fwmark, "fwmark", "", "Firewall mark for outgoing packets (number, 0x hex or off)")
+	cmd.Flags().StringSliceVarP(&addresses, "address", "a", nil, "Addresses of the interface in CIDR notation")
+	cmd.Flags().IntVar(&mtu, "mtu", 0, "MTU of the interface (kernel default 1420)")
+	cmd.Flags().BoolVar(&down, "down", false, "Leave the interface down")
+	return cmd
+}
+
+// 删除配置失败的新建接口，避免留下配置不完整的接口
+func discard(cmd *cobra.Command, wg interfaces.WireGuards, name string) {
+	if err := wg.DeleteWireGuard(name); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to remove %s: %v\n", name, err)
+	}
+}
+
+// 设置接口的地址和 mtu，然后按需启用
+func setupLink(name string, addresses []*net.IPNet, mtu int, up bool) error {
+	iface := utils.IfaceUtils()
+	if len(addresses) > 0 {
+		if err := iface.SetIPs(name, addresses); err != nil {
+			return err
+		}
+	}
+	if mtu > 0 {
+		if err := iface.SetMTU(name, mtu); err != nil {
+			return err
+		}
+	}
+	if up {
+		return status.Toggle(name, true)
+	}
+	return nil
+}
+
+func set() *cobra.Command {
+	var keyFile, fwmark string
+	var listenPort int
+	var generate bool
+	cmd := &cobra.Command{
+		Use:   "set <name>",
+		Short: "Change the private key, listen port or fwmark of a WireGuard interface",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := &interfaces.WireGuardConfig{}
+			if keyFile != "" && generate {
+				fmt.Fprintln(cmd.ErrOrStderr(), "Error: --private-key-file and --generate-key cannot be used together")
+				return
+			}
+			var key string
+			var err error
+			switch {
+			case keyFile != "":
+				key, err = readKeyFile(keyFile)
+			case generate:
+				key, err = interfaces.GenerateWireGuardKey()
+			}
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			if key != "" {
+				cfg.PrivateKey = &key
+			}
+			if cmd.Flags().Changed("listen-port") {
+				if err := parseListenPort(listenPort); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+					return
+				}
+				cfg.ListenPort = &listenPort
+			}
+			if fwmark != "" {
+				mark, err := parseFwMark(fwmark)
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+					return
+				}
+				cfg.FwMark = &mark
+			}
+			if cfg.PrivateKey == nil && cfg.ListenPort == nil && cfg.FwMark == nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "Error: nothing to change, see --help")
+				return
+			}
+
+			if err := utils.WireGuardUtils().ConfigureWireGuard(args[0], cfg); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Updated WireGuard interface %s\n", args[0])
+			if key != "" {
+				pub, _ := interfaces.WireGuardPublicKey(key)
+				fmt.Fprintf(cmd.OutOrStdout(), "public key: %s\n", pub)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&keyFile, "private-key-file", "", "Read a new private key from a file")
+	cmd.Flags().BoolVar(&generate, "generate-key", false, "Generate a new private key")
+	cmd.Flags().IntVarP(&listenPort, "listen-port", "p", 0, "UDP port to listen on, 0 for random")
+	cmd.Flags().StringVar(&fwmark, "fwmark", "", "Firewall mark for outgoing packets (number, 0x hex or off)")
+	return cmd
+}
+
+func peer() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "peer",
+		Short: "Add, update or remove peers of a WireGuard interface",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	// 挂载 net vpn wg peer 系列命令
+	cmd.AddCommand(peerAdd())
+	cmd.AddCommand(peerDel())
+	return cmd
+}
+
+func peerAdd() *cobra.Command {
+	var endpoint, pskFile string
+	var allowedIPs []string
+	var keepalive int
+	var replace bool
+	cmd := &cobra.Command{
+		Use:   "add <name> <public-key>",
+		Short: "Add a peer or update an existing one",
+		Long: "Add a peer or update an existing one. Only the given options are changed; allowed ips are\n" +
+			"appended to the current list unless --replace-allowed-ips is set.",
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if _, err := interfaces.ParseWireGuardKey(args[1]); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			p := interfaces.WireGuardPeerConfig{PublicKey: args[1], ReplaceAllowedIPs: replace}
+			var err error
+			if endpoint != "" {
+				if p.Endpoint, err = parseEndpoint(endpoint); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+					return
+				}
+			}
+			if p.AllowedIPs, err = parseAllowedIPs(allowedIPs); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			if cmd.Flags().Changed("keepalive") {
+				if keepalive < 0 || keepalive > 65535 {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Error: invalid keepalive interval %d\n", keepalive)
+					return
+				}
+				d := time.Duration(keepalive) * time.Second
+				p.PersistentKeepalive = &d
+			}
+			if pskFile != "" {
+				psk, err := readKeyFile(pskFile)
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+					return
+				}
+				p.PresharedKey = &psk
+			}
+
+			cfg := &interfaces.WireGuardConfig{Peers: []interfaces.WireGuardPeerConfig{p}}
+			if err := utils.WireGuardUtils().ConfigureWireGuard(args[0], cfg); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Configured peer %s on %s\n", args[1], args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&endpoint, "endpoint", "e", "", "Address of the peer as host:port")
+	cmd.Flags().StringSliceVar(&allowedIPs, "allowed-ips", nil, "Networks routed to and accepted from the peer")
+	cmd.Flags().BoolVar(&replace, "replace-allowed-ips", false, "Replace the allowed ips instead of appending")
+	cmd.Flags().IntVarP(&keepalive, "keepalive", "k", 0, "Persistent keepalive interval in seconds, 0 to disable")
+	cmd.Flags().StringVar(&pskFile, "preshared-key-file", "", "Read a preshared key from a file")
+	return cmd
+}
+
+func peerDel() *cobra.Command {
+	return &cobra.Command{
+		Use:   "del <name> <public-key>",
+		Short: "Remove a peer",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if _, err := interfaces.ParseWireGuardKey(args[1]); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			devices, err := utils.WireGuardUtils().ListWireGuards(args[0])
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			found := false
+			for _, p := range devices[0].Peers {
+				found = found || p.PublicKey == args[1]
+			}
+			if !found {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %s has no peer %s\n", args[0], args[1])
+				return
+			}
+
+			cfg := &interfaces.WireGuardConfig{Peers: []interfaces.WireGuardPeerConfig{{PublicKey: args[1], Remove: true}}}
+			if err := utils.WireGuardUtils().ConfigureWireGuard(args[0], cfg); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed peer %s from %s\n", args[1], args[0])
+		},
+	}
+}
+
+func genkey() *cobra.Command {
+	return &cobra.Command{
+		Use:   "genkey",
+		Short: "Generate a private key",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			key, err := interfaces.GenerateWireGuardKey()
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), key)
+		},
+	}
+}
+
+func pubkey() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pubkey",
+		Short: "Read a private key from standard input and print its public key",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			key, err := readKeyFile("-")
+			if err == nil {
+				key, err = interfaces.WireGuardPublicKey(key)
+			}
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), key)
+		},
+	}
+}
@@ -1 +1,145 @@
 package list
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"nctl/interfaces"
+	"nctl/internal/utils"
+	"nctl/internal/utils/output"
+)
+
+var (
+	listFamily   int
+	listTable    string
+	listIface    string
+	listProtocol string
+)
+
+// 结构化输出中的路由信息，字段名保持稳定
+type routeRecord struct {
+	Family   string  `json:"family" yaml:"family"`
+	Dst      string  `json:"dst" yaml:"dst"`
+	Gateway  *string `json:"gateway" yaml:"gateway"`
+	Iface    *string `json:"iface" yaml:"iface"`
+	Src      *string `json:"src" yaml:"src"`
+	Metric   int     `json:"metric" yaml:"metric"`
+	Table    string  `json:"table" yaml:"table"`
+	Scope    *string `json:"scope" yaml:"scope"`
+	Protocol string  `json:"protocol" yaml:"protocol"`
+	Type     string  `json:"type" yaml:"type"`
+}
+
+func List() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List routes in all routing tables",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := output.Format(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return
+			}
+
+			filter := interfaces.RouteFilter{
+				Family:   listFamily,
+				Iface:    listIface,
+				Protocol: listProtocol,
+			}
+			if listTable != "" && listTable != "all" {
+				if filter.Table, err = interfaces.ParseTable(listTable); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+					return
+				}
+			}
+
+			routes, err := utils.RouteUtils().ListRoutes(filter)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return
+			}
+
+			if err := printRoutes(cmd, format, routes); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+			}
+		},
+	}
+
+	cmd.Flags().IntVarP(&listFamily, "family", "f", 0, "Only show routes of this address family (value: 4, 6)")
+	cmd.Flags().StringVarP(&listTable, "table", "t", "all", "Only show routes in this table (name or number)")
+	cmd.Flags().StringVarP(&listIface, "dev", "d", "", "Only show routes through this interface")
+	cmd.Flags().StringVarP(&listProtocol, "protocol", "p", "", "Only show routes installed by this protocol (kernel, static, dhcp, ra ...)")
+
+	return cmd
+}
+
+// 空值转换为 nil
+func nullable(s string) *string {
+	if s == "" || s == "<nil>" {
+		return nil
+	}
+	return &s
+}
+
+func toRecord(r interfaces.Route) routeRecord {
+	return routeRecord{
+		Family:   "ipv" + strconv.Itoa(r.Family),
+		Dst:      r.Dst.String(),
+		Gateway:  nullable(r.Gateway.String()),
+		Iface:    nullable(r.Iface),
+		Src:      nullable(r.Src.String()),
+		Metric:   r.Metric,
+		Table:    interfaces.TableName(r.Table),
+		Scope:    nullable(r.Scope),
+		Protocol: r.Protocol,
+		Type:     r.Type,
+	}
+}
+
+func orDash(s *string) string {
+	if s == nil {
+		return "-"
+	}
+	return *s
+}
+
+func printRoutes(cmd *cobra.Command, format string, routes []interfaces.Route) error {
+	records := make([]routeRecord, 0, len(routes))
+	for _, r := range routes {
+		records = append(records, toRecord(r))
+	}
+
+	switch format {
+	case output.JSON, output.YAML:
+		return output.Write(cmd.OutOrStdout(), format, records)
+	case output.CSV:
+		var rows [][]string
+		for _, r := range records {
+			rows = append(rows, []string{
+				r.Family, r.Dst, orDash(r.Gateway), orDash(r.Iface), orDash(r.Src),
+				strconv.Itoa(r.Metric), r.Table, orDash(r.Scope), r.Protocol, r.Type,
+			})
+		}
+		return output.WriteCSV(cmd.OutOrStdout(), []string{"family", "dst", "gateway", "iface", "src", "metric", "table", "scope", "protocol", "type"}, rows)
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.AppendHeader(table.Row{"DESTINATION", "GATEWAY", "DEVICE", "SOURCE", "METRIC", "TABLE", "SCOPE", "PROTO", "TYPE"})
+	for _, r := range records {
+		dst := r.Dst
+		if dst == "0.0.0.0/0" || dst == "::/0" {
+			dst = "default (" + r.Family + ")"
+		}
+		t.AppendRow(table.Row{
+			dst, orDash(r.Gateway), orDash(r.Iface), orDash(r.Src),
+			r.Metric, r.Table, orDash(r.Scope), r.Protocol, r.Type,
+		})
+	}
+	t.Render()
+	return nil
+}
@@ -0,0 +1,35 @@
+package route
+
+import (
+	"nctl/internal/route/list"
+	"nctl/internal/route/rule"
+	"nctl/internal/route/set"
+	"nctl/internal/route/test"
+
+	"github.com/spf13/cobra"
+)
+
+var routeCmd = &cobra.Command{
+	Use:   "route",
+	Short: "Routing table management",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// 注册所有 route 下的子命令
+func RegisterRouteCommands(rootCmd *cobra.Command) {
+	// 挂载 route 子命令
+	rootCmd.AddCommand(routeCmd)
+
+	// 挂载 route list 命令
+	routeCmd.AddCommand(list.List())
+	// 挂载 route add/del/replace 命令
+	routeCmd.AddCommand(set.Add())
+	routeCmd.AddCommand(set.Del())
+	routeCmd.AddCommand(set.Replace())
+	// 挂载 route test 命令
+	routeCmd.AddCommand(test.Test())
+	// 挂载 route rule 系列命令
+	routeCmd.AddCommand(rule.Rule())
+}
@@ -0,0 +1,249 @@
+package rule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	"nctl/interfaces"
+	"nctl/internal/utils"
+	"nctl/internal/utils/output"
+)
+
+// 结构化输出中的规则信息，字段名保持稳定
+type ruleRecord struct {
+	Family            string  `json:"family" yaml:"family"`
+	Priority          int     `json:"priority" yaml:"priority"`
+	Not               bool    `json:"not" yaml:"not"`
+	From              string  `json:"from" yaml:"from"`
+	To                string  `json:"to" yaml:"to"`
+	Fwmark            *string `json:"fwmark" yaml:"fwmark"`
+	Iif               *string `json:"iif" yaml:"iif"`
+	Oif               *string `json:"oif" yaml:"oif"`
+	Action            string  `json:"action" yaml:"action"`
+	Table             *string `json:"table" yaml:"table"`
+	Goto              *int    `json:"goto" yaml:"goto"`
+	SuppressPrefixlen *int    `json:"suppress_prefixlength" yaml:"suppress_prefixlength"`
+}
+
+func Rule() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rule",
+		Short: "Policy routing rule management",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	cmd.AddCommand(list(), newRuleCommand("add", "Add a policy routing rule", "Added", interfaces.Routes.AddRule))
+	cmd.AddCommand(newRuleCommand("del", "Delete a policy routing rule", "Deleted", interfaces.Routes.DelRule))
+	return cmd
+}
+
+func list() *cobra.Command {
+	var family int
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List policy routing rules in priority order",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := output.Format(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return
+			}
+
+			rules, err := utils.RouteUtils().ListRules(family)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return
+			}
+
+			if err := printRules(cmd, format, rules); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+			}
+		},
+	}
+	cmd.Flags().IntVarP(&family, "family", "f", 0, "Only show rules of this address family (value: 4, 6)")
+	return cmd
+}
+
+// add 和 del 共用的规则参数
+type ruleFlags struct {
+	family   int
+	priority int
+	from     string
+	to       string
+	fwmark   string
+	iif      string
+	oif      string
+	table    string
+	action   string
+	gotoPrio int
+	not      bool
+	suppress int
+}
+
+func (f *ruleFlags) register(cmd *cobra.Command) {
+	cmd.Flags().IntVarP(&f.family, "family", "f", 0, "Address family when neither --from nor --to is given (value: 4, 6)")
+	cmd.Flags().IntVarP(&f.priority, "priority", "p", -1, "Rule priority, lower is evaluated first (default: assigned by the kernel)")
+	cmd.Flags().StringVar(&f.from, "from", "", "Source prefix to match")
+	cmd.Flags().StringVar(&f.to, "to", "", "Destination prefix to match")
+	cmd.Flags().StringVar(&f.fwmark, "fwmark", "", "Firewall mark to match, optionally with a mask (e.g. 0x1 or 0x100/0xff00)")
+	cmd.Flags().StringVar(&f.iif, "iif", "", "Incoming interface to match (lo for locally generated traffic)")
+	cmd.Flags().StringVar(&f.oif, "oif", "", "Outgoing interface to match (sockets bound to a device)")
+	cmd.Flags().StringVarP(&f.table, "table", "t", "", "Routing table to look up (name or number, default main)")
+	cmd.Flags().StringVarP(&f.action, "action", "a", "lookup", "Rule action (value: lookup, goto, nop, blackhole, unreachable, prohibit)")
+	cmd.Flags().IntVar(&f.gotoPrio, "goto", 0, "Priority of the rule to jump to when --action is goto")
+	cmd.Flags().BoolVar(&f.not, "not", false, "Invert the selector")
+	cmd.Flags().IntVar(&f.suppress, "suppress-prefixlength", -1, "Ignore routes with a prefix length at or below this value")
+}
+
+func (f *ruleFlags) rule() (*interfaces.Rule, error) {
+	r := &interfaces.Rule{
+		Family:            f.family,
+		Priority:          f.priority,
+		Iif:               f.iif,
+		Oif:               f.oif,
+		Invert:            f.not,
+		Action:            f.action,
+		Goto:              f.gotoPrio,
+		SuppressPrefixlen: f.suppress,
+	}
+	if r.Family != 0 && r.Family != 4 && r.Family != 6 {
+		return nil, fmt.Errorf("invalid address family %d (value: 4, 6)", r.Family)
+	}
+
+	var err error
+	if r.Src, err = interfaces.ParsePrefix(f.from); err != nil {
+		return nil, err
+	}
+	if r.Dst, err = interfaces.ParsePrefix(f.to); err != nil {
+		return nil, err
+	}
+	if r.Mark, r.Mask, err = interfaces.ParseFwmark(f.fwmark); err != nil {
+		return nil, err
+	}
+	if f.table != "" {
+		if r.Action != "lookup" {
+			return nil, fmt.Errorf("--table can only be used with the lookup action")
+		}
+		if r.Table, err = interfaces.ParseTable(f.table); err != nil {
+			return nil, err
+		}
+	}
+	if r.Action == "goto" && r.Goto <= 0 {
+		return nil, fmt.Errorf("--goto is required with the goto action")
+	}
+	return r, nil
+}
+
+func newRuleCommand(use, short, done string, apply func(interfaces.Routes, *interfaces.Rule) error) *cobra.Command {
+	var flags ruleFlags
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			r, err := flags.rule()
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			if err := apply(utils.RouteUtils(), r); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s rule\n", done)
+		},
+	}
+	flags.register(cmd)
+	return cmd
+}
+
+func toRecord(r interfaces.Rule) ruleRecord {
+	record := ruleRecord{
+		Family:   "ipv" + strconv.Itoa(r.Family),
+		Priority: r.Priority,
+		Not:      r.Invert,
+		From:     "all",
+		To:       "all",
+		Action:   r.Action,
+	}
+	if r.Src != nil {
+		record.From = r.Src.String()
+	}
+	if r.Dst != nil {
+		record.To = r.Dst.String()
+	}
+	if r.Mark != 0 || r.Mask != 0 {
+		mark := fmt.Sprintf("%#x", r.Mark)
+		if r.Mask != 0 && r.Mask != 0xffffffff {
+			mark += fmt.Sprintf("/%#x", r.Mask)
+		}
+		record.Fwmark = &mark
+	}
+	if r.Iif != "" {
+		record.Iif = &r.Iif
+	}
+	if r.Oif != "" {
+		record.Oif = &r.Oif
+	}
+	switch r.Action {
+	case "lookup":
+		t := interfaces.TableName(r.Table)
+		record.Table = &t
+	case "goto":
+		record.Goto = &r.Goto
+	}
+	if r.SuppressPrefixlen >= 0 {
+		record.SuppressPrefixlen = &r.SuppressPrefixlen
+	}
+	return record
+}
+
+func orDash(s *string) string {
+	if s == nil {
+		return "-"
+	}
+	return *s
+}
+
+func printRules(cmd *cobra.Command, format string, rules []interfaces.Rule) error {
+	records := make([]ruleRecord, 0, len(rules))
+	for _, r := range rules {
+		records = append(records, toRecord(r))
+	}
+
+	switch format {
+	case output.JSON, output.YAML:
+		return output.Write(cmd.OutOrStdout(), format, records)
+	case output.CSV:
+		var rows [][]string
+		for i, r := range records {
+			rows = append(rows, []string{
+				r.Family, strconv.Itoa(r.Priority), strconv.FormatBool(r.Not), r.From, r.To, orDash(r.Fwmark),
+				orDash(r.Iif), orDash(r.Oif), r.Action, orDash(r.Table), rules[i].String(),
+			})
+		}
+		return output.WriteCSV(cmd.OutOrStdout(), []string{"family", "priority", "not", "from", "to", "fwmark", "iif", "oif", "action", "table", "rule"}, rows)
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(cmd.OutOrStdout())
+	t.AppendHeader(table.Row{"FAMILY", "PRIORITY", "FROM", "TO", "FWMARK", "IIF", "OIF", "ACTION"})
+	for i, r := range records {
+		from := r.From
+		if r.Not {
+			from = "not " + from
+		}
+		// 动作部分沿用 ip rule 的写法，例如 lookup main、goto 100
+		action := rules[i].String()
+		action = action[strings.LastIndex(action, " "+r.Action)+1:]
+		t.AppendRow(table.Row{r.Family, r.Priority, from, r.To, orDash(r.Fwmark), orDash(r.Iif), orDash(r.Oif), action})
+	}
+	t.Render()
+	return nil
+}
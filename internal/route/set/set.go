@@ -1 +1,144 @@
 package set
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/spf13/cobra"
+
+	"nctl/interfaces"
+	"nctl/internal/utils"
+)
+
+// add、del、replace 共用的路由参数
+type routeFlags struct {
+	gateway  string
+	dev      string
+	src      string
+	metric   int
+	table    string
+	scope    string
+	protocol string
+	kind     string
+}
+
+func (f *routeFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&f.gateway, "gw", "g", "", "Next hop gateway")
+	cmd.Flags().StringVarP(&f.dev, "dev", "d", "", "Output interface")
+	cmd.Flags().StringVar(&f.src, "src", "", "Preferred source address for traffic using this route")
+	cmd.Flags().IntVarP(&f.metric, "metric", "m", 0, "Route metric (lower is preferred)")
+	cmd.Flags().StringVarP(&f.table, "table", "t", "main", "Routing table (name or number)")
+	cmd.Flags().StringVar(&f.scope, "scope", "", "Route scope (value: universe, site, link, host, nowhere)")
+	cmd.Flags().StringVar(&f.protocol, "protocol", "", "Routing protocol recorded on the route (default static)")
+	cmd.Flags().StringVar(&f.kind, "type", "", "Route type (unicast, blackhole, unreachable, prohibit ...)")
+}
+
+// 解析目的网段，default 表示默认路由，单个地址视为主机路由
+func parseDst(s string, family int) (*net.IPNet, error) {
+	if s == "default" {
+		if family == 6 {
+			return &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}, nil
+		}
+		return &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}, nil
+	}
+	if _, ipnet, err := net.ParseCIDR(s); err == nil {
+		return ipnet, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid destination '%s', CIDR, address or 'default' required", s)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// 根据命令行参数构造路由
+func (f *routeFlags) route(dst string) (*interfaces.Route, error) {
+	r := &interfaces.Route{
+		Iface:    f.dev,
+		Metric:   f.metric,
+		Scope:    f.scope,
+		Protocol: f.protocol,
+		Type:     f.kind,
+	}
+
+	family := 4
+	if f.gateway != "" {
+		if r.Gateway = net.ParseIP(f.gateway); r.Gateway == nil {
+			return nil, fmt.Errorf("invalid gateway '%s'", f.gateway)
+		}
+		if r.Gateway.To4() == nil {
+			family = 6
+		}
+	}
+	if f.src != "" {
+		if r.Src = net.ParseIP(f.src); r.Src == nil {
+			return nil, fmt.Errorf("invalid source address '%s'", f.src)
+		}
+		if r.Src.To4() == nil {
+			family = 6
+		}
+	}
+
+	var err error
+	if r.Dst, err = parseDst(dst, family); err != nil {
+		return nil, err
+	}
+	r.Family = 4
+	if r.Dst.IP.To4() == nil {
+		r.Family = 6
+	}
+	if r.Gateway != nil && (r.Gateway.To4() == nil) != (r.Family == 6) {
+		return nil, fmt.Errorf("gateway %s and destination %s are not the same address family", r.Gateway, r.Dst)
+	}
+	if r.Metric < 0 {
+		return nil, fmt.Errorf("invalid metric %d", r.Metric)
+	}
+	if r.Table, err = interfaces.ParseTable(f.table); err != nil {
+		return nil, err
+	}
+	if r.Iface != "" {
+		if err := utils.IfaceUtils().IsExistingIface(r.Iface); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func newRouteCommand(use, short, done string, apply func(interfaces.Routes, *interfaces.Route) error) *cobra.Command {
+	var flags routeFlags
+	cmd := &cobra.Command{
+		Use:   use + " <destination>",
+		Short: short,
+		Long:  short + ".\n\nThe destination is a CIDR prefix, a single address or 'default'.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			r, err := flags.route(args[0])
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			if err := apply(utils.RouteUtils(), r); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s route %s\n", done, r.Dst)
+		},
+	}
+	flags.register(cmd)
+	return cmd
+}
+
+func Add() *cobra.Command {
+	return newRouteCommand("add", "Add a route", "Added", interfaces.Routes.AddRoute)
+}
+
+func Del() *cobra.Command {
+	return newRouteCommand("del", "Delete a route", "Deleted", interfaces.Routes.DelRoute)
+}
+
+func Replace() *cobra.Command {
+	return newRouteCommand("replace", "Add a route or replace the one with the same destination", "Replaced", interfaces.Routes.ReplaceRoute)
+}
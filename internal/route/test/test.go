@@ -1 +1,150 @@
 package test
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"nctl/interfaces"
+	"nctl/internal/utils"
+	"nctl/internal/utils/output"
+)
+
+var (
+	testFrom string
+	testMark uint32
+	testIif  string
+)
+
+// 结构化输出中的查询结果，字段名保持稳定
+type lookupRecord struct {
+	Destination string  `json:"destination" yaml:"destination"`
+	Route       string  `json:"route" yaml:"route"`
+	Type        string  `json:"type" yaml:"type"`
+	Gateway     *string `json:"gateway" yaml:"gateway"`
+	Iface       *string `json:"iface" yaml:"iface"`
+	Src         *string `json:"src" yaml:"src"`
+	Table       string  `json:"table" yaml:"table"`
+	Metric      int     `json:"metric" yaml:"metric"`
+	Protocol    *string `json:"protocol" yaml:"protocol"`
+	Rule        *string `json:"rule" yaml:"rule"`
+}
+
+func Test() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test <destination>",
+		Short: "Show which route, source address and interface a destination uses",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			format, err := output.Format(cmd)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+				return
+			}
+
+			q, err := buildQuery(args[0])
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+
+			result, err := utils.RouteUtils().LookupRoute(q)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				return
+			}
+
+			if err := printLookup(cmd, format, q, result); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%v\n", err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&testFrom, "from", "", "Source address of the packet")
+	cmd.Flags().Uint32Var(&testMark, "mark", 0, "Firewall mark of the packet")
+	cmd.Flags().StringVar(&testIif, "iif", "", "Pretend the packet was received on this interface (usually needs --from)")
+
+	return cmd
+}
+
+// 目的地址可以是 ip 或主机名
+func buildQuery(dest string) (interfaces.RouteQuery, error) {
+	q := interfaces.RouteQuery{Mark: testMark, Iif: testIif}
+
+	if q.Dst = net.ParseIP(dest); q.Dst == nil {
+		ips, err := net.LookupIP(dest)
+		if err != nil || len(ips) == 0 {
+			return q, fmt.Errorf("invalid destination '%s': %v", dest, err)
+		}
+		q.Dst = ips[0]
+	}
+	if testFrom != "" {
+		if q.Src = net.ParseIP(testFrom); q.Src == nil {
+			return q, fmt.Errorf("invalid source address '%s'", testFrom)
+		}
+		if (q.Src.To4() == nil) != (q.Dst.To4() == nil) {
+			return q, fmt.Errorf("source %s and destination %s are not the same address family", q.Src, q.Dst)
+		}
+	}
+	if testIif != "" {
+		if err := utils.IfaceUtils().IsExistingIface(testIif); err != nil {
+			return q, err
+		}
+	}
+	return q, nil
+}
+
+// 空值转换为 nil
+func nullable(s string) *string {
+	if s == "" || s == "<nil>" {
+		return nil
+	}
+	return &s
+}
+
+func orDash(s *string) string {
+	if s == nil {
+		return "-"
+	}
+	return *s
+}
+
+func printLookup(cmd *cobra.Command, format string, q interfaces.RouteQuery, l *interfaces.RouteLookup) error {
+	r := l.Route
+	record := lookupRecord{
+		Destination: q.Dst.String(),
+		Route:       r.Dst.String(),
+		Type:        r.Type,
+		Gateway:     nullable(r.Gateway.String()),
+		Iface:       nullable(r.Iface),
+		Src:         nullable(r.Src.String()),
+		Table:       interfaces.TableName(r.Table),
+		Metric:      r.Metric,
+		Protocol:    nullable(r.Protocol),
+	}
+	if l.Rule != nil {
+		record.Rule = nullable(l.Rule.String())
+	}
+
+	switch format {
+	case output.JSON, output.YAML:
+		return output.Write(cmd.OutOrStdout(), format, record)
+	case output.CSV:
+		return output.WriteCSV(cmd.OutOrStdout(),
+			[]string{"destination", "route", "type", "gateway", "iface", "src", "table", "metric", "protocol", "rule"},
+			[][]string{{record.Destination, record.Route, record.Type, orDash(record.Gateway), orDash(record.Iface),
+				orDash(record.Src), record.Table, strconv.Itoa(record.Metric), orDash(record.Protocol), orDash(record.Rule)}})
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Destination: %s\n", record.Destination)
+	fmt.Fprintf(out, "Route:       %s (%s, metric %d, proto %s)\n", record.Route, record.Type, record.Metric, orDash(record.Protocol))
+	fmt.Fprintf(out, "Gateway:     %s\n", orDash(record.Gateway))
+	fmt.Fprintf(out, "Device:      %s\n", orDash(record.Iface))
+	fmt.Fprintf(out, "Source:      %s\n", orDash(record.Src))
+	fmt.Fprintf(out, "Table:       %s\n", record.Table)
+	fmt.Fprintf(out, "Rule:        %s\n", orDash(record.Rule))
+	return nil
+}
@@ -0,0 +1,261 @@
+package dhcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// 默认的服务器和客户端端口
+const (
+	clientPort = 68
+	serverPort = 67
+)
+
+// 从服务器获取的租约
+type Lease struct {
+	Iface     string        `json:"iface"`
+	IP        net.IP        `json:"ip"`
+	Mask      net.IPMask    `json:"mask"`
+	Routers   []net.IP      `json:"routers"`
+	DNS       []net.IP      `json:"dns"`
+	Server    net.IP        `json:"server"`
+	LeaseTime time.Duration `json:"lease_time"`
+	T1        time.Duration `json:"t1"`
+	T2        time.Duration `json:"t2"`
+	Obtained  time.Time     `json:"obtained"`
+}
+
+// 租约中的地址
+func (l *Lease) IPNet() *net.IPNet {
+	return &net.IPNet{IP: l.IP, Mask: l.Mask}
+}
+
+// 租约到期时间
+func (l *Lease) Expiry() time.Time {
+	return l.Obtained.Add(l.LeaseTime)
+}
+
+// 租约中的参数是否一致（不比较时间）
+func (l *Lease) SameConfig(o *Lease) bool {
+	if !l.IP.Equal(o.IP) || l.Mask.String() != o.Mask.String() || len(l.Routers) != len(o.Routers) || len(l.DNS) != len(o.DNS) {
+		return false
+	}
+	for i := range l.Routers {
+		if !l.Routers[i].Equal(o.Routers[i]) {
+			return false
+		}
+	}
+	for i := range l.DNS {
+		if !l.DNS[i].Equal(o.DNS[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// DHCPv4 客户端
+type Client struct {
+	iface *net.Interface
+	// 单次等待回复的超时时间
+	Timeout time.Duration
+	// 每个阶段的重试次数
+	Retries int
+	// 服务器和客户端使用的 UDP 端口，默认为 67 和 68
+	ServerPort int
+	ClientPort int
+}
+
+// 创建绑定在指定接口上的客户端
+func NewClient(ifaceName string) (*Client, error) {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interface '%s': %w", ifaceName, err)
+	}
+	if len(iface.HardwareAddr) == 0 {
+		return nil, fmt.Errorf("interface '%s' has no hardware address", ifaceName)
+	}
+	return &Client{iface: iface, Timeout: 4 * time.Second, Retries: 3, ServerPort: serverPort, ClientPort: clientPort}, nil
+}
+
+func newXID() uint32 {
+	var b [4]byte
+	rand.Read(b[:])
+	return binary.BigEndian.Uint32(b[:])
+}
+
+// 打开绑定在接口客户端端口上的 udp socket
+func (c *Client) listen() (net.PacketConn, error) {
+	lc := net.ListenConfig{Control: controlFunc(c.iface.Name)}
+	conn, err := lc.ListenPacket(context.Background(), "udp4", fmt.Sprintf("0.0.0.0:%d", c.ClientPort))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on port %d: %w", c.ClientPort, err)
+	}
+	return conn, nil
+}
+
+func (c *Client) baseOptions(m *message) {
+	m.Options[optClientID] = append([]byte{1}, c.iface.HardwareAddr...)
+	m.Options[optParamRequest] = []byte{optSubnetMask, optRouter, optDNS, optLeaseTime, optRenewalTime, optRebindTime}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		m.Options[optHostname] = []byte(hostname)
+	}
+}
+
+// 发送报文并等待匹配 xid 和类型的回复，超时后重发
+func (c *Client) exchange(conn net.PacketConn, req *message, dst *net.UDPAddr, want ...byte) (*message, error) {
+	buf := make([]byte, 1500)
+	data := req.marshal()
+
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if _, err := conn.WriteTo(data, dst); err != nil {
+			return nil, fmt.Errorf("failed to send DHCP message: %w", err)
+		}
+
+		deadline := time.Now().Add(c.Timeout << attempt)
+		conn.SetReadDeadline(deadline)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				var ne net.Error
+				if errors.As(err, &ne) && ne.Timeout() {
+					break
+				}
+				return nil, fmt.Errorf("failed to receive DHCP message: %w", err)
+			}
+
+			resp, err := unmarshal(buf[:n])
+			if err != nil || resp.Op != opReply || resp.XID != req.XID {
+				continue
+			}
+			for _, t := range want {
+				if resp.msgType() == t {
+					return resp, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("no DHCP response after %d attempts", c.Retries+1)
+}
+
+// 由 ACK 报文生成租约
+func (c *Client) leaseFromAck(ack *message) (*Lease, error) {
+	if ack.msgType() == msgNak {
+		return nil, fmt.Errorf("server %s refused the request (DHCPNAK)", ack.ip(optServerID))
+	}
+
+	l := &Lease{
+		Iface:     c.iface.Name,
+		IP:        ack.YIAddr.To4(),
+		Mask:      net.IPMask(ack.Options[optSubnetMask]),
+		Routers:   ack.ips(optRouter),
+		DNS:       ack.ips(optDNS),
+		Server:    ack.ip(optServerID),
+		LeaseTime: ack.duration(optLeaseTime),
+		T1:        ack.duration(optRenewalTime),
+		T2:        ack.duration(optRebindTime),
+		Obtained:  time.Now(),
+	}
+
+	if len(l.Mask) != net.IPv4len {
+		l.Mask = l.IP.DefaultMask()
+	}
+	if l.LeaseTime == 0 {
+		l.LeaseTime = time.Hour
+	}
+	// 未给出 T1/T2 时按 RFC 2131 取租期的 50% 和 87.5%
+	if l.T1 == 0 {
+		l.T1 = l.LeaseTime / 2
+	}
+	if l.T2 == 0 {
+		l.T2 = l.LeaseTime * 7 / 8
+	}
+	return l, nil
+}
+
+// 完整的 DISCOVER/OFFER/REQUEST/ACK 流程
+func (c *Client) Obtain() (*Lease, error) {
+	conn, err := c.listen()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	bcast := &net.UDPAddr{IP: net.IPv4bcast, Port: c.ServerPort}
+	xid := newXID()
+
+	discover := newMessage(xid, c.iface.HardwareAddr, msgDiscover)
+	discover.Flags = flagBroadcast
+	c.baseOptions(discover)
+
+	offer, err := c.exchange(conn, discover, bcast, msgOffer)
+	if err != nil {
+		return nil, fmt.Errorf("DISCOVER failed: %w", err)
+	}
+
+	request := newMessage(xid, c.iface.HardwareAddr, msgRequest)
+	request.Flags = flagBroadcast
+	c.baseOptions(request)
+	request.Options[optRequestedIP] = offer.YIAddr.To4()
+	if server := offer.ip(optServerID); server != nil {
+		request.Options[optServerID] = server
+	}
+
+	ack, err := c.exchange(conn, request, bcast, msgAck, msgNak)
+	if err != nil {
+		return nil, fmt.Errorf("REQUEST failed: %w", err)
+	}
+	return c.leaseFromAck(ack)
+}
+
+// 续约，rebind 为 true 时广播请求（T2 之后），否则单播给原服务器（T1 之后）
+func (c *Client) Renew(l *Lease, rebind bool) (*Lease, error) {
+	conn, err := c.listen()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	dst := &net.UDPAddr{IP: l.Server, Port: c.ServerPort}
+	if rebind || l.Server == nil {
+		dst = &net.UDPAddr{IP: net.IPv4bcast, Port: c.ServerPort}
+	}
+
+	request := newMessage(newXID(), c.iface.HardwareAddr, msgRequest)
+	request.CIAddr = l.IP
+	c.baseOptions(request)
+
+	ack, err := c.exchange(conn, request, dst, msgAck, msgNak)
+	if err != nil {
+		return nil, fmt.Errorf("renew failed: %w", err)
+	}
+	return c.leaseFromAck(ack)
+}
+
+// 释放租约，服务器不会回复
+func (c *Client) Release(l *Lease) error {
+	if l.Server == nil {
+		return fmt.Errorf("lease has no server identifier")
+	}
+
+	conn, err := c.listen()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	release := newMessage(newXID(), c.iface.HardwareAddr, msgRelease)
+	release.CIAddr = l.IP
+	release.Options[optServerID] = l.Server.To4()
+	release.Options[optClientID] = append([]byte{1}, c.iface.HardwareAddr...)
+
+	if _, err := conn.WriteTo(release.marshal(), &net.UDPAddr{IP: l.Server, Port: c.ServerPort}); err != nil {
+		return fmt.Errorf("failed to send DHCPRELEASE: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+//go:build linux
+
+package dhcp
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+const (
+	testClientIface = "nctl-dhcp0"
+	testServerIface = "nctl-dhcp1"
+	// 避开系统上可能运行的 DHCP 服务
+	testServerPort = 10067
+	testClientPort = 10068
+)
+
+var (
+	testServerIP = net.IPv4(10, 99, 0, 1).To4()
+	testLeaseIP  = net.IPv4(10, 99, 0, 2).To4()
+)
+
+// 创建 veth 对，服务器一端放在单独的网络命名空间中，返回服务器端的 socket
+func setupVeth(t *testing.T) net.PacketConn {
+	t.Helper()
+	if os.Geteuid() != 0 {
+		t.Skip("creating a veth pair requires root")
+	}
+	veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: testClientIface}, PeerName: testServerIface}
+	if err := netlink.LinkAdd(veth); err != nil {
+		t.Skipf("veth is not available: %v", err)
+	}
+	t.Cleanup(func() { netlink.LinkDel(veth) })
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	orig, err := netns.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer orig.Close()
+	// netns.New 会把当前线程切换到新的命名空间
+	ns, err := netns.New()
+	if err != nil {
+		t.Skipf("network namespaces are not available: %v", err)
+	}
+	defer netns.Set(orig)
+	t.Cleanup(func() { ns.Close() })
+	if err := netns.Set(orig); err != nil {
+		t.Fatal(err)
+	}
+
+	peer, err := netlink.LinkByName(testServerIface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := netlink.LinkSetNsFd(peer, int(ns)); err != nil {
+		t.Fatal(err)
+	}
+	h, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if peer, err = h.LinkByName(testServerIface); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.AddrAdd(peer, &netlink.Addr{IPNet: &net.IPNet{IP: testServerIP, Mask: net.CIDRMask(24, 32)}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.LinkSetUp(peer); err != nil {
+		t.Fatal(err)
+	}
+	if err := netlink.LinkSetUp(veth); err != nil {
+		t.Fatal(err)
+	}
+
+	// socket 属于创建它时所在的命名空间
+	if err := netns.Set(ns); err != nil {
+		t.Fatal(err)
+	}
+	lc := net.ListenConfig{Control: controlFunc(testServerIface)}
+	conn, err := lc.ListenPacket(context.Background(), "udp4", fmt.Sprintf("0.0.0.0:%d", testServerPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// 只处理一个客户端的最小 DHCP 服务器，收到 RELEASE 时把报文发送到 released
+func serve(conn net.PacketConn, released chan<- *message) {
+	buf := make([]byte, 1500)
+	leaseTime := uint32(600)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		req, err := unmarshal(buf[:n])
+		if err != nil || req.Op != opRequest {
+			continue
+		}
+
+		var typ byte
+		switch req.msgType() {
+		case msgDiscover:
+			typ = msgOffer
+		case msgRequest:
+			typ = msgAck
+			// 续约时的 ACK 给出更长的租期，便于区分
+			if req.CIAddr.Equal(testLeaseIP) {
+				leaseTime = 1200
+			}
+		case msgRelease:
+			released <- req
+			continue
+		default:
+			continue
+		}
+
+		resp := newMessage(req.XID, req.CHAddr, typ)
+		resp.Op = opReply
+		resp.YIAddr = testLeaseIP
+		resp.Options[optServerID] = testServerIP
+		resp.Options[optSubnetMask] = net.CIDRMask(24, 32)
+		resp.Options[optRouter] = testServerIP
+		resp.Options[optDNS] = append(net.IPv4(10, 99, 0, 53).To4(), net.IPv4(10, 99, 0, 54).To4()...)
+		resp.Options[optLeaseTime] = binary.BigEndian.AppendUint32(nil, leaseTime)
+
+		// 客户端已有地址时单播回复，否则广播
+		dst := &net.UDPAddr{IP: net.IPv4bcast, Port: testClientPort}
+		if !req.CIAddr.IsUnspecified() {
+			dst.IP = req.CIAddr
+		}
+		conn.WriteTo(resp.marshal(), dst)
+	}
+}
+
+func TestClientLeaseLifecycle(t *testing.T) {
+	conn := setupVeth(t)
+	released := make(chan *message, 1)
+	go serve(conn, released)
+
+	c, err := NewClient(testClientIface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.ServerPort, c.ClientPort = testServerPort, testClientPort
+	c.Timeout, c.Retries = time.Second, 2
+
+	l, err := c.Obtain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !l.IP.Equal(testLeaseIP) || l.Mask.String() != net.CIDRMask(24, 32).String() || !l.Server.Equal(testServerIP) {
+		t.Fatalf("lease = %+v, want %s/24 from %s", l, testLeaseIP, testServerIP)
+	}
+	if len(l.Routers) != 1 || !l.Routers[0].Equal(testServerIP) || len(l.DNS) != 2 {
+		t.Fatalf("lease routers %v dns %v", l.Routers, l.DNS)
+	}
+	if l.LeaseTime != 600*time.Second || l.T1 != 300*time.Second || l.T2 != 525*time.Second {
+		t.Fatalf("lease times %v/%v/%v, want 600s with RFC 2131 T1/T2", l.LeaseTime, l.T1, l.T2)
+	}
+
+	// 续约单播给服务器，需要先在接口上配置租约中的地址
+	link, err := netlink.LinkByName(testClientIface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := netlink.AddrAdd(link, &netlink.Addr{IPNet: l.IPNet()}); err != nil {
+		t.Fatal(err)
+	}
+	renewed, err := c.Renew(l, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !renewed.SameConfig(l) || renewed.LeaseTime != 1200*time.Second {
+		t.Fatalf("renewed lease = %+v, want the same config with a 1200s lease", renewed)
+	}
+
+	if err := c.Release(renewed); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case m := <-released:
+		if !m.CIAddr.Equal(testLeaseIP) || !m.ip(optServerID).Equal(testServerIP) {
+			t.Fatalf("release ciaddr %s server %s", m.CIAddr, m.ip(optServerID))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not receive DHCPRELEASE")
+	}
+}
@@ -0,0 +1,192 @@
+package dhcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// 报文类型（option 53）
+const (
+	msgDiscover = 1
+	msgOffer    = 2
+	msgRequest  = 3
+	msgDecline  = 4
+	msgAck      = 5
+	msgNak      = 6
+	msgRelease  = 7
+)
+
+// 使用到的 option 编号，见 RFC 2132
+const (
+	optPad          = 0
+	optSubnetMask   = 1
+	optRouter       = 3
+	optDNS          = 6
+	optHostname     = 12
+	optRequestedIP  = 50
+	optLeaseTime    = 51
+	optMessageType  = 53
+	optServerID     = 54
+	optParamRequest = 55
+	optRenewalTime  = 58
+	optRebindTime   = 59
+	optClientID     = 61
+	optEnd          = 255
+)
+
+const (
+	opRequest = 1
+	opReply   = 2
+
+	// 固定头部 236 字节加 4 字节 magic cookie
+	headerLen     = 236
+	flagBroadcast = 0x8000
+)
+
+var magicCookie = []byte{99, 130, 83, 99}
+
+// DHCPv4 报文
+type message struct {
+	Op      byte
+	XID     uint32
+	Secs    uint16
+	Flags   uint16
+	CIAddr  net.IP
+	YIAddr  net.IP
+	SIAddr  net.IP
+	GIAddr  net.IP
+	CHAddr  net.HardwareAddr
+	Options map[byte][]byte
+}
+
+func newMessage(xid uint32, mac net.HardwareAddr, msgType byte) *message {
+	return &message{
+		Op:      opRequest,
+		XID:     xid,
+		CHAddr:  mac,
+		Options: map[byte][]byte{optMessageType: {msgType}},
+	}
+}
+
+// 报文类型，不存在时返回 0
+func (m *message) msgType() byte {
+	if v := m.Options[optMessageType]; len(v) == 1 {
+		return v[0]
+	}
+	return 0
+}
+
+func (m *message) ip(opt byte) net.IP {
+	if v := m.Options[opt]; len(v) == net.IPv4len {
+		return net.IP(v)
+	}
+	return nil
+}
+
+func (m *message) ips(opt byte) []net.IP {
+	v := m.Options[opt]
+	var ips []net.IP
+	for i := 0; i+net.IPv4len <= len(v); i += net.IPv4len {
+		ips = append(ips, net.IP(v[i:i+net.IPv4len]))
+	}
+	return ips
+}
+
+func (m *message) duration(opt byte) time.Duration {
+	if v := m.Options[opt]; len(v) == 4 {
+		return time.Duration(binary.BigEndian.Uint32(v)) * time.Second
+	}
+	return 0
+}
+
+func putIP(b []byte, ip net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		copy(b, ip4)
+	}
+}
+
+// 序列化报文
+func (m *message) marshal() []byte {
+	b := make([]byte, headerLen, 548)
+	b[0] = m.Op
+	b[1] = 1 // ethernet
+	b[2] = byte(len(m.CHAddr))
+	binary.BigEndian.PutUint32(b[4:8], m.XID)
+	binary.BigEndian.PutUint16(b[8:10], m.Secs)
+	binary.BigEndian.PutUint16(b[10:12], m.Flags)
+	putIP(b[12:16], m.CIAddr)
+	putIP(b[16:20], m.YIAddr)
+	putIP(b[20:24], m.SIAddr)
+	putIP(b[24:28], m.GIAddr)
+	copy(b[28:44], m.CHAddr)
+
+	b = append(b, magicCookie...)
+	// option 53 必须放在最前面
+	b = append(b, optMessageType, 1, m.msgType())
+	for code, v := range m.Options {
+		if code == optMessageType {
+			continue
+		}
+		b = append(b, code, byte(len(v)))
+		b = append(b, v...)
+	}
+	b = append(b, optEnd)
+
+	// 部分服务器要求报文不小于 BOOTP 的 300 字节
+	for len(b) < 300 {
+		b = append(b, optPad)
+	}
+	return b
+}
+
+// 解析报文
+func unmarshal(b []byte) (*message, error) {
+	if len(b) < headerLen+len(magicCookie) {
+		return nil, fmt.Errorf("message too short (%d bytes)", len(b))
+	}
+	if string(b[headerLen:headerLen+4]) != string(magicCookie) {
+		return nil, fmt.Errorf("invalid magic cookie")
+	}
+
+	hlen := int(b[2])
+	if hlen > 16 {
+		hlen = 16
+	}
+	m := &message{
+		Op:      b[0],
+		XID:     binary.BigEndian.Uint32(b[4:8]),
+		Secs:    binary.BigEndian.Uint16(b[8:10]),
+		Flags:   binary.BigEndian.Uint16(b[10:12]),
+		CIAddr:  net.IP(append([]byte(nil), b[12:16]...)),
+		YIAddr:  net.IP(append([]byte(nil), b[16:20]...)),
+		SIAddr:  net.IP(append([]byte(nil), b[20:24]...)),
+		GIAddr:  net.IP(append([]byte(nil), b[24:28]...)),
+		CHAddr:  net.HardwareAddr(append([]byte(nil), b[28:28+hlen]...)),
+		Options: map[byte][]byte{},
+	}
+
+	opts := b[headerLen+4:]
+	for i := 0; i < len(opts); {
+		code := opts[i]
+		if code == optEnd {
+			break
+		}
+		if code == optPad {
+			i++
+			continue
+		}
+		if i+1 >= len(opts) {
+			return nil, fmt.Errorf("truncated option %d", code)
+		}
+		l := int(opts[i+1])
+		if i+2+l > len(opts) {
+			return nil, fmt.Errorf("truncated option %d", code)
+		}
+		// 同一 option 重复出现时按 RFC 3396 拼接
+		m.Options[code] = append(m.Options[code], opts[i+2:i+2+l]...)
+		i += 2 + l
+	}
+	return m, nil
+}
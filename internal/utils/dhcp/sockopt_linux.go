@@ -0,0 +1,29 @@
+//go:build linux
+
+package dhcp
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// 允许广播并将 socket 绑定到指定接口，使接口在没有地址时也能收发
+func controlFunc(ifaceName string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			if sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_BROADCAST, 1); sockErr != nil {
+				return
+			}
+			if sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1); sockErr != nil {
+				return
+			}
+			sockErr = unix.SetsockoptString(int(fd), unix.SOL_SOCKET, unix.SO_BINDTODEVICE, ifaceName)
+		})
+		if err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
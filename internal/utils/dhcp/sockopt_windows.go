@@ -0,0 +1,23 @@
+//go:build windows
+
+package dhcp
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// windows 不支持按接口绑定，只允许广播
+func controlFunc(ifaceName string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			sockErr = windows.SetsockoptInt(windows.Handle(fd), windows.SOL_SOCKET, windows.SO_BROADCAST, 1)
+		})
+		if err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
@@ -0,0 +1,284 @@
+// 测试用的 interfaces.Ifaces 和 interfaces.Routes 实现，只在测试中使用
+package fake
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"nctl/interfaces"
+	"nctl/internal/utils/dhcp"
+)
+
+// 在内存中保存一个接口的状态：读取操作返回当前状态，改动会按顺序记录调用并更新状态
+type Host struct {
+	Details interfaces.LinkDetails
+	IPs     []*net.IPNet
+	// IPs 中动态获取的地址
+	Dynamic  []*net.IPNet
+	Gateways []interfaces.Gateway
+	DNS      []net.IP
+	Routes   []interfaces.Route
+	Rules    []interfaces.Rule
+
+	// 改动操作的调用记录，读取操作不记录
+	Calls []string
+	// 以该前缀开头的调用返回错误，状态保持不变
+	Fail string
+}
+
+// 记录一次改动调用，命中 Fail 时返回错误
+func (h *Host) record(format string, a ...any) error {
+	call := fmt.Sprintf(format, a...)
+	h.Calls = append(h.Calls, call)
+	if h.Fail != "" && strings.HasPrefix(call, h.Fail) {
+		return errors.New("injected failure")
+	}
+	return nil
+}
+
+func sameFamily(a, b net.IP) bool {
+	return (a.To4() == nil) == (b.To4() == nil)
+}
+
+func (h *Host) IsExistingIface(iface string) error { return nil }
+
+func (h *Host) AddIP(iface string, ipnet *net.IPNet) error {
+	if err := h.record("AddIP %s %s", iface, ipnet); err != nil {
+		return err
+	}
+	h.IPs = append(h.IPs, ipnet)
+	return nil
+}
+
+func (h *Host) DelIP(iface string, ipnet *net.IPNet) error {
+	if err := h.record("DelIP %s %s", iface, ipnet); err != nil {
+		return err
+	}
+	var kept []*net.IPNet
+	for _, ip := range h.IPs {
+		if ip.String() != ipnet.String() {
+			kept = append(kept, ip)
+		}
+	}
+	h.IPs = kept
+	return nil
+}
+
+func (h *Host) SetIPs(iface string, ipnets []*net.IPNet) error {
+	if err := h.record("SetIPs %s %v", iface, ipnets); err != nil {
+		return err
+	}
+	h.IPs = ipnets
+	return nil
+}
+
+func (h *Host) AddDNS(iface string, ip net.IP) error {
+	if err := h.record("AddDNS %s %s", iface, ip); err != nil {
+		return err
+	}
+	h.DNS = append(h.DNS, ip)
+	return nil
+}
+
+func (h *Host) DelDNS(iface string, ip net.IP) error {
+	if err := h.record("DelDNS %s %s", iface, ip); err != nil {
+		return err
+	}
+	var kept []net.IP
+	for _, d := range h.DNS {
+		if !d.Equal(ip) {
+			kept = append(kept, d)
+		}
+	}
+	h.DNS = kept
+	return nil
+}
+
+func (h *Host) SetDNSs(iface string, ips []net.IP) error {
+	if err := h.record("SetDNSs %s %v", iface, ips); err != nil {
+		return err
+	}
+	h.DNS = ips
+	return nil
+}
+
+// 与真实实现一样替换同一地址族的默认网关
+func (h *Host) SetGateway(iface string, gw net.IP, metric int) error {
+	if err := h.record("SetGateway %s %s %d", iface, gw, metric); err != nil {
+		return err
+	}
+	var kept []interfaces.Gateway
+	for _, g := range h.Gateways {
+		if !sameFamily(g.IP, gw) {
+			kept = append(kept, g)
+		}
+	}
+	h.Gateways = append(kept, interfaces.Gateway{IP: gw, Metric: metric})
+	return nil
+}
+
+func (h *Host) AddGateway(iface string, gw net.IP, metric int) error {
+	if err := h.record("AddGateway %s %s %d", iface, gw, metric); err != nil {
+		return err
+	}
+	h.Gateways = append(h.Gateways, interfaces.Gateway{IP: gw, Metric: metric})
+	return nil
+}
+
+func (h *Host) DelGateway(iface string, gw net.IP) error {
+	if err := h.record("DelGateway %s %s", iface, gw); err != nil {
+		return err
+	}
+	var kept []interfaces.Gateway
+	for _, g := range h.Gateways {
+		if !g.IP.Equal(gw) {
+			kept = append(kept, g)
+		}
+	}
+	h.Gateways = kept
+	return nil
+}
+
+func (h *Host) GetLinkDetails(iface string) (*interfaces.LinkDetails, error) {
+	d := h.Details
+	return &d, nil
+}
+
+func (h *Host) GetIPs(iface string) ([]*net.IPNet, error)              { return h.IPs, nil }
+func (h *Host) GetDynamicIPs(iface string) ([]*net.IPNet, error)       { return h.Dynamic, nil }
+func (h *Host) GetDNSs(iface string) ([]net.IP, error)                 { return h.DNS, nil }
+func (h *Host) GetGateways(iface string) ([]interfaces.Gateway, error) { return h.Gateways, nil }
+
+func (h *Host) FlushIPs(iface string) error {
+	if err := h.record("FlushIPs %s", iface); err != nil {
+		return err
+	}
+	h.IPs = nil
+	return nil
+}
+
+// 与真实实现一样同时删除默认路由
+func (h *Host) FlushRoutes(iface string) error {
+	if err := h.record("FlushRoutes %s", iface); err != nil {
+		return err
+	}
+	h.Routes, h.Gateways = nil, nil
+	return nil
+}
+
+func (h *Host) FlushDNS(iface string) error {
+	if err := h.record("FlushDNS %s", iface); err != nil {
+		return err
+	}
+	h.DNS = nil
+	return nil
+}
+
+func (h *Host) ObtainDHCP(iface string) (*dhcp.Lease, error) {
+	return nil, h.record("ObtainDHCP %s", iface)
+}
+
+func (h *Host) SetLinkState(iface string, up bool) error {
+	if err := h.record("SetLinkState %s %t", iface, up); err != nil {
+		return err
+	}
+	h.Details.Up = up
+	return nil
+}
+
+func (h *Host) SetMAC(iface string, mac net.HardwareAddr) error {
+	if err := h.record("SetMAC %s %s", iface, mac); err != nil {
+		return err
+	}
+	h.Details.HardwareAddr = mac
+	return nil
+}
+
+func (h *Host) SetMTU(iface string, mtu int) error {
+	if err := h.record("SetMTU %s %d", iface, mtu); err != nil {
+		return err
+	}
+	h.Details.MTU = mtu
+	return nil
+}
+
+func (h *Host) SetPromisc(iface string, enable bool) error {
+	return h.record("SetPromisc %s %t", iface, enable)
+}
+func (h *Host) SetARP(iface string, enable bool) error {
+	return h.record("SetARP %s %t", iface, enable)
+}
+func (h *Host) SetAlias(iface string, alias string) error {
+	return h.record("SetAlias %s %s", iface, alias)
+}
+func (h *Host) SetIPv6(iface string, enable bool) error {
+	return h.record("SetIPv6 %s %t", iface, enable)
+}
+func (h *Host) SetLinkMode(iface string, speed int, duplex string) error {
+	return h.record("SetLinkMode %s %d %s", iface, speed, duplex)
+}
+func (h *Host) AddVLAN(iface string, id int) error { return h.record("AddVLAN %s %d", iface, id) }
+func (h *Host) SetTTL(iface string, ttl int) error { return h.record("SetTTL %s %d", iface, ttl) }
+
+func (h *Host) ListRoutes(filter interfaces.RouteFilter) ([]interfaces.Route, error) {
+	return h.Routes, nil
+}
+
+func (h *Host) AddRoute(r *interfaces.Route) error {
+	if err := h.record("AddRoute %s via %s", r.Dst, r.Gateway); err != nil {
+		return err
+	}
+	h.Routes = append(h.Routes, *r)
+	return nil
+}
+
+func (h *Host) DelRoute(r *interfaces.Route) error {
+	if err := h.record("DelRoute %s via %s", r.Dst, r.Gateway); err != nil {
+		return err
+	}
+	var kept []interfaces.Route
+	for _, have := range h.Routes {
+		if have.Dst.String() != r.Dst.String() || !have.Gateway.Equal(r.Gateway) {
+			kept = append(kept, have)
+		}
+	}
+	h.Routes = kept
+	return nil
+}
+
+func (h *Host) ReplaceRoute(r *interfaces.Route) error {
+	if err := h.record("ReplaceRoute %s via %s", r.Dst, r.Gateway); err != nil {
+		return err
+	}
+	for i, have := range h.Routes {
+		if have.Dst.String() == r.Dst.String() {
+			h.Routes[i] = *r
+			return nil
+		}
+	}
+	h.Routes = append(h.Routes, *r)
+	return nil
+}
+
+func (h *Host) LookupRoute(q interfaces.RouteQuery) (*interfaces.RouteLookup, error) {
+	return nil, nil
+}
+
+func (h *Host) ListRules(family int) ([]interfaces.Rule, error) { return h.Rules, nil }
+
+func (h *Host) AddRule(r *interfaces.Rule) error {
+	if err := h.record("AddRule %d", r.Priority); err != nil {
+		return err
+	}
+	h.Rules = append(h.Rules, *r)
+	return nil
+}
+
+func (h *Host) DelRule(r *interfaces.Rule) error { return h.record("DelRule %d", r.Priority) }
+
+var (
+	_ interfaces.Ifaces = (*Host)(nil)
+	_ interfaces.Routes = (*Host)(nil)
+)
@@ -13,3 +13,28 @@ import (
 func IfaceUtils() interfaces.Ifaces {
 	return linux.Iface()
 }
+
+// 返回关于路由操作的工厂函数
+func RouteUtils() interfaces.Routes {
+	return linux.Route()
+}
+
+// 返回关于邻居表操作的工厂函数
+func NeighUtils() interfaces.Neighs {
+	return linux.Neigh()
+}
+
+// 返回关于套接字查询的工厂函数
+func SocketUtils() interfaces.Sockets {
+	return linux.Socket()
+}
+
+// 返回关于代理设置的工厂函数
+func ProxyUtils() interfaces.Proxies {
+	return linux.Proxy()
+}
+
+// 返回关于 WireGuard 接口操作的工厂函数
+func WireGuardUtils() interfaces.WireGuards {
+	return linux.WireGuard()
+}
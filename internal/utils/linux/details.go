@@ -0,0 +1,83 @@
+//go:build linux
+
+package linux
+
+import (
+	"fmt"
+	"nctl/interfaces"
+	"net"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// 通过 ethtool 获取驱动和固件信息
+func ethtoolDrvinfo(iface string) (*unix.EthtoolDrvinfo, error) {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ioctl socket: %w", err)
+	}
+	defer unix.Close(fd)
+
+	return unix.IoctlGetEthtoolDrvinfo(fd, iface)
+}
+
+// 获取接口的链路层详情
+func (u *UnixNctl) GetLinkDetails(iface string) (*interfaces.LinkDetails, error) {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interface '%s': %w", iface, err)
+	}
+	attrs := link.Attrs()
+
+	d := &interfaces.LinkDetails{
+		Up:           attrs.Flags&net.FlagUp != 0,
+		MTU:          attrs.MTU,
+		HardwareAddr: attrs.HardwareAddr,
+		OperState:    attrs.OperState.String(),
+		Carrier:      attrs.RawFlags&unix.IFF_LOWER_UP != 0,
+		Type:         link.Type(),
+		TxQueueLen:   attrs.TxQLen,
+	}
+
+	if attrs.MasterIndex > 0 {
+		if master, err := netlink.LinkByIndex(attrs.MasterIndex); err == nil {
+			d.Master = master.Attrs().Name
+		}
+	}
+
+	if s := attrs.Statistics; s != nil {
+		d.Stats = interfaces.LinkStats{
+			RxBytes:   s.RxBytes,
+			RxPackets: s.RxPackets,
+			RxErrors:  s.RxErrors,
+			RxDropped: s.RxDropped,
+			TxBytes:   s.TxBytes,
+			TxPackets: s.TxPackets,
+			TxErrors:  s.TxErrors,
+			TxDropped: s.TxDropped,
+		}
+	}
+
+	// 虚拟设备通常不支持 ethtool，忽略错误
+	if info, err := ethtoolDrvinfo(iface); err == nil {
+		d.Driver = unix.ByteSliceToString(info.Driver[:])
+		d.DriverVersion = unix.ByteSliceToString(info.Version[:])
+		d.Firmware = unix.ByteSliceToString(info.Fw_version[:])
+	}
+
+	if ecmd, err := ethtoolGet(iface); err == nil {
+		// 链路未连接时内核返回 SPEED_UNKNOWN(-1)
+		if speed := ecmd.speed(); speed != 0 && speed != 0xffff && speed != 0xffffffff {
+			d.Speed = int(speed)
+		}
+		switch ecmd.Duplex {
+		case duplexHalf:
+			d.Duplex = "half"
+		case duplexFull:
+			d.Duplex = "full"
+		}
+	}
+
+	return d, nil
+}
@@ -0,0 +1,112 @@
+//go:build linux
+
+package linux
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// include/uapi/linux/ethtool.h 中的常量
+const (
+	duplexHalf     = 0x00
+	duplexFull     = 0x01
+	duplexUnknown  = 0xff
+	autonegDisable = 0x00
+)
+
+// 对应内核中的 struct ethtool_cmd（ETHTOOL_GSET / ETHTOOL_SSET）
+type ethtoolCmd struct {
+	Cmd           uint32
+	Supported     uint32
+	Advertising   uint32
+	Speed         uint16
+	Duplex        uint8
+	Port          uint8
+	PhyAddress    uint8
+	Transceiver   uint8
+	Autoneg       uint8
+	MdioSupport   uint8
+	Maxtxpkt      uint32
+	Maxrxpkt      uint32
+	SpeedHi       uint16
+	EthTpMdix     uint8
+	EthTpMdixCtrl uint8
+	LpAdvertising uint32
+	Reserved      [2]uint32
+}
+
+// 携带数据指针的 struct ifreq
+type ifreqData struct {
+	Name [unix.IFNAMSIZ]byte
+	Data uintptr
+	_    [16]byte
+}
+
+// 执行 SIOCETHTOOL ioctl
+func ethtoolIoctl(iface string, data unsafe.Pointer) error {
+	if len(iface) >= unix.IFNAMSIZ {
+		return fmt.Errorf("interface name '%s' is too long", iface)
+	}
+
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open ioctl socket: %w", err)
+	}
+	defer unix.Close(fd)
+
+	var ifr ifreqData
+	copy(ifr.Name[:], iface)
+	ifr.Data = uintptr(data)
+
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.SIOCETHTOOL, uintptr(unsafe.Pointer(&ifr)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// 读取接口的链路设置
+func ethtoolGet(iface string) (*ethtoolCmd, error) {
+	ecmd := &ethtoolCmd{Cmd: unix.ETHTOOL_GSET}
+	if err := ethtoolIoctl(iface, unsafe.Pointer(ecmd)); err != nil {
+		return nil, fmt.Errorf("ETHTOOL_GSET failed on '%s': %w", iface, err)
+	}
+	return ecmd, nil
+}
+
+// 速率（Mb/s）
+func (e *ethtoolCmd) speed() uint32 {
+	return uint32(e.SpeedHi)<<16 | uint32(e.Speed)
+}
+
+// 设置接口速率和双工模式，同时关闭自协商
+func ethtoolSetLinkMode(iface string, speed int, duplex string) error {
+	ecmd, err := ethtoolGet(iface)
+	if err != nil {
+		return err
+	}
+
+	if speed > 0 {
+		ecmd.Speed = uint16(speed & 0xffff)
+		ecmd.SpeedHi = uint16(speed >> 16)
+	}
+	switch duplex {
+	case "":
+	case "half":
+		ecmd.Duplex = duplexHalf
+	case "full":
+		ecmd.Duplex = duplexFull
+	default:
+		return fmt.Errorf("invalid duplex mode '%s' (value: half, full)", duplex)
+	}
+	ecmd.Autoneg = autonegDisable
+	ecmd.Cmd = unix.ETHTOOL_SSET
+
+	if err := ethtoolIoctl(iface, unsafe.Pointer(ecmd)); err != nil {
+		return fmt.Errorf("ETHTOOL_SSET failed on '%s': %w", iface, err)
+	}
+	return nil
+}
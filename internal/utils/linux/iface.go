@@ -3,12 +3,11 @@
 package linux
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"nctl/interfaces"
 	"net"
 	"os"
-	"strings"
 	"syscall"
 
 	"github.com/godbus/dbus"
@@ -56,41 +55,101 @@ func (u *UnixNctl) DelIP(iface string, ipnet *net.IPNet) error {
 	return netlink.AddrDel(link, addr)
 }
 
-// 设置默认网关
-func (u *UnixNctl) SetGateway(iface string, gateway net.IP) error {
+// 设置默认网关，只替换该接口上同一地址族的默认路由，其他接口和地址族的默认路由保持不变
+func (u *UnixNctl) SetGateway(iface string, gateway net.IP, metric int) error {
 	link, err := netlink.LinkByName(iface)
 	if err != nil {
 		return fmt.Errorf("failed to get interface '%s': %w", iface, err)
 	}
 
-	routes, err := netlink.RouteList(nil, netlink.FAMILY_ALL)
+	family, dst := netlink.FAMILY_V4, zeroIPNet(4)
+	if gateway.To4() == nil {
+		family, dst = netlink.FAMILY_V6, zeroIPNet(6)
+	}
+	old, err := mainDefaultRoutes(link, family)
 	if err != nil {
-		return fmt.Errorf("failed to list routes: %w", err)
+		return err
 	}
-	for _, r := range routes {
-		if r.Gw != nil && r.Dst == nil {
-			if err := netlink.RouteDel(&r); err != nil {
-				if !strings.Contains(err.Error(), "no such process") {
-					fmt.Fprintf(os.Stderr, "Warning: failed to delete old default gateway: %v\n", err)
-				}
-			}
+	for _, r := range old {
+		if err := netlink.RouteDel(&r); err != nil {
+			return fmt.Errorf("failed to delete old default gateway %s: %w", r.Gw, err)
 		}
 	}
 
 	newRoute := &netlink.Route{
 		LinkIndex: link.Attrs().Index,
 		Gw:        gateway,
-		Dst:       nil,
+		Dst:       dst,
+		Priority:  metric,
 		Scope:     netlink.SCOPE_UNIVERSE,
 		Protocol:  syscall.RTPROT_STATIC,
 	}
 	if err := netlink.RouteAdd(newRoute); err != nil {
+		// 恢复被删除的默认路由
+		for _, r := range old {
+			netlink.RouteAdd(&r)
+		}
+		if errors.Is(err, syscall.EEXIST) {
+			return fmt.Errorf("failed to set gateway: a default route with metric %d already exists on another interface, use a different metric", metric)
+		}
 		return fmt.Errorf("failed to set gateway: %w", err)
 	}
 
 	return nil
 }
 
+// 添加一条默认路由，接口上已有的默认网关保持不变
+func (u *UnixNctl) AddGateway(iface string, gateway net.IP, metric int) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("failed to get interface '%s': %w", iface, err)
+	}
+
+	dst := zeroIPNet(4)
+	if gateway.To4() == nil {
+		dst = zeroIPNet(6)
+	}
+	route := &netlink.Route{
+		LinkIndex: link.Attrs().Index,
+		Gw:        gateway,
+		Dst:       dst,
+		Priority:  metric,
+		Scope:     netlink.SCOPE_UNIVERSE,
+		Protocol:  syscall.RTPROT_STATIC,
+	}
+	if err := netlink.RouteAdd(route); err != nil {
+		return fmt.Errorf("failed to add gateway %s: %w", gateway, err)
+	}
+	return nil
+}
+
+// 删除接口上经由该网关的默认路由
+func (u *UnixNctl) DelGateway(iface string, gateway net.IP) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("failed to get interface '%s': %w", iface, err)
+	}
+
+	routes, err := mainDefaultRoutes(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, r := range routes {
+		if !r.Gw.Equal(gateway) {
+			continue
+		}
+		if err := netlink.RouteDel(&r); err != nil {
+			return fmt.Errorf("failed to delete default gateway %s: %w", gateway, err)
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("gateway %s is not configured on %s", gateway, iface)
+	}
+	return nil
+}
+
 // 增加 dns
 func (u *UnixNctl) AddDNS(iface string, dnsIP net.IP) error {
 	conn, err := dbus.SystemBus()
@@ -104,35 +163,21 @@ func (u *UnixNctl) AddDNS(iface string, dnsIP net.IP) error {
 		return fmt.Errorf("failed to get interface '%s': %w", iface, err)
 	}
 
-	obj := conn.Object("org.freedesktop.resolve1", "/org/freedesktop/resolve1")
-	var currentDNS [][]byte
-	err = obj.Call("org.freedesktop.resolve1.Manager.GetLinkDNS", 0, link.Attrs().Index).Store(&currentDNS)
+	currentDNS, err := getLinkDNS(conn, link.Attrs().Index)
 	if err != nil {
 		return fmt.Errorf("failed to get current DNS servers: %w", err)
 	}
 
-	isExist := false
-	for _, ipBytes := range currentDNS {
-		if bytes.Equal(ipBytes, dnsIP) {
-			isExist = true
-			break
+	for _, ip := range currentDNS {
+		if ip.Equal(dnsIP) {
+			fmt.Fprintf(os.Stdout, "DNS server '%s' is already set for interface '%s'\n", dnsIP.String(), iface)
+			return nil
 		}
 	}
 
-	if isExist {
-		fmt.Fprintf(os.Stdout, "DNS server '%s' is already set for interface '%s'\n", dnsIP.String(), iface)
-		return nil
-	}
-
-	newDNSes := make([][]byte, 0, len(currentDNS)+1)
-	for _, ipBytes := range currentDNS {
-		newDNSes = append(newDNSes, ipBytes)
-	}
-	newDNSes = append(newDNSes, dnsIP)
-
-	call := obj.Call("org.freedesktop.resolve1.Manager.SetLinkDNS", 0, link.Attrs().Index, newDNSes)
-	if call.Err != nil {
-		return fmt.Errorf("failed to set DNS server '%s': %w", dnsIP.String(), call.Err)
+	newDNSes := append(currentDNS, dnsIP)
+	if err := setLinkDNS(conn, link.Attrs().Index, newDNSes); err != nil {
+		return fmt.Errorf("failed to set DNS server '%s': %w", dnsIP.String(), err)
 	}
 
 	fmt.Fprintf(os.Stderr, "Successfully added DNS server '%s' for interface '%s'\n", dnsIP.String(), iface)
@@ -152,18 +197,16 @@ func (u *UnixNctl) DelDNS(iface string, dnsIP net.IP) error {
 		return fmt.Errorf("failed to get interface '%s': %w", iface, err)
 	}
 
-	obj := conn.Object("org.freedesktop.resolve1", "/org/freedesktop/resolve1")
-	var currentDNS [][]byte
-	err = obj.Call("org.freedesktop.resolve1.Manager.GetLinkDNS", 0, link.Attrs().Index).Store(&currentDNS)
+	currentDNS, err := getLinkDNS(conn, link.Attrs().Index)
 	if err != nil {
 		return fmt.Errorf("failed to get current DNS servers: %w", err)
 	}
 
 	found := false
-	newDNSes := make([][]byte, 0, len(currentDNS))
-	for _, ipBytes := range currentDNS {
-		if !bytes.Equal(ipBytes, dnsIP) {
-			newDNSes = append(newDNSes, ipBytes)
+	newDNSes := make([]net.IP, 0, len(currentDNS))
+	for _, ip := range currentDNS {
+		if !ip.Equal(dnsIP) {
+			newDNSes = append(newDNSes, ip)
 		} else {
 			found = true
 		}
@@ -173,9 +216,8 @@ func (u *UnixNctl) DelDNS(iface string, dnsIP net.IP) error {
 		return fmt.Errorf("DNS server '%s' not found for interface '%s'", dnsIP.String(), iface)
 	}
 
-	call := obj.Call("org.freedesktop.resolve1.Manager.SetLinkDNS", 0, link.Attrs().Index, newDNSes)
-	if call.Err != nil {
-		return fmt.Errorf("failed to delete DNS server '%s': %w", dnsIP.String(), call.Err)
+	if err := setLinkDNS(conn, link.Attrs().Index, newDNSes); err != nil {
+		return fmt.Errorf("failed to delete DNS server '%s': %w", dnsIP.String(), err)
 	}
 
 	fmt.Printf("Successfully deleted DNS server '%s' for interface '%s'\n", dnsIP.String(), iface)
@@ -233,21 +275,8 @@ func (u *UnixNctl) SetDNSs(ifaceName string, dnsIPs []net.IP) error {
 		return fmt.Errorf("failed to get interface '%s': %w", ifaceName, err)
 	}
 
-	// 将 net.IP 转换为 D-Bus 需要的 [][]byte 格式
-	var dnsData [][]byte
-	for _, ip := range dnsIPs {
-		// D-Bus 需要的字节顺序可能取决于架构，但通常 To4/To16 即可
-		if ip.To4() != nil {
-			dnsData = append(dnsData, []byte(ip.To4()))
-		} else {
-			dnsData = append(dnsData, []byte(ip.To16()))
-		}
-	}
-
-	obj := conn.Object("org.freedesktop.resolve1", "/org/freedesktop/resolve1")
-	call := obj.Call("org.freedesktop.resolve1.Manager.SetLinkDNS", 0, link.Attrs().Index, dnsData)
-	if call.Err != nil {
-		return fmt.Errorf("failed to set DNS servers via D-Bus: %w", call.Err)
+	if err := setLinkDNS(conn, link.Attrs().Index, dnsIPs); err != nil {
+		return fmt.Errorf("failed to set DNS servers via D-Bus: %w", err)
 	}
 
 	fmt.Printf("Successfully set new DNS servers on '%s'\n", ifaceName)
@@ -0,0 +1,141 @@
+//go:build linux
+
+package linux
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/vishvananda/netlink"
+)
+
+// 写入 /proc/sys 下的内核参数
+func writeSysctl(path string, value string) error {
+	if err := os.WriteFile(filepath.Join("/proc/sys", path), []byte(value), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// 启用或禁用接口
+func (u *UnixNctl) SetLinkState(iface string, up bool) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("failed to find network interface %s: %v", iface, err)
+	}
+	if up {
+		err = netlink.LinkSetUp(link)
+	} else {
+		err = netlink.LinkSetDown(link)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set network interface %s status: %v", iface, err)
+	}
+	return nil
+}
+
+// 设置 mac 地址
+func (u *UnixNctl) SetMAC(iface string, mac net.HardwareAddr) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("failed to get interface '%s': %w", iface, err)
+	}
+	return netlink.LinkSetHardwareAddr(link, mac)
+}
+
+// 设置 mtu
+func (u *UnixNctl) SetMTU(iface string, mtu int) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("failed to get interface '%s': %w", iface, err)
+	}
+	return netlink.LinkSetMTU(link, mtu)
+}
+
+// 开启或关闭混杂模式
+func (u *UnixNctl) SetPromisc(iface string, enable bool) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("failed to get interface '%s': %w", iface, err)
+	}
+	if enable {
+		return netlink.SetPromiscOn(link)
+	}
+	return netlink.SetPromiscOff(link)
+}
+
+// 开启或关闭 arp
+func (u *UnixNctl) SetARP(iface string, enable bool) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("failed to get interface '%s': %w", iface, err)
+	}
+	if enable {
+		return netlink.LinkSetARPOn(link)
+	}
+	return netlink.LinkSetARPOff(link)
+}
+
+// 设置接口别名（ip link set alias）
+func (u *UnixNctl) SetAlias(iface string, alias string) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("failed to get interface '%s': %w", iface, err)
+	}
+	return netlink.LinkSetAlias(link, alias)
+}
+
+// 启用或禁用接口上的 ipv6
+func (u *UnixNctl) SetIPv6(iface string, enable bool) error {
+	if err := u.IsExistingIface(iface); err != nil {
+		return err
+	}
+	value := "1"
+	if enable {
+		value = "0"
+	}
+	return writeSysctl(filepath.Join("net/ipv6/conf", iface, "disable_ipv6"), value)
+}
+
+// 通过 ethtool 设置速率和双工模式
+func (u *UnixNctl) SetLinkMode(iface string, speed int, duplex string) error {
+	if err := u.IsExistingIface(iface); err != nil {
+		return err
+	}
+	return ethtoolSetLinkMode(iface, speed, duplex)
+}
+
+// 创建名为 <iface>.<id> 的 vlan 子接口
+func (u *UnixNctl) AddVLAN(iface string, id int) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("failed to get interface '%s': %w", iface, err)
+	}
+
+	vlan := &netlink.Vlan{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:        fmt.Sprintf("%s.%d", iface, id),
+			ParentIndex: link.Attrs().Index,
+		},
+		VlanId: id,
+	}
+	if err := netlink.LinkAdd(vlan); err != nil {
+		return fmt.Errorf("failed to create VLAN interface '%s': %w", vlan.Name, err)
+	}
+	return nil
+}
+
+// linux 上 ipv4 的默认 ttl 是全局参数，这里只设置接口的 ipv6 hop limit
+func (u *UnixNctl) SetTTL(iface string, ttl int) error {
+	if err := u.IsExistingIface(iface); err != nil {
+		return err
+	}
+	if ttl < 1 || ttl > 255 {
+		return fmt.Errorf("TTL must be between 1 and 255, got %d", ttl)
+	}
+
+	return writeSysctl(filepath.Join("net/ipv6/conf", iface, "hop_limit"), strconv.Itoa(ttl))
+}
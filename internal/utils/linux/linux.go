@@ -12,3 +12,28 @@ type UnixNctl struct{}
 func Iface() interfaces.Ifaces {
 	return &UnixNctl{}
 }
+
+// 路由操作的工厂函数
+func Route() interfaces.Routes {
+	return &UnixNctl{}
+}
+
+// 邻居表操作的工厂函数
+func Neigh() interfaces.Neighs {
+	return &UnixNctl{}
+}
+
+// 套接字查询的工厂函数
+func Socket() interfaces.Sockets {
+	return &UnixNctl{}
+}
+
+// 代理设置的工厂函数
+func Proxy() interfaces.Proxies {
+	return &UnixNctl{}
+}
+
+// WireGuard 接口操作的工厂函数
+func WireGuard() interfaces.WireGuards {
+	return &UnixNctl{}
+}
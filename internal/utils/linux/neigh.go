@@ -0,0 +1,208 @@
+//go:build linux
+
+package linux
+
+import (
+	"fmt"
+	"nctl/interfaces"
+	"net"
+	"strconv"
+
+	"github.com/vishvananda/netlink"
+)
+
+var neighStates = map[int]string{
+	netlink.NUD_INCOMPLETE: "INCOMPLETE",
+	netlink.NUD_REACHABLE:  "REACHABLE",
+	netlink.NUD_STALE:      "STALE",
+	netlink.NUD_DELAY:      "DELAY",
+	netlink.NUD_PROBE:      "PROBE",
+	netlink.NUD_FAILED:     "FAILED",
+	netlink.NUD_NOARP:      "NOARP",
+	netlink.NUD_PERMANENT:  "PERMANENT",
+}
+
+func neighStateName(state int) string {
+	if s, ok := neighStates[state]; ok {
+		return s
+	}
+	if state == netlink.NUD_NONE {
+		return "NONE"
+	}
+	return "0x" + strconv.FormatInt(int64(state), 16)
+}
+
+func parseNeighState(name string) (int, error) {
+	for state, s := range neighStates {
+		if s == name {
+			return state, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown neighbor state '%s'", name)
+}
+
+// 静态邻居不会被 flush 删除，除非明确指定了状态
+func isStaticNeigh(state string) bool {
+	return state == "PERMANENT" || state == "NOARP"
+}
+
+// 列出符合条件的邻居，与 ip neigh show 一致，未指定状态时不显示 NOARP 和 NONE
+func (u *UnixNctl) ListNeighs(filter interfaces.NeighFilter) ([]interfaces.Neigh, error) {
+	family, err := netlinkFamily(filter.Family)
+	if err != nil {
+		return nil, err
+	}
+
+	linkIndex := 0
+	if filter.Iface != "" {
+		link, err := netlink.LinkByName(filter.Iface)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get interface: %w", err)
+		}
+		linkIndex = link.Attrs().Index
+	}
+
+	neighs, err := netlink.NeighList(linkIndex, family)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list neighbors: %w", err)
+	}
+
+	var result []interfaces.Neigh
+	for _, n := range neighs {
+		if n.IP == nil {
+			continue
+		}
+		state := neighStateName(n.State)
+		if filter.State != "" && state != filter.State {
+			continue
+		}
+		if filter.State == "" && (state == "NOARP" || state == "NONE") {
+			continue
+		}
+
+		neigh := interfaces.Neigh{
+			Family: 4,
+			IP:     n.IP,
+			State:  state,
+			Router: n.Flags&netlink.NTF_ROUTER != 0,
+		}
+		if n.IP.To4() == nil {
+			neigh.Family = 6
+		}
+		if len(n.HardwareAddr) > 0 {
+			neigh.MAC = n.HardwareAddr
+		}
+		if ifi, err := net.InterfaceByIndex(n.LinkIndex); err == nil {
+			neigh.Iface = ifi.Name
+		} else {
+			neigh.Iface = strconv.Itoa(n.LinkIndex)
+		}
+		result = append(result, neigh)
+	}
+	return result, nil
+}
+
+// 转换为 netlink 邻居，接口必填
+func toNetlinkNeigh(n *interfaces.Neigh) (*netlink.Neigh, error) {
+	if n.Iface == "" {
+		return nil, fmt.Errorf("interface is required")
+	}
+	link, err := netlink.LinkByName(n.Iface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interface: %w", err)
+	}
+
+	neigh := &netlink.Neigh{
+		LinkIndex:    link.Attrs().Index,
+		Family:       netlink.FAMILY_V4,
+		IP:           n.IP,
+		HardwareAddr: n.MAC,
+	}
+	if n.IP.To4() == nil {
+		neigh.Family = netlink.FAMILY_V6
+	}
+	if n.Router {
+		neigh.Flags = netlink.NTF_ROUTER
+	}
+	if n.State != "" {
+		if neigh.State, err = parseNeighState(n.State); err != nil {
+			return nil, err
+		}
+	}
+	return neigh, nil
+}
+
+// 添加邻居，已存在时覆盖，未指定状态时为 PERMANENT
+func (u *UnixNctl) AddNeigh(n *interfaces.Neigh) error {
+	if n.MAC == nil {
+		return fmt.Errorf("link layer address is required")
+	}
+	neigh, err := toNetlinkNeigh(n)
+	if err != nil {
+		return err
+	}
+	if neigh.State == 0 {
+		neigh.State = netlink.NUD_PERMANENT
+	}
+	if err := netlink.NeighSet(neigh); err != nil {
+		return fmt.Errorf("failed to add neighbor %s: %w", n.IP, err)
+	}
+	return nil
+}
+
+// 删除邻居，未指定接口时删除所有接口上该地址的记录
+func (u *UnixNctl) DelNeigh(n *interfaces.Neigh) error {
+	targets := []interfaces.Neigh{*n}
+	if n.Iface == "" {
+		targets = nil
+		neighs, err := u.ListNeighs(interfaces.NeighFilter{})
+		if err != nil {
+			return err
+		}
+		for _, item := range neighs {
+			if item.IP.Equal(n.IP) {
+				targets = append(targets, item)
+			}
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("neighbor %s not found", n.IP)
+		}
+	}
+
+	for _, t := range targets {
+		t.State = ""
+		neigh, err := toNetlinkNeigh(&t)
+		if err != nil {
+			return err
+		}
+		if err := netlink.NeighDel(neigh); err != nil {
+			return fmt.Errorf("failed to delete neighbor %s on %s: %w", t.IP, t.Iface, err)
+		}
+	}
+	return nil
+}
+
+// 逐条删除符合条件的邻居
+func (u *UnixNctl) FlushNeighs(filter interfaces.NeighFilter) (int, error) {
+	neighs, err := u.ListNeighs(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, n := range neighs {
+		if filter.State == "" && isStaticNeigh(n.State) {
+			continue
+		}
+		n.State = ""
+		neigh, err := toNetlinkNeigh(&n)
+		if err != nil {
+			return count, err
+		}
+		if err := netlink.NeighDel(neigh); err != nil {
+			return count, fmt.Errorf("failed to delete neighbor %s on %s: %w", n.IP, n.Iface, err)
+		}
+		count++
+	}
+	return count, nil
+}
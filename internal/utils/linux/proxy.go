@@ -0,0 +1,326 @@
+//go:build linux
+
+package linux
+
+import (
+	"fmt"
+	"nctl/interfaces"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+const (
+	// 由 pam_env 读取，对所有会话生效
+	environmentFile = "/etc/environment"
+	// 登录 shell 读取，覆盖不经过 pam_env 的场景
+	profileProxyFile = "/etc/profile.d/nctl-proxy.sh"
+
+	// ~/.profile 中由 nctl 管理的一段
+	profileBlockBegin = "# >>> nctl proxy >>>"
+	profileBlockEnd   = "# <<< nctl proxy <<<"
+)
+
+// 写入的变量名，不同工具读取大写或小写，两种都写
+var proxyVars = []string{"http_proxy", "HTTP_PROXY", "https_proxy", "HTTPS_PROXY", "no_proxy", "NO_PROXY"}
+
+func isProxyVar(key string) bool {
+	for _, v := range proxyVars {
+		if v == key {
+			return true
+		}
+	}
+	return false
+}
+
+// 解析 KEY=VALUE 或 export KEY=VALUE，值两边的引号会被去掉
+func parseEnvLine(line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false
+	}
+	line = strings.TrimPrefix(line, "export ")
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", "", false
+	}
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return strings.TrimSpace(key), value, true
+}
+
+// 从多行文本中读取代理变量，小写优先
+func parseProxyVars(lines []string) *interfaces.ProxySettings {
+	values := map[string]string{}
+	for _, line := range lines {
+		if key, value, ok := parseEnvLine(line); ok && isProxyVar(key) {
+			values[key] = value
+		}
+	}
+	pick := func(lower string) string {
+		if v := values[lower]; v != "" {
+			return v
+		}
+		return values[strings.ToUpper(lower)]
+	}
+
+	p := &interfaces.ProxySettings{HTTP: pick("http_proxy"), HTTPS: pick("https_proxy")}
+	for _, host := range strings.Split(pick("no_proxy"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			p.NoProxy = append(p.NoProxy, host)
+		}
+	}
+	return p
+}
+
+// 按 proxyVars 的顺序生成 KEY='VALUE' 行，未设置的变量不写
+func proxyLines(p *interfaces.ProxySettings, prefix string) []string {
+	values := map[string]string{
+		"http_proxy":  p.HTTP,
+		"https_proxy": p.HTTPS,
+		"no_proxy":    strings.Join(p.NoProxy, ","),
+	}
+	var lines []string
+	for _, key := range proxyVars {
+		if v := values[strings.ToLower(key)]; v != "" {
+			lines = append(lines, prefix+key+"="+shellQuote(v))
+		}
+	}
+	return lines
+}
+
+// 用单引号包住值，值中的单引号先结束引号再用反斜杠转义
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// 写入前再检查一次，这些值会被每个登录 shell 执行
+func checkProxySettings(p *interfaces.ProxySettings) error {
+	for _, v := range []string{p.HTTP, p.HTTPS} {
+		if v == "" {
+			continue
+		}
+		if parsed, err := interfaces.ParseProxyURL(v); err != nil {
+			return err
+		} else if parsed != v {
+			return fmt.Errorf("proxy address '%s' is not normalized", v)
+		}
+	}
+	for _, host := range p.NoProxy {
+		if _, err := interfaces.ParseNoProxy(host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimRight(string(data), "\n"), "\n"), nil
+}
+
+// 先写临时文件再改名。符号链接写到它指向的文件，保留原文件的权限和属主
+func writeLines(path string, lines []string, perm os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	uid, gid := -1, -1
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			uid, gid = int(st.Uid), int(st.Gid)
+		}
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	content := strings.Join(lines, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	if uid >= 0 {
+		if err := os.Chown(tmp.Name(), uid, gid); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// 去掉代理变量后的 /etc/environment
+func environmentWithoutProxy() ([]string, error) {
+	lines, err := readLines(environmentFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var kept []string
+	for _, line := range lines {
+		if key, _, ok := parseEnvLine(line); ok && isProxyVar(key) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return kept, nil
+}
+
+func userProfile() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".profile"), nil
+}
+
+// 把 ~/.profile 拆成管理段之前、管理段内和之后三部分
+func splitProfile(lines []string) (before, block, after []string) {
+	begin, end := -1, -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case profileBlockBegin:
+			begin = i
+		case profileBlockEnd:
+			if begin >= 0 {
+				end = i
+			}
+		}
+	}
+	if begin < 0 || end < 0 {
+		return lines, nil, nil
+	}
+	return lines[:begin], lines[begin+1 : end], lines[end+1:]
+}
+
+func (u *UnixNctl) GetProxy(scope string) (*interfaces.ProxySettings, error) {
+	switch scope {
+	case interfaces.ProxyScopeSystem:
+		// /etc/environment 中没有设置时再看 profile.d
+		for _, path := range []string{environmentFile, profileProxyFile} {
+			lines, err := readLines(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if p := parseProxyVars(lines); !p.Empty() {
+				p.Source = path
+				return p, nil
+			}
+		}
+		return &interfaces.ProxySettings{}, nil
+	case interfaces.ProxyScopeUser:
+		path, err := userProfile()
+		if err != nil {
+			return nil, err
+		}
+		lines, err := readLines(path)
+		if os.IsNotExist(err) {
+			return &interfaces.ProxySettings{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		_, block, _ := splitProfile(lines)
+		p := parseProxyVars(block)
+		if !p.Empty() {
+			p.Source = path
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("unknown proxy scope '%s'", scope)
+}
+
+func (u *UnixNctl) SetProxy(scope string, p *interfaces.ProxySettings) error {
+	if err := checkProxySettings(p); err != nil {
+		return err
+	}
+	switch scope {
+	case interfaces.ProxyScopeSystem:
+		lines, err := environmentWithoutProxy()
+		if err != nil {
+			return err
+		}
+		if err := writeLines(environmentFile, append(lines, proxyLines(p, "")...), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", environmentFile, err)
+		}
+		script := append([]string{"# Generated by nctl net proxy, do not edit"}, proxyLines(p, "export ")...)
+		if err := writeLines(profileProxyFile, script, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", profileProxyFile, err)
+		}
+		return nil
+	case interfaces.ProxyScopeUser:
+		path, err := userProfile()
+		if err != nil {
+			return err
+		}
+		lines, err := readLines(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		before, _, after := splitProfile(lines)
+		block := append([]string{profileBlockBegin}, proxyLines(p, "export ")...)
+		block = append(block, profileBlockEnd)
+		lines = append(append(append([]string{}, before...), block...), after...)
+		if err := writeLines(path, lines, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown proxy scope '%s'", scope)
+}
+
+func (u *UnixNctl) UnsetProxy(scope string) error {
+	switch scope {
+	case interfaces.ProxyScopeSystem:
+		lines, err := environmentWithoutProxy()
+		if err != nil {
+			return err
+		}
+		if err := writeLines(environmentFile, lines, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", environmentFile, err)
+		}
+		if err := os.Remove(profileProxyFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	case interfaces.ProxyScopeUser:
+		path, err := userProfile()
+		if err != nil {
+			return err
+		}
+		lines, err := readLines(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		before, block, after := splitProfile(lines)
+		if block == nil {
+			return nil
+		}
+		if err := writeLines(path, append(before, after...), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown proxy scope '%s'", scope)
+}
@@ -0,0 +1,196 @@
+//go:build linux
+
+package linux
+
+import (
+	"fmt"
+	"nctl/interfaces"
+	"nctl/internal/utils/dhcp"
+	"net"
+	"sort"
+	"syscall"
+
+	"github.com/godbus/dbus"
+	"github.com/vishvananda/netlink"
+)
+
+// 获取接口上的 ip，不包含 link-local 地址
+func (u *UnixNctl) GetIPs(iface string) ([]*net.IPNet, error) {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interface '%s': %w", iface, err)
+	}
+
+	addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses for '%s': %w", iface, err)
+	}
+
+	var ipnets []*net.IPNet
+	for _, addr := range addrs {
+		if addr.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		ipnets = append(ipnets, addr.IPNet)
+	}
+	return ipnets, nil
+}
+
+// 获取接口上带有效期的动态地址，没有 IFA_F_PERMANENT 标志的即为 dhcp 或 slaac 获取的地址
+func (u *UnixNctl) GetDynamicIPs(iface string) ([]*net.IPNet, error) {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interface '%s': %w", iface, err)
+	}
+
+	addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses for '%s': %w", iface, err)
+	}
+
+	var ipnets []*net.IPNet
+	for _, addr := range addrs {
+		if addr.IP.IsLinkLocalUnicast() || addr.Flags&syscall.IFA_F_PERMANENT != 0 {
+			continue
+		}
+		ipnets = append(ipnets, addr.IPNet)
+	}
+	return ipnets, nil
+}
+
+// 获取接口上通过 resolve1 配置的 dns
+func (u *UnixNctl) GetDNSs(iface string) ([]net.IP, error) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to D-Bus system bus: %w", err)
+	}
+	defer conn.Close()
+
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interface '%s': %w", iface, err)
+	}
+
+	return getLinkDNS(conn, link.Attrs().Index)
+}
+
+// 获取接口上的默认网关
+func (u *UnixNctl) GetGateways(iface string) ([]interfaces.Gateway, error) {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interface '%s': %w", iface, err)
+	}
+
+	routes, err := mainDefaultRoutes(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return nil, err
+	}
+
+	var gws []interfaces.Gateway
+	for _, r := range routes {
+		gws = append(gws, interfaces.Gateway{IP: r.Gw, Metric: r.Priority})
+	}
+	return gws, nil
+}
+
+// 主路由表中接口上带网关的默认路由，按 metric 从小到大排列
+func mainDefaultRoutes(link netlink.Link, family int) ([]netlink.Route, error) {
+	routes, err := netlink.RouteList(link, family)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes for '%s': %w", link.Attrs().Name, err)
+	}
+
+	var result []netlink.Route
+	for _, r := range routes {
+		if isDefaultRoute(r) && r.Gw != nil {
+			result = append(result, r)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Priority < result[j].Priority })
+	return result, nil
+}
+
+// 判断是否为默认路由，netlink 返回的默认路由 Dst 可能为 nil 或 0.0.0.0/0、::/0
+func isDefaultRoute(r netlink.Route) bool {
+	if r.Dst == nil {
+		return true
+	}
+	ones, _ := r.Dst.Mask.Size()
+	return ones == 0 && r.Dst.IP.IsUnspecified()
+}
+
+// 删除接口上所有非 link-local 地址
+func (u *UnixNctl) FlushIPs(iface string) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("failed to get interface '%s': %w", iface, err)
+	}
+
+	addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return fmt.Errorf("failed to list addresses for '%s': %w", iface, err)
+	}
+
+	var firstErr error
+	for _, addr := range addrs {
+		if addr.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if err := netlink.AddrDel(link, &addr); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to delete address %s: %w", addr.IPNet.String(), err)
+		}
+	}
+	return firstErr
+}
+
+// 删除接口上除内核和路由通告以外的所有路由
+func (u *UnixNctl) FlushRoutes(iface string) error {
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("failed to get interface '%s': %w", iface, err)
+	}
+
+	routes, err := netlink.RouteList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return fmt.Errorf("failed to list routes for '%s': %w", iface, err)
+	}
+
+	var firstErr error
+	for _, r := range routes {
+		if r.Protocol == syscall.RTPROT_KERNEL || r.Protocol == syscall.RTPROT_RA {
+			continue
+		}
+		if err := netlink.RouteDel(&r); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to delete route %s: %w", r.String(), err)
+		}
+	}
+	return firstErr
+}
+
+// 撤销接口上通过 resolve1 设置的 dns
+func (u *UnixNctl) FlushDNS(iface string) error {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to D-Bus system bus: %w", err)
+	}
+	defer conn.Close()
+
+	link, err := netlink.LinkByName(iface)
+	if err != nil {
+		return fmt.Errorf("failed to get interface '%s': %w", iface, err)
+	}
+
+	if err := revertLinkDNS(conn, link.Attrs().Index); err != nil {
+		return fmt.Errorf("failed to revert DNS settings: %w", err)
+	}
+	return nil
+}
+
+// linux 上没有统一的系统 dhcp 客户端，使用内置客户端获取租约
+func (u *UnixNctl) ObtainDHCP(iface string) (*dhcp.Lease, error) {
+	client, err := dhcp.NewClient(iface)
+	if err != nil {
+		return nil, err
+	}
+	return client.Obtain()
+}
@@ -0,0 +1,74 @@
+//go:build linux
+
+package linux
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	resolve1Dest    = "org.freedesktop.resolve1"
+	resolve1Path    = "/org/freedesktop/resolve1"
+	resolve1Manager = "org.freedesktop.resolve1.Manager"
+)
+
+// resolve1 中 a(iay) 类型的 dns 地址
+type linkDNS struct {
+	Family  int32
+	Address []byte
+}
+
+// 通过 resolve1 读取接口上配置的 dns
+func getLinkDNS(conn *dbus.Conn, ifindex int) ([]net.IP, error) {
+	var linkPath dbus.ObjectPath
+	err := conn.Object(resolve1Dest, resolve1Path).Call(resolve1Manager+".GetLink", 0, int32(ifindex)).Store(&linkPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resolve1 link: %w", err)
+	}
+
+	v, err := conn.Object(resolve1Dest, linkPath).GetProperty("org.freedesktop.resolve1.Link.DNS")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link DNS property: %w", err)
+	}
+
+	entries, ok := v.Value().([][]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected link DNS property type %s", v.Signature())
+	}
+
+	var ips []net.IP
+	for _, e := range entries {
+		if len(e) != 2 {
+			continue
+		}
+		if addr, ok := e[1].([]byte); ok {
+			ips = append(ips, net.IP(addr))
+		}
+	}
+	return ips, nil
+}
+
+// 通过 resolve1 覆盖接口上的 dns
+func setLinkDNS(conn *dbus.Conn, ifindex int, ips []net.IP) error {
+	data := make([]linkDNS, 0, len(ips))
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			data = append(data, linkDNS{Family: syscall.AF_INET, Address: []byte(ip4)})
+		} else {
+			data = append(data, linkDNS{Family: syscall.AF_INET6, Address: []byte(ip.To16())})
+		}
+	}
+
+	call := conn.Object(resolve1Dest, resolve1Path).Call(resolve1Manager+".SetLinkDNS", 0, int32(ifindex), data)
+	return call.Err
+}
+
+// 撤销接口上所有通过 resolve1 设置的 dns 配置
+func revertLinkDNS(conn *dbus.Conn, ifindex int) error {
+	call := conn.Object(resolve1Dest, resolve1Path).Call(resolve1Manager+".RevertLink", 0, int32(ifindex))
+	return call.Err
+}
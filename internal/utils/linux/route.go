@@ -0,0 +1,337 @@
+//go:build linux
+
+package linux
+
+import (
+	"fmt"
+	"nctl/interfaces"
+	"net"
+	"strconv"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+var routeTypes = map[int]string{
+	unix.RTN_UNICAST:     "unicast",
+	unix.RTN_LOCAL:       "local",
+	unix.RTN_BROADCAST:   "broadcast",
+	unix.RTN_ANYCAST:     "anycast",
+	unix.RTN_MULTICAST:   "multicast",
+	unix.RTN_BLACKHOLE:   "blackhole",
+	unix.RTN_UNREACHABLE: "unreachable",
+	unix.RTN_PROHIBIT:    "prohibit",
+	unix.RTN_THROW:       "throw",
+	unix.RTN_NAT:         "nat",
+}
+
+var routeScopes = []netlink.Scope{
+	netlink.SCOPE_UNIVERSE, netlink.SCOPE_SITE, netlink.SCOPE_LINK, netlink.SCOPE_HOST, netlink.SCOPE_NOWHERE,
+}
+
+// 将协议名（static, dhcp ...）或编号转换为 RouteProtocol
+func parseProtocol(name string) (netlink.RouteProtocol, error) {
+	for i := 0; i < 256; i++ {
+		if netlink.RouteProtocol(i).String() == name {
+			return netlink.RouteProtocol(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown route protocol '%s'", name)
+}
+
+func parseScope(name string) (netlink.Scope, error) {
+	for _, s := range routeScopes {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown route scope '%s' (value: universe, site, link, host, nowhere)", name)
+}
+
+func parseRouteType(name string) (int, error) {
+	for t, s := range routeTypes {
+		if s == name {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown route type '%s'", name)
+}
+
+func routeTypeName(t int) string {
+	if s, ok := routeTypes[t]; ok {
+		return s
+	}
+	return strconv.Itoa(t)
+}
+
+func netlinkFamily(family int) (int, error) {
+	switch family {
+	case 0:
+		return netlink.FAMILY_ALL, nil
+	case 4:
+		return netlink.FAMILY_V4, nil
+	case 6:
+		return netlink.FAMILY_V6, nil
+	default:
+		return 0, fmt.Errorf("invalid address family %d (value: 4, 6)", family)
+	}
+}
+
+// 列出所有路由表中符合条件的路由
+func (u *UnixNctl) ListRoutes(filter interfaces.RouteFilter) ([]interfaces.Route, error) {
+	family, err := netlinkFamily(filter.Family)
+	if err != nil {
+		return nil, err
+	}
+
+	// Table 为 0（RT_TABLE_UNSPEC）时返回所有路由表
+	nf := &netlink.Route{Table: filter.Table}
+	mask := netlink.RT_FILTER_TABLE
+	if filter.Iface != "" {
+		link, err := netlink.LinkByName(filter.Iface)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get interface '%s': %w", filter.Iface, err)
+		}
+		nf.LinkIndex = link.Attrs().Index
+		mask |= netlink.RT_FILTER_OIF
+	}
+	if filter.Protocol != "" {
+		if nf.Protocol, err = parseProtocol(filter.Protocol); err != nil {
+			return nil, err
+		}
+		mask |= netlink.RT_FILTER_PROTOCOL
+	}
+
+	routes, err := netlink.RouteListFiltered(family, nf, mask)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes: %w", err)
+	}
+
+	names := map[int]string{}
+	linkName := func(index int) string {
+		if index == 0 {
+			return ""
+		}
+		if name, ok := names[index]; ok {
+			return name
+		}
+		name := strconv.Itoa(index)
+		if link, err := netlink.LinkByIndex(index); err == nil {
+			name = link.Attrs().Name
+		}
+		names[index] = name
+		return name
+	}
+
+	var result []interfaces.Route
+	for _, r := range routes {
+		route := interfaces.Route{
+			Family:   4,
+			Dst:      r.Dst,
+			Gateway:  r.Gw,
+			Iface:    linkName(r.LinkIndex),
+			Src:      r.Src,
+			Metric:   r.Priority,
+			Table:    r.Table,
+			Scope:    r.Scope.String(),
+			Protocol: r.Protocol.String(),
+			Type:     routeTypeName(r.Type),
+		}
+		if r.Family == netlink.FAMILY_V6 {
+			route.Family = 6
+		}
+		if route.Dst == nil {
+			route.Dst = zeroIPNet(route.Family)
+		}
+		// 多路径路由只展示第一跳
+		if len(r.MultiPath) > 0 {
+			route.Gateway = r.MultiPath[0].Gw
+			route.Iface = linkName(r.MultiPath[0].LinkIndex)
+		}
+		result = append(result, route)
+	}
+	return result, nil
+}
+
+func zeroIPNet(family int) *net.IPNet {
+	if family == 6 {
+		return &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
+	}
+	return &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
+}
+
+// 转换为 netlink 路由，未指定 scope 时按 ip route 的规则推断
+func toNetlinkRoute(r *interfaces.Route) (*netlink.Route, error) {
+	if r.Dst == nil {
+		return nil, fmt.Errorf("route destination is required")
+	}
+
+	route := &netlink.Route{
+		Dst:      r.Dst,
+		Gw:       r.Gateway,
+		Src:      r.Src,
+		Priority: r.Metric,
+		Table:    r.Table,
+		Type:     unix.RTN_UNICAST,
+	}
+	if r.Iface != "" {
+		link, err := netlink.LinkByName(r.Iface)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get interface '%s': %w", r.Iface, err)
+		}
+		route.LinkIndex = link.Attrs().Index
+	}
+	if r.Type != "" {
+		t, err := parseRouteType(r.Type)
+		if err != nil {
+			return nil, err
+		}
+		route.Type = t
+	}
+	if r.Protocol != "" {
+		p, err := parseProtocol(r.Protocol)
+		if err != nil {
+			return nil, err
+		}
+		route.Protocol = p
+	}
+
+	switch {
+	case r.Scope != "":
+		s, err := parseScope(r.Scope)
+		if err != nil {
+			return nil, err
+		}
+		route.Scope = s
+	case route.Type == unix.RTN_LOCAL:
+		route.Scope = netlink.SCOPE_HOST
+	case route.Type == unix.RTN_UNICAST && r.Gateway == nil && r.Iface != "":
+		route.Scope = netlink.SCOPE_LINK
+	}
+	return route, nil
+}
+
+// 添加路由，默认标记为 static 协议
+func (u *UnixNctl) AddRoute(r *interfaces.Route) error {
+	route, err := toNetlinkRoute(r)
+	if err != nil {
+		return err
+	}
+	if r.Protocol == "" {
+		route.Protocol = unix.RTPROT_STATIC
+	}
+	if err := netlink.RouteAdd(route); err != nil {
+		return fmt.Errorf("failed to add route %s: %w", r.Dst, err)
+	}
+	return nil
+}
+
+// 删除路由，只按填写的字段匹配
+func (u *UnixNctl) DelRoute(r *interfaces.Route) error {
+	route, err := toNetlinkRoute(r)
+	if err != nil {
+		return err
+	}
+	// 删除时不限制 scope 和 type，交给内核匹配
+	if r.Scope == "" {
+		route.Scope = netlink.SCOPE_NOWHERE
+	}
+	if r.Type == "" {
+		route.Type = 0
+	}
+	if err := netlink.RouteDel(route); err != nil {
+		return fmt.Errorf("failed to delete route %s: %w", r.Dst, err)
+	}
+	return nil
+}
+
+func (u *UnixNctl) ReplaceRoute(r *interfaces.Route) error {
+	route, err := toNetlinkRoute(r)
+	if err != nil {
+		return err
+	}
+	if r.Protocol == "" {
+		route.Protocol = unix.RTPROT_STATIC
+	}
+	if err := netlink.RouteReplace(route); err != nil {
+		return fmt.Errorf("failed to replace route %s: %w", r.Dst, err)
+	}
+	return nil
+}
+
+// 通过 RouteGet 查询内核选择的路由，再从路由表和策略规则中找出对应的表项和规则
+func (u *UnixNctl) LookupRoute(q interfaces.RouteQuery) (*interfaces.RouteLookup, error) {
+	opts := &netlink.RouteGetOptions{SrcAddr: q.Src, Mark: q.Mark, Iif: q.Iif}
+	routes, err := netlink.RouteGetWithOptions(q.Dst, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up route to %s: %w", q.Dst, err)
+	}
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("no route to %s", q.Dst)
+	}
+	r := routes[0]
+
+	family := netlink.FAMILY_V4
+	result := &interfaces.RouteLookup{Route: interfaces.Route{
+		Family:  4,
+		Gateway: r.Gw,
+		Src:     r.Src,
+		Table:   r.Table,
+		Type:    routeTypeName(r.Type),
+	}}
+	if q.Dst.To4() == nil {
+		family = netlink.FAMILY_V6
+		result.Route.Family = 6
+	}
+	if link, err := netlink.LinkByIndex(r.LinkIndex); err == nil {
+		result.Route.Iface = link.Attrs().Name
+	}
+
+	// RouteGet 返回的是主机路由，取路由表中包含目的地址的最长前缀作为匹配的表项
+	entries, err := netlink.RouteListFiltered(family, &netlink.Route{Table: r.Table}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes: %w", err)
+	}
+	best := -1
+	for _, e := range entries {
+		dst := e.Dst
+		if dst == nil {
+			dst = zeroIPNet(result.Route.Family)
+		}
+		ones, _ := dst.Mask.Size()
+		if !dst.Contains(q.Dst) || ones <= best || e.Type != r.Type {
+			continue
+		}
+		if e.Type == unix.RTN_UNICAST && (e.LinkIndex != r.LinkIndex || !e.Gw.Equal(r.Gw)) {
+			continue
+		}
+		best = ones
+		result.Route.Dst = dst
+		result.Route.Metric = e.Priority
+		result.Route.Scope = e.Scope.String()
+		result.Route.Protocol = e.Protocol.String()
+	}
+	if result.Route.Dst == nil {
+		ip, bits := q.Dst.To4(), 32
+		if result.Route.Family == 6 {
+			ip, bits = q.Dst.To16(), 128
+		}
+		result.Route.Dst = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	}
+
+	// 第一条匹配查询、查询该路由表且未被 suppress_prefixlength 排除的规则即为选中路由的规则
+	rules, err := listRules(family)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if rule.SuppressPrefixlen >= 0 && best <= rule.SuppressPrefixlen {
+			continue
+		}
+		if rule.Action == "lookup" && rule.Table == r.Table && ruleMatches(rule, q) {
+			result.Rule = &rule
+			break
+		}
+	}
+	return result, nil
+}
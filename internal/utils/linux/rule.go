@@ -0,0 +1,219 @@
+//go:build linux
+
+package linux
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"nctl/interfaces"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+var ruleActions = map[uint8]string{
+	unix.FR_ACT_TO_TBL:      "lookup",
+	unix.FR_ACT_GOTO:        "goto",
+	unix.FR_ACT_NOP:         "nop",
+	unix.FR_ACT_BLACKHOLE:   "blackhole",
+	unix.FR_ACT_UNREACHABLE: "unreachable",
+	unix.FR_ACT_PROHIBIT:    "prohibit",
+}
+
+// netlink.RuleList 不返回规则的动作，这里直接解析 RTM_GETRULE 的结果，按优先级排列
+func listRules(family int) ([]interfaces.Rule, error) {
+	req := nl.NewNetlinkRequest(unix.RTM_GETRULE, unix.NLM_F_DUMP)
+	req.AddData(nl.NewIfInfomsg(family))
+
+	msgs, err := req.Execute(unix.NETLINK_ROUTE, unix.RTM_NEWRULE)
+	if err != nil && !errors.Is(err, nl.ErrDumpInterrupted) {
+		return nil, fmt.Errorf("failed to list rules: %w", err)
+	}
+
+	var rules []interfaces.Rule
+	for _, m := range msgs {
+		// fib_rule_hdr 与 rtmsg 布局相同，动作保存在 rtm_type 的位置
+		msg := nl.DeserializeRtMsg(m)
+		// 未指定地址族时内核也会返回组播路由等其他类型的规则
+		if msg.Family != unix.AF_INET && msg.Family != unix.AF_INET6 {
+			continue
+		}
+		attrs, err := nl.ParseRouteAttr(m[msg.Len():])
+		if err != nil {
+			return nil, err
+		}
+
+		rule := interfaces.Rule{
+			Family:            4,
+			Invert:            msg.Flags&netlink.FibRuleInvert != 0,
+			Action:            ruleActions[msg.Type],
+			Table:             int(msg.Table),
+			SuppressPrefixlen: -1,
+		}
+		if msg.Family == unix.AF_INET6 {
+			rule.Family = 6
+		}
+		if rule.Action == "" {
+			rule.Action = fmt.Sprintf("action-%d", msg.Type)
+		}
+
+		for _, attr := range attrs {
+			v := attr.Value
+			switch attr.Attr.Type {
+			case unix.FRA_PRIORITY:
+				rule.Priority = int(binary.NativeEndian.Uint32(v))
+			case unix.FRA_TABLE:
+				rule.Table = int(binary.NativeEndian.Uint32(v))
+			case unix.FRA_SRC:
+				rule.Src = &net.IPNet{IP: net.IP(v), Mask: net.CIDRMask(int(msg.Src_len), 8*len(v))}
+			case unix.FRA_DST:
+				rule.Dst = &net.IPNet{IP: net.IP(v), Mask: net.CIDRMask(int(msg.Dst_len), 8*len(v))}
+			case unix.FRA_FWMARK:
+				rule.Mark = binary.NativeEndian.Uint32(v)
+			case unix.FRA_FWMASK:
+				rule.Mask = binary.NativeEndian.Uint32(v)
+			case unix.FRA_IIFNAME:
+				rule.Iif = strings.TrimRight(string(v), "\x00")
+			case unix.FRA_OIFNAME:
+				rule.Oif = strings.TrimRight(string(v), "\x00")
+			case unix.FRA_GOTO:
+				rule.Goto = int(binary.NativeEndian.Uint32(v))
+			case unix.FRA_SUPPRESS_PREFIXLEN:
+				rule.SuppressPrefixlen = int(int32(binary.NativeEndian.Uint32(v)))
+			}
+		}
+		rules = append(rules, rule)
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Priority < rules[j].Priority })
+	return rules, nil
+}
+
+// 判断规则的选择条件是否匹配查询，本机发出的报文 iif 为 lo，且不带 oif
+func ruleMatches(r interfaces.Rule, q interfaces.RouteQuery) bool {
+	match := func() bool {
+		if r.Src != nil {
+			if ones, _ := r.Src.Mask.Size(); ones > 0 && (q.Src == nil || !r.Src.Contains(q.Src)) {
+				return false
+			}
+		}
+		if r.Dst != nil && !r.Dst.Contains(q.Dst) {
+			return false
+		}
+		if r.Mark != 0 || r.Mask != 0 {
+			mask := r.Mask
+			if mask == 0 {
+				mask = 0xffffffff
+			}
+			if q.Mark&mask != r.Mark {
+				return false
+			}
+		}
+		iif := q.Iif
+		if iif == "" {
+			iif = "lo"
+		}
+		if r.Iif != "" && r.Iif != iif {
+			return false
+		}
+		return r.Oif == ""
+	}()
+	return match != r.Invert
+}
+
+// 按优先级列出策略路由规则
+func (u *UnixNctl) ListRules(family int) ([]interfaces.Rule, error) {
+	nf, err := netlinkFamily(family)
+	if err != nil {
+		return nil, err
+	}
+	return listRules(nf)
+}
+
+// 转换为 netlink 规则，地址族由 Src/Dst 推断，都未指定时使用 r.Family
+func toNetlinkRule(r *interfaces.Rule) (*netlink.Rule, error) {
+	rule := netlink.NewRule()
+	rule.Priority = r.Priority
+	rule.Src = r.Src
+	rule.Dst = r.Dst
+	rule.Mark = r.Mark
+	rule.IifName = r.Iif
+	rule.OifName = r.Oif
+	rule.Invert = r.Invert
+	rule.SuppressPrefixlen = r.SuppressPrefixlen
+	if r.Mask != 0 {
+		mask := r.Mask
+		rule.Mask = &mask
+	}
+
+	rule.Family = netlink.FAMILY_V4
+	if r.Family == 6 {
+		rule.Family = netlink.FAMILY_V6
+	}
+	for _, ipnet := range []*net.IPNet{r.Src, r.Dst} {
+		if ipnet == nil {
+			continue
+		}
+		family := netlink.FAMILY_V4
+		if ipnet.IP.To4() == nil {
+			family = netlink.FAMILY_V6
+		}
+		if r.Family != 0 && family != rule.Family {
+			return nil, fmt.Errorf("address %s does not match the rule family", ipnet)
+		}
+		rule.Family = family
+	}
+
+	switch r.Action {
+	case "", "lookup":
+		rule.Table = r.Table
+		if rule.Table == 0 {
+			rule.Table = interfaces.TableMain
+		}
+	case "goto":
+		rule.Goto = r.Goto
+	default:
+		found := false
+		for t, name := range ruleActions {
+			if name == r.Action {
+				rule.Type = t
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown rule action '%s' (value: lookup, goto, nop, blackhole, unreachable, prohibit)", r.Action)
+		}
+	}
+	return rule, nil
+}
+
+func (u *UnixNctl) AddRule(r *interfaces.Rule) error {
+	rule, err := toNetlinkRule(r)
+	if err != nil {
+		return err
+	}
+	if err := netlink.RuleAdd(rule); err != nil {
+		return fmt.Errorf("failed to add rule: %w", err)
+	}
+	return nil
+}
+
+func (u *UnixNctl) DelRule(r *interfaces.Rule) error {
+	rule, err := toNetlinkRule(r)
+	if err != nil {
+		return err
+	}
+	// 删除时未指定路由表则不按路由表匹配
+	if r.Table == 0 && (r.Action == "" || r.Action == "lookup") {
+		rule.Table = -1
+	}
+	if err := netlink.RuleDel(rule); err != nil {
+		return fmt.Errorf("failed to delete rule: %w", err)
+	}
+	return nil
+}
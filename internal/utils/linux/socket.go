@@ -0,0 +1,127 @@
+//go:build linux
+
+package linux
+
+import (
+	"errors"
+	"fmt"
+	"nctl/interfaces"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// TCP 状态名称，与 ss 保持一致
+var tcpStates = map[uint8]string{
+	netlink.TCP_ESTABLISHED: "ESTAB",
+	netlink.TCP_SYN_SENT:    "SYN-SENT",
+	netlink.TCP_SYN_RECV:    "SYN-RECV",
+	netlink.TCP_FIN_WAIT1:   "FIN-WAIT-1",
+	netlink.TCP_FIN_WAIT2:   "FIN-WAIT-2",
+	netlink.TCP_TIME_WAIT:   "TIME-WAIT",
+	netlink.TCP_CLOSE:       "UNCONN",
+	netlink.TCP_CLOSE_WAIT:  "CLOSE-WAIT",
+	netlink.TCP_LAST_ACK:    "LAST-ACK",
+	netlink.TCP_LISTEN:      "LISTEN",
+	netlink.TCP_CLOSING:     "CLOSING",
+	netlink.TCP_NEW_SYN_REC: "NEW-SYN-RECV",
+}
+
+// 通过 sock_diag 列出套接字
+func (u *UnixNctl) ListSockets(filter interfaces.SocketFilter) ([]interfaces.Socket, error) {
+	type query struct {
+		proto string
+		dump  func(uint8) ([]*netlink.Socket, error)
+	}
+	var queries []query
+	if filter.TCP || !filter.UDP {
+		queries = append(queries, query{"tcp", netlink.SocketDiagTCP})
+	}
+	if filter.UDP || !filter.TCP {
+		queries = append(queries, query{"udp", netlink.SocketDiagUDP})
+	}
+
+	var result []interfaces.Socket
+	var inodes []uint32
+	for _, q := range queries {
+		for _, family := range []uint8{unix.AF_INET, unix.AF_INET6} {
+			socks, err := q.dump(family)
+			if err != nil && !errors.Is(err, netlink.ErrDumpInterrupted) {
+				return nil, fmt.Errorf("failed to list %s sockets: %w", q.proto, err)
+			}
+			for _, s := range socks {
+				sock := fromDiagSocket(q.proto, s)
+				if filter.Listen && !sock.Listening() {
+					continue
+				}
+				if filter.Port != 0 && sock.LocalPort != filter.Port && sock.RemotePort != filter.Port {
+					continue
+				}
+				result = append(result, sock)
+				inodes = append(inodes, s.INode)
+			}
+		}
+	}
+
+	// 所有套接字共用一次 /proc 扫描
+	owners := socketOwners()
+	for i := range result {
+		if owner, ok := owners[inodes[i]]; ok {
+			result[i].PID = owner
+			result[i].Command = processName(owner)
+		}
+	}
+	return result, nil
+}
+
+func fromDiagSocket(proto string, s *netlink.Socket) interfaces.Socket {
+	sock := interfaces.Socket{
+		Proto:      proto,
+		Family:     4,
+		LocalIP:    s.ID.Source,
+		LocalPort:  int(s.ID.SourcePort),
+		RemoteIP:   s.ID.Destination,
+		RemotePort: int(s.ID.DestinationPort),
+		State:      tcpStates[s.State],
+	}
+	if s.Family == unix.AF_INET6 {
+		sock.Family = 6
+	}
+	if sock.State == "" {
+		sock.State = strconv.Itoa(int(s.State))
+	}
+	return sock
+}
+
+// 扫描 /proc/<pid>/fd 得到套接字 inode 到进程号的映射，无权限的进程被跳过
+func socketOwners() map[uint32]int {
+	owners := map[uint32]int{}
+	links, _ := filepath.Glob("/proc/[0-9]*/fd/[0-9]*")
+	for _, link := range links {
+		target, err := os.Readlink(link)
+		if err != nil || !strings.HasPrefix(target, "socket:[") {
+			continue
+		}
+		inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]"), 10, 32)
+		if err != nil {
+			continue
+		}
+		pid, _ := strconv.Atoi(strings.Split(link, "/")[2])
+		if _, ok := owners[uint32(inode)]; !ok {
+			owners[uint32(inode)] = pid
+		}
+	}
+	return owners
+}
+
+func processName(pid int) string {
+	comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(comm))
+}
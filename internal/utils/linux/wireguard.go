@@ -0,0 +1,402 @@
+//go:build linux
+
+package linux
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"nctl/interfaces"
+	"net"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// include/uapi/linux/wireguard.h
+const (
+	wgGenlName    = "wireguard"
+	wgGenlVersion = 1
+
+	wgCmdGetDevice = 0
+	wgCmdSetDevice = 1
+
+	wgDeviceAIfname     = 2
+	wgDeviceAPrivateKey = 3
+	wgDeviceAPublicKey  = 4
+	wgDeviceAFlags      = 5
+	wgDeviceAListenPort = 6
+	wgDeviceAFwmark     = 7
+	wgDeviceAPeers      = 8
+
+	wgDeviceFReplacePeers = 1
+
+	wgPeerAPublicKey           = 1
+	wgPeerAPresharedKey        = 2
+	wgPeerAFlags               = 3
+	wgPeerAEndpoint            = 4
+	wgPeerAPersistentKeepalive = 5
+	wgPeerALastHandshakeTime   = 6
+	wgPeerARxBytes             = 7
+	wgPeerATxBytes             = 8
+	wgPeerAAllowedIPs          = 9
+
+	wgPeerFRemoveMe          = 1
+	wgPeerFReplaceAllowedIPs = 2
+
+	wgAllowedIPAFamily   = 1
+	wgAllowedIPAIPAddr   = 2
+	wgAllowedIPACidrMask = 3
+)
+
+func wgFamily() (uint16, error) {
+	f, err := netlink.GenlFamilyGet(wgGenlName)
+	if err != nil {
+		return 0, fmt.Errorf("wireguard is not available in this kernel: %w", err)
+	}
+	return f.ID, nil
+}
+
+func (u *UnixNctl) CreateWireGuard(name string) error {
+	if _, err := netlink.LinkByName(name); err == nil {
+		return fmt.Errorf("interface %s already exists", name)
+	}
+	if err := netlink.LinkAdd(&netlink.Wireguard{LinkAttrs: netlink.LinkAttrs{Name: name}}); err != nil {
+		return fmt.Errorf("failed to create wireguard interface %s (is the wireguard module available?): %w", name, err)
+	}
+	return nil
+}
+
+func (u *UnixNctl) DeleteWireGuard(name string) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return fmt.Errorf("interface %s not found: %w", name, err)
+	}
+	if link.Type() != "wireguard" {
+		return fmt.Errorf("%s is not a wireguard interface", name)
+	}
+	if err := netlink.LinkDel(link); err != nil {
+		return fmt.Errorf("failed to delete wireguard interface %s: %w", name, err)
+	}
+	return nil
+}
+
+func (u *UnixNctl) ListWireGuards(name string) ([]interfaces.WireGuardDevice, error) {
+	var names []string
+	if name != "" {
+		link, err := netlink.LinkByName(name)
+		if err != nil {
+			return nil, fmt.Errorf("interface %s not found: %w", name, err)
+		}
+		if link.Type() != "wireguard" {
+			return nil, fmt.Errorf("%s is not a wireguard interface", name)
+		}
+		names = append(names, name)
+	} else {
+		links, err := netlink.LinkList()
+		if err != nil {
+			return nil, err
+		}
+		for _, link := range links {
+			if link.Type() == "wireguard" {
+				names = append(names, link.Attrs().Name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	family, err := wgFamily()
+	if err != nil {
+		return nil, err
+	}
+	var devices []interfaces.WireGuardDevice
+	for _, n := range names {
+		d, err := getWireGuard(family, n)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", n, err)
+		}
+		devices = append(devices, *d)
+	}
+	return devices, nil
+}
+
+// 对端较多时内核会把结果拆成多条消息，同一个对端的 allowed ips 也可能跨消息
+func getWireGuard(family uint16, name string) (*interfaces.WireGuardDevice, error) {
+	req := nl.NewNetlinkRequest(int(family), unix.NLM_F_DUMP)
+	req.AddData(&nl.Genlmsg{Command: wgCmdGetDevice, Version: wgGenlVersion})
+	req.AddData(nl.NewRtAttr(wgDeviceAIfname, nl.ZeroTerminated(name)))
+	msgs, err := req.Execute(unix.NETLINK_GENERIC, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &interfaces.WireGuardDevice{Name: name}
+	for _, m := range msgs {
+		attrs, err := nl.ParseRouteAttr(m[nl.SizeofGenlmsg:])
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range attrs {
+			switch a.Attr.Type & nl.NLA_TYPE_MASK {
+			case wgDeviceAPrivateKey:
+				d.PrivateKey = encodeKey(a.Value)
+			case wgDeviceAPublicKey:
+				d.PublicKey = encodeKey(a.Value)
+			case wgDeviceAListenPort:
+				d.ListenPort = int(native.Uint16(a.Value))
+			case wgDeviceAFwmark:
+				d.FwMark = native.Uint32(a.Value)
+			case wgDeviceAPeers:
+				peers, err := nl.ParseRouteAttr(a.Value)
+				if err != nil {
+					return nil, err
+				}
+				for _, p := range peers {
+					peer, err := parsePeer(p.Value)
+					if err != nil {
+						return nil, err
+					}
+					if n := len(d.Peers); n > 0 && d.Peers[n-1].PublicKey == peer.PublicKey {
+						d.Peers[n-1].AllowedIPs = append(d.Peers[n-1].AllowedIPs, peer.AllowedIPs...)
+						continue
+					}
+					d.Peers = append(d.Peers, *peer)
+				}
+			}
+		}
+	}
+	return d, nil
+}
+
+var native = binary.NativeEndian
+
+// 全零的密钥表示未设置
+func encodeKey(b []byte) string {
+	for _, c := range b {
+		if c != 0 {
+			return base64.StdEncoding.EncodeToString(b)
+		}
+	}
+	return ""
+}
+
+func parsePeer(b []byte) (*interfaces.WireGuardPeer, error) {
+	attrs, err := nl.ParseRouteAttr(b)
+	if err != nil {
+		return nil, err
+	}
+	p := &interfaces.WireGuardPeer{}
+	for _, a := range attrs {
+		switch a.Attr.Type & nl.NLA_TYPE_MASK {
+		case wgPeerAPublicKey:
+			p.PublicKey = encodeKey(a.Value)
+		case wgPeerAPresharedKey:
+			p.PresharedKey = encodeKey(a.Value)
+		case wgPeerAEndpoint:
+			p.Endpoint = parseSockaddr(a.Value)
+		case wgPeerAPersistentKeepalive:
+			p.PersistentKeepalive = time.Duration(native.Uint16(a.Value)) * time.Second
+		case wgPeerALastHandshakeTime:
+			// struct __kernel_timespec
+			if len(a.Value) >= 16 {
+				sec, nsec := int64(native.Uint64(a.Value)), int64(native.Uint64(a.Value[8:]))
+				if sec != 0 || nsec != 0 {
+					p.LastHandshake = time.Unix(sec, nsec)
+				}
+			}
+		case wgPeerARxBytes:
+			p.RxBytes = native.Uint64(a.Value)
+		case wgPeerATxBytes:
+			p.TxBytes = native.Uint64(a.Value)
+		case wgPeerAAllowedIPs:
+			ips, err := nl.ParseRouteAttr(a.Value)
+			if err != nil {
+				return nil, err
+			}
+			for _, ip := range ips {
+				if ipnet := parseAllowedIP(ip.Value); ipnet != nil {
+					p.AllowedIPs = append(p.AllowedIPs, ipnet)
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+func parseAllowedIP(b []byte) *net.IPNet {
+	attrs, err := nl.ParseRouteAttr(b)
+	if err != nil {
+		return nil
+	}
+	var ip net.IP
+	var ones int
+	for _, a := range attrs {
+		switch a.Attr.Type & nl.NLA_TYPE_MASK {
+		case wgAllowedIPAIPAddr:
+			ip = net.IP(append([]byte(nil), a.Value...))
+		case wgAllowedIPACidrMask:
+			ones = int(a.Value[0])
+		}
+	}
+	if ip == nil {
+		return nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(ones, len(ip)*8)}
+}
+
+// sockaddr_in 或 sockaddr_in6，端口为网络字节序
+func parseSockaddr(b []byte) *net.UDPAddr {
+	if len(b) < 2 {
+		return nil
+	}
+	switch native.Uint16(b) {
+	case unix.AF_INET:
+		if len(b) >= 8 {
+			return &net.UDPAddr{IP: net.IP(append([]byte(nil), b[4:8]...)), Port: int(binary.BigEndian.Uint16(b[2:]))}
+		}
+	case unix.AF_INET6:
+		if len(b) >= 28 {
+			addr := &net.UDPAddr{IP: net.IP(append([]byte(nil), b[8:24]...)), Port: int(binary.BigEndian.Uint16(b[2:]))}
+			if scope := native.Uint32(b[24:]); scope != 0 {
+				if ifi, err := net.InterfaceByIndex(int(scope)); err == nil {
+					addr.Zone = ifi.Name
+				}
+			}
+			return addr
+		}
+	}
+	return nil
+}
+
+func sockaddrBytes(addr *net.UDPAddr) []byte {
+	if ip4 := addr.IP.To4(); ip4 != nil {
+		b := make([]byte, unix.SizeofSockaddrInet4)
+		native.PutUint16(b, unix.AF_INET)
+		binary.BigEndian.PutUint16(b[2:], uint16(addr.Port))
+		copy(b[4:], ip4)
+		return b
+	}
+	b := make([]byte, unix.SizeofSockaddrInet6)
+	native.PutUint16(b, unix.AF_INET6)
+	binary.BigEndian.PutUint16(b[2:], uint16(addr.Port))
+	copy(b[8:], addr.IP.To16())
+	if addr.Zone != "" {
+		if ifi, err := net.InterfaceByName(addr.Zone); err == nil {
+			native.PutUint32(b[24:], uint32(ifi.Index))
+		}
+	}
+	return b
+}
+
+func keyAttr(attrType int, key string) (*nl.RtAttr, error) {
+	b, err := interfaces.ParseWireGuardKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return nl.NewRtAttr(attrType, b), nil
+}
+
+func (u *UnixNctl) ConfigureWireGuard(name string, cfg *interfaces.WireGuardConfig) error {
+	if _, err := u.ListWireGuards(name); err != nil {
+		return err
+	}
+	family, err := wgFamily()
+	if err != nil {
+		return err
+	}
+
+	req := nl.NewNetlinkRequest(int(family), unix.NLM_F_ACK)
+	req.AddData(&nl.Genlmsg{Command: wgCmdSetDevice, Version: wgGenlVersion})
+	req.AddData(nl.NewRtAttr(wgDeviceAIfname, nl.ZeroTerminated(name)))
+	if cfg.PrivateKey != nil {
+		attr, err := keyAttr(wgDeviceAPrivateKey, *cfg.PrivateKey)
+		if err != nil {
+			return err
+		}
+		req.AddData(attr)
+	}
+	if cfg.ListenPort != nil {
+		req.AddData(nl.NewRtAttr(wgDeviceAListenPort, nl.Uint16Attr(uint16(*cfg.ListenPort))))
+	}
+	if cfg.FwMark != nil {
+		req.AddData(nl.NewRtAttr(wgDeviceAFwmark, nl.Uint32Attr(*cfg.FwMark)))
+	}
+	if cfg.ReplacePeers {
+		req.AddData(nl.NewRtAttr(wgDeviceAFlags, nl.Uint32Attr(wgDeviceFReplacePeers)))
+	}
+
+	if len(cfg.Peers) > 0 {
+		peers := nl.NewRtAttr(unix.NLA_F_NESTED|wgDeviceAPeers, nil)
+		for i, p := range cfg.Peers {
+			peer, err := peerAttr(i, p)
+			if err != nil {
+				return err
+			}
+			peers.AddChild(peer)
+		}
+		req.AddData(peers)
+	}
+
+	if _, err := req.Execute(unix.NETLINK_GENERIC, 0); err != nil {
+		return fmt.Errorf("failed to configure %s: %w", name, err)
+	}
+	return nil
+}
+
+func peerAttr(index int, p interfaces.WireGuardPeerConfig) (*nl.RtAttr, error) {
+	peer := nl.NewRtAttr(unix.NLA_F_NESTED|index, nil)
+	pub, err := keyAttr(wgPeerAPublicKey, p.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	peer.AddChild(pub)
+
+	var flags uint32
+	if p.Remove {
+		peer.AddChild(nl.NewRtAttr(wgPeerAFlags, nl.Uint32Attr(wgPeerFRemoveMe)))
+		return peer, nil
+	}
+	if p.ReplaceAllowedIPs {
+		flags |= wgPeerFReplaceAllowedIPs
+	}
+	if flags != 0 {
+		peer.AddChild(nl.NewRtAttr(wgPeerAFlags, nl.Uint32Attr(flags)))
+	}
+	if p.PresharedKey != nil {
+		psk := make([]byte, interfaces.WireGuardKeyLen)
+		// 空字符串表示清除预共享密钥
+		if *p.PresharedKey != "" {
+			if psk, err = interfaces.ParseWireGuardKey(*p.PresharedKey); err != nil {
+				return nil, err
+			}
+		}
+		peer.AddChild(nl.NewRtAttr(wgPeerAPresharedKey, psk))
+	}
+	if p.Endpoint != nil {
+		peer.AddChild(nl.NewRtAttr(wgPeerAEndpoint, sockaddrBytes(p.Endpoint)))
+	}
+	if p.PersistentKeepalive != nil {
+		peer.AddChild(nl.NewRtAttr(wgPeerAPersistentKeepalive, nl.Uint16Attr(uint16(*p.PersistentKeepalive/time.Second))))
+	}
+	if len(p.AllowedIPs) > 0 || p.ReplaceAllowedIPs {
+		ips := nl.NewRtAttr(unix.NLA_F_NESTED|wgPeerAAllowedIPs, nil)
+		for i, ipnet := range p.AllowedIPs {
+			entry := nl.NewRtAttr(unix.NLA_F_NESTED|i, nil)
+			family, ip := uint16(unix.AF_INET6), ipnet.IP.To16()
+			if ip4 := ipnet.IP.To4(); ip4 != nil {
+				family, ip = unix.AF_INET, ip4
+			}
+			ones, _ := ipnet.Mask.Size()
+			entry.AddChild(nl.NewRtAttr(wgAllowedIPAFamily, nl.Uint16Attr(family)))
+			entry.AddChild(nl.NewRtAttr(wgAllowedIPAIPAddr, ip))
+			entry.AddChild(nl.NewRtAttr(wgAllowedIPACidrMask, nl.Uint8Attr(uint8(ones))))
+			ips.AddChild(entry)
+		}
+		peer.AddChild(ips)
+	}
+	return peer, nil
+}
@@ -0,0 +1,69 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// 支持的输出格式
+const (
+	Table = "table"
+	JSON  = "json"
+	YAML  = "yaml"
+	CSV   = "csv"
+)
+
+// 在根命令上注册全局的 --output 参数，所有子命令都会继承
+func RegisterFlag(rootCmd *cobra.Command) {
+	rootCmd.PersistentFlags().String("output", Table, "Output format (value: table, json, yaml, csv)")
+}
+
+// 读取并校验 --output 参数
+func Format(cmd *cobra.Command) (string, error) {
+	format, err := cmd.Flags().GetString("output")
+	if err != nil {
+		// 未挂载在根命令下时使用表格输出
+		return Table, nil
+	}
+
+	switch format {
+	case Table, JSON, YAML, CSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported output format '%s' (value: table, json, yaml, csv)", format)
+	}
+}
+
+// 以 json 或 yaml 格式输出
+func Write(w io.Writer, format string, v any) error {
+	switch format {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case YAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(v)
+	default:
+		return fmt.Errorf("format '%s' cannot be written as structured data", format)
+	}
+}
+
+// 以 csv 格式输出
+func WriteCSV(w io.Writer, header []string, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
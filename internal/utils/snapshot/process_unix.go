@@ -0,0 +1,17 @@
+//go:build !windows
+
+package snapshot
+
+import (
+	"errors"
+	"syscall"
+)
+
+// 用 0 号信号检查进程是否存在，没有权限发送信号时进程同样存在
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package snapshot
+
+import "golang.org/x/sys/windows"
+
+// STILL_ACTIVE
+const stillActive = 259
+
+// 打开进程并检查是否已退出
+func processAlive(pid int) bool {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		// 没有权限打开说明进程存在
+		return err == windows.ERROR_ACCESS_DENIED
+	}
+	defer windows.CloseHandle(h)
+
+	var code uint32
+	if err := windows.GetExitCodeProcess(h, &code); err != nil {
+		return true
+	}
+	return code == stillActive
+}
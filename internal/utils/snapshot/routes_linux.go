@@ -0,0 +1,72 @@
+//go:build linux
+
+package snapshot
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+)
+
+// 记录接口上非内核生成的非默认路由
+func CaptureRoutes(ifaceName string) ([]Route, error) {
+	link, err := netlink.LinkByName(ifaceName)
+	if err != nil {
+		return nil, err
+	}
+
+	routes, err := netlink.RouteList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Route
+	for _, r := range routes {
+		if r.Dst == nil || r.Protocol == syscall.RTPROT_KERNEL || r.Protocol == syscall.RTPROT_RA {
+			continue
+		}
+		ones, _ := r.Dst.Mask.Size()
+		if ones == 0 {
+			continue
+		}
+		route := Route{
+			Dst:      r.Dst.String(),
+			Metric:   r.Priority,
+			Table:    r.Table,
+			Scope:    int(r.Scope),
+			Protocol: int(r.Protocol),
+		}
+		if r.Gw != nil {
+			route.Gw = r.Gw.String()
+		}
+		result = append(result, route)
+	}
+	return result, nil
+}
+
+func RestoreRoute(ifaceName string, r Route) error {
+	link, err := netlink.LinkByName(ifaceName)
+	if err != nil {
+		return err
+	}
+
+	_, dst, err := net.ParseCIDR(r.Dst)
+	if err != nil {
+		return fmt.Errorf("invalid route destination %s", r.Dst)
+	}
+
+	route := &netlink.Route{
+		LinkIndex: link.Attrs().Index,
+		Dst:       dst,
+		Priority:  r.Metric,
+		Table:     r.Table,
+		Scope:     netlink.Scope(r.Scope),
+		Protocol:  netlink.RouteProtocol(r.Protocol),
+	}
+	if r.Gw != "" {
+		route.Gw = net.ParseIP(r.Gw)
+	}
+	return netlink.RouteReplace(route)
+}
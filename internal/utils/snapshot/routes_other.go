@@ -0,0 +1,14 @@
+//go:build !linux
+
+package snapshot
+
+import "fmt"
+
+// 非 linux 平台只记录默认网关
+func CaptureRoutes(ifaceName string) ([]Route, error) {
+	return nil, nil
+}
+
+func RestoreRoute(ifaceName string, r Route) error {
+	return fmt.Errorf("restoring routes is not supported on this platform")
+}
@@ -0,0 +1,233 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"nctl/interfaces"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 接口在某一时刻的链路状态、ip、路由和 dns 配置
+type Snapshot struct {
+	Iface string    `json:"iface"`
+	Taken time.Time `json:"taken"`
+	// 链路层设置，旧版本保存的快照中没有这些字段，恢复时跳过
+	Up       *bool    `json:"up,omitempty"`
+	MTU      int      `json:"mtu,omitempty"`
+	MAC      string   `json:"mac,omitempty"`
+	IPs      []string `json:"ips"`
+	Gateways []string `json:"gateways"`
+	// 网关的 metric，旧版本保存的快照中没有该字段
+	GatewayMetrics map[string]int `json:"gateway_metrics,omitempty"`
+	Routes         []Route        `json:"routes,omitempty"`
+	DNS            []string       `json:"dns"`
+}
+
+// 非默认路由，默认路由记录在 Gateways 中
+type Route struct {
+	Dst      string `json:"dst"`
+	Gw       string `json:"gw,omitempty"`
+	Metric   int    `json:"metric,omitempty"`
+	Table    int    `json:"table,omitempty"`
+	Scope    int    `json:"scope,omitempty"`
+	Protocol int    `json:"protocol,omitempty"`
+}
+
+// 记录接口当前的配置。dns 读取失败时快照中不包含 dns，错误通过 warn 报告，warn 可以为 nil
+func Take(ifaceUtils interfaces.Ifaces, ifaceName string, warn func(error)) (*Snapshot, error) {
+	if err := ifaceUtils.IsExistingIface(ifaceName); err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{Iface: ifaceName, Taken: time.Now()}
+
+	details, err := ifaceUtils.GetLinkDetails(ifaceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read link details: %w", err)
+	}
+	s.Up, s.MTU = &details.Up, details.MTU
+	if len(details.HardwareAddr) > 0 {
+		s.MAC = details.HardwareAddr.String()
+	}
+
+	ipnets, err := ifaceUtils.GetIPs(ifaceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IP addresses: %w", err)
+	}
+	for _, ipnet := range ipnets {
+		s.IPs = append(s.IPs, ipnet.String())
+	}
+
+	gws, err := ifaceUtils.GetGateways(ifaceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gateways: %w", err)
+	}
+	for _, gw := range gws {
+		s.Gateways = append(s.Gateways, gw.IP.String())
+		if gw.Metric != 0 {
+			if s.GatewayMetrics == nil {
+				s.GatewayMetrics = map[string]int{}
+			}
+			s.GatewayMetrics[gw.IP.String()] = gw.Metric
+		}
+	}
+
+	if s.Routes, err = CaptureRoutes(ifaceName); err != nil {
+		return nil, fmt.Errorf("failed to read routes: %w", err)
+	}
+
+	dnsIPs, err := ifaceUtils.GetDNSs(ifaceName)
+	if err != nil && warn != nil {
+		warn(fmt.Errorf("failed to read DNS servers: %w", err))
+	}
+	for _, ip := range dnsIPs {
+		s.DNS = append(s.DNS, ip.String())
+	}
+
+	return s, nil
+}
+
+// 恢复快照中的一步操作
+type Step struct {
+	Desc  string
+	Apply func() error
+}
+
+// 恢复快照所需的操作：先恢复 MAC、MTU 和链路状态，再清空接口上的地址、路由和 dns 并按快照重新设置。
+// 清空路由时默认网关一并删除，之后逐个添加，不能用 SetGateway，否则同一地址族只会留下最后一个
+func (s *Snapshot) Steps(ifaceUtils interfaces.Ifaces) []Step {
+	steps := s.linkSteps(ifaceUtils)
+	steps = append(steps,
+		Step{"Flushing IP addresses", func() error { return ifaceUtils.FlushIPs(s.Iface) }},
+		Step{"Flushing static routes", func() error { return ifaceUtils.FlushRoutes(s.Iface) }},
+		Step{"Flushing DNS servers", func() error { return ifaceUtils.FlushDNS(s.Iface) }},
+	)
+
+	for _, ipStr := range s.IPs {
+		steps = append(steps, Step{"Restoring IP " + ipStr, func() error {
+			ip, ipnet, err := net.ParseCIDR(ipStr)
+			if err != nil {
+				return err
+			}
+			ipnet.IP = ip
+			return ifaceUtils.AddIP(s.Iface, ipnet)
+		}})
+	}
+
+	for _, gw := range s.Gateways {
+		steps = append(steps, Step{"Restoring gateway " + gw, func() error {
+			ip := net.ParseIP(gw)
+			if ip == nil {
+				return fmt.Errorf("invalid gateway address format: %s", gw)
+			}
+			return ifaceUtils.AddGateway(s.Iface, ip, s.GatewayMetrics[gw])
+		}})
+	}
+
+	for _, r := range s.Routes {
+		steps = append(steps, Step{"Restoring route " + r.Dst, func() error { return RestoreRoute(s.Iface, r) }})
+	}
+
+	if len(s.DNS) > 0 {
+		steps = append(steps, Step{fmt.Sprintf("Restoring DNS servers %v", s.DNS), func() error {
+			var dnsIPs []net.IP
+			for _, d := range s.DNS {
+				if ip := net.ParseIP(d); ip != nil {
+					dnsIPs = append(dnsIPs, ip)
+				}
+			}
+			return ifaceUtils.SetDNSs(s.Iface, dnsIPs)
+		}})
+	}
+
+	return steps
+}
+
+// 恢复链路层设置，执行时与当前状态相同的设置会跳过，避免无谓地重启网卡
+func (s *Snapshot) linkSteps(ifaceUtils interfaces.Ifaces) []Step {
+	var steps []Step
+	if s.MAC != "" {
+		steps = append(steps, Step{"Restoring MAC address " + s.MAC, func() error {
+			mac, err := net.ParseMAC(s.MAC)
+			if err != nil {
+				return err
+			}
+			d, err := ifaceUtils.GetLinkDetails(s.Iface)
+			if err == nil && d.HardwareAddr.String() == mac.String() {
+				return nil
+			}
+			return ifaceUtils.SetMAC(s.Iface, mac)
+		}})
+	}
+	if s.MTU > 0 {
+		steps = append(steps, Step{fmt.Sprintf("Restoring MTU %d", s.MTU), func() error {
+			d, err := ifaceUtils.GetLinkDetails(s.Iface)
+			if err == nil && d.MTU == s.MTU {
+				return nil
+			}
+			return ifaceUtils.SetMTU(s.Iface, s.MTU)
+		}})
+	}
+	if s.Up != nil {
+		state := "DOWN"
+		if *s.Up {
+			state = "UP"
+		}
+		steps = append(steps, Step{"Restoring link state " + state, func() error {
+			d, err := ifaceUtils.GetLinkDetails(s.Iface)
+			if err == nil && d.Up == *s.Up {
+				return nil
+			}
+			return ifaceUtils.SetLinkState(s.Iface, *s.Up)
+		}})
+	}
+	return steps
+}
+
+// 恢复快照，step 用于报告每一步的结果，可以为 nil
+func (s *Snapshot) Restore(ifaceUtils interfaces.Ifaces, step func(string, error)) error {
+	var firstErr error
+	for _, st := range s.Steps(ifaceUtils) {
+		err := st.Apply()
+		if step != nil {
+			step(st.Desc, err)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", st.Desc, err)
+		}
+	}
+	return firstErr
+}
+
+// 保存快照到文件
+func (s *Snapshot) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// 从文件读取快照，文件不存在时返回 nil
+func Load(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	return &s, nil
+}
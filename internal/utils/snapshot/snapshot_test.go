@@ -0,0 +1,70 @@
+package snapshot
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	"nctl/interfaces"
+	"nctl/internal/utils/fake"
+)
+
+func TestRestoreKeepsAllGateways(t *testing.T) {
+	host := &fake.Host{Gateways: []interfaces.Gateway{{IP: net.ParseIP("192.0.2.254"), Metric: 10}}}
+	s := &Snapshot{
+		Iface:          "eth0",
+		Gateways:       []string{"192.0.2.1", "192.0.2.2", "2001:db8::1"},
+		GatewayMetrics: map[string]int{"192.0.2.1": 100, "192.0.2.2": 200},
+	}
+	if err := s.Restore(host, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 主备两个 ipv4 网关都要恢复，改动后添加的网关被清除
+	want := []string{"192.0.2.1/100", "192.0.2.2/200", "2001:db8::1/0"}
+	var got []string
+	for _, gw := range host.Gateways {
+		got = append(got, fmt.Sprintf("%s/%d", gw.IP, gw.Metric))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("gateways = %v, want %v", got, want)
+	}
+}
+
+func TestRestoreLinkSettingsFirst(t *testing.T) {
+	host := &fake.Host{Details: interfaces.LinkDetails{
+		Up:           false,
+		MTU:          9000,
+		HardwareAddr: net.HardwareAddr{2, 0, 0, 0, 0, 1},
+	}}
+	up := true
+	s := &Snapshot{Iface: "eth0", Up: &up, MTU: 1500, MAC: "02:00:00:00:00:01", IPs: []string{"192.0.2.10/24"}}
+	if err := s.Restore(host, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// MAC 未变化时跳过，链路状态在地址之前恢复
+	want := []string{
+		"SetMTU eth0 1500",
+		"SetLinkState eth0 true",
+		"FlushIPs eth0",
+		"FlushRoutes eth0",
+		"FlushDNS eth0",
+		"AddIP eth0 192.0.2.10/24",
+	}
+	if !reflect.DeepEqual(host.Calls, want) {
+		t.Fatalf("calls = %v, want %v", host.Calls, want)
+	}
+}
+
+func TestTakeRecordsLinkSettings(t *testing.T) {
+	host := &fake.Host{Details: interfaces.LinkDetails{Up: true, MTU: 1500, HardwareAddr: net.HardwareAddr{2, 0, 0, 0, 0, 1}}}
+	s, err := Take(host, "lo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Up == nil || !*s.Up || s.MTU != 1500 || s.MAC != "02:00:00:00:00:01" {
+		t.Fatalf("snapshot up %v mtu %d mac %s", s.Up, s.MTU, s.MAC)
+	}
+}
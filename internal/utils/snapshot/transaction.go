@@ -0,0 +1,190 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"nctl/interfaces"
+	"nctl/internal/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 截止时间之后留给回滚的时间，超过后即使进程号仍然存在也认为标记已遗留
+const staleGrace = time.Minute
+
+// 等待确认的快照存放目录
+func pendingDir() string {
+	return filepath.Join(utils.StateDir(), "pending")
+}
+
+func pendingPath(ifaceName string) string {
+	return filepath.Join(pendingDir(), ifaceName+".json")
+}
+
+// 存在时表示请求等待中的进程回滚
+func rollbackPath(ifaceName string) string {
+	return filepath.Join(pendingDir(), ifaceName+".rollback")
+}
+
+// 等待确认的标记，记录发起事务的进程和确认截止时间，用于识别进程退出后遗留的标记
+type pendingMarker struct {
+	PID      int       `json:"pid"`
+	Deadline time.Time `json:"deadline"`
+	Snapshot *Snapshot `json:"snapshot"`
+}
+
+// 发起事务的进程已退出，或者早已超过截止时间
+func (m *pendingMarker) stale(now time.Time) bool {
+	return !processAlive(m.PID) || now.After(m.Deadline.Add(staleGrace))
+}
+
+func (m *pendingMarker) save() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := os.MkdirAll(pendingDir(), 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	return os.WriteFile(pendingPath(m.Snapshot.Iface), data, 0o644)
+}
+
+// 读取接口上的等待标记，不存在时返回 nil
+func loadMarker(ifaceName string) (*pendingMarker, error) {
+	data, err := os.ReadFile(pendingPath(ifaceName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m pendingMarker
+	if err := json.Unmarshal(data, &m); err != nil || m.Snapshot == nil {
+		return nil, fmt.Errorf("invalid pending snapshot %s", pendingPath(ifaceName))
+	}
+	return &m, nil
+}
+
+// 事务：应用改动前记录快照，超时未确认时自动恢复
+type Transaction struct {
+	Snapshot *Snapshot
+	marker   *pendingMarker
+}
+
+// 记录快照并标记为等待确认，timeout 为确认的期限，warn 的含义与 Take 相同
+func Begin(ifaceUtils interfaces.Ifaces, ifaceName string, timeout time.Duration, warn func(error)) (*Transaction, error) {
+	m, err := loadMarker(ifaceName)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil {
+		if m.stale(time.Now()) {
+			return nil, fmt.Errorf("a change on '%s' was left unconfirmed by process %d, run 'nctl confirm --rollback %s' to restore the saved state or 'nctl confirm %s' to keep the current one",
+				ifaceName, m.PID, ifaceName, ifaceName)
+		}
+		return nil, fmt.Errorf("a change on '%s' is already waiting for confirmation (process %d)", ifaceName, m.PID)
+	}
+
+	s, err := Take(ifaceUtils, ifaceName, warn)
+	if err != nil {
+		return nil, err
+	}
+	m = &pendingMarker{PID: os.Getpid(), Deadline: time.Now().Add(timeout), Snapshot: s}
+	os.Remove(rollbackPath(ifaceName))
+	if err := m.save(); err != nil {
+		return nil, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return &Transaction{Snapshot: s, marker: m}, nil
+}
+
+// 等待 Confirm 删除标记，返回是否已确认；超时、ctx 结束或收到回滚请求时返回 false
+func (t *Transaction) Wait(ctx context.Context, timeout time.Duration) bool {
+	// 应用改动可能耗时较长，从开始等待时重新计算截止时间；写入失败时沿用 Begin 记录的截止时间
+	t.marker.Deadline = time.Now().Add(timeout)
+	t.marker.save()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if _, err := os.Stat(rollbackPath(t.Snapshot.Iface)); err == nil {
+			return false
+		}
+		if _, err := os.Stat(pendingPath(t.Snapshot.Iface)); errors.Is(err, os.ErrNotExist) {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-deadline.C:
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// 恢复快照并清除等待标记
+func (t *Transaction) Rollback(ifaceUtils interfaces.Ifaces, step func(string, error)) error {
+	err := t.Snapshot.Restore(ifaceUtils, step)
+	os.Remove(pendingPath(t.Snapshot.Iface))
+	os.Remove(rollbackPath(t.Snapshot.Iface))
+	return err
+}
+
+// 确认接口上等待中的改动
+func Confirm(ifaceName string) error {
+	err := os.Remove(pendingPath(ifaceName))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no change on '%s' is waiting for confirmation", ifaceName)
+	}
+	os.Remove(rollbackPath(ifaceName))
+	return err
+}
+
+// 放弃接口上等待中的改动：发起事务的进程仍在等待时请求它回滚，返回 false；
+// 标记已遗留时直接恢复其中的快照，返回 true
+func RequestRollback(ifaceUtils interfaces.Ifaces, ifaceName string, step func(string, error)) (bool, error) {
+	m, err := loadMarker(ifaceName)
+	if err != nil {
+		return false, err
+	}
+	if m == nil {
+		return false, fmt.Errorf("no change on '%s' is waiting for confirmation", ifaceName)
+	}
+
+	if !m.stale(time.Now()) {
+		if err := os.WriteFile(rollbackPath(ifaceName), nil, 0o644); err != nil {
+			return false, fmt.Errorf("failed to request rollback: %w", err)
+		}
+		return false, nil
+	}
+
+	tx := &Transaction{Snapshot: m.Snapshot, marker: m}
+	return true, tx.Rollback(ifaceUtils, step)
+}
+
+// 列出所有等待确认的接口
+func Pending() ([]string, error) {
+	entries, err := os.ReadDir(pendingDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".json"); ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,37 @@
+package snapshot
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestMarkerStale(t *testing.T) {
+	// 已退出进程的进程号
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	exited := cmd.Process.Pid
+
+	now := time.Now()
+	tests := []struct {
+		name     string
+		pid      int
+		deadline time.Time
+		want     bool
+	}{
+		{"waiting", os.Getpid(), now.Add(time.Minute), false},
+		{"rolling back after deadline", os.Getpid(), now.Add(-staleGrace / 2), false},
+		{"long past deadline", os.Getpid(), now.Add(-2 * staleGrace), true},
+		{"process exited", exited, now.Add(time.Minute), true},
+		{"no pid", 0, now.Add(time.Minute), true},
+	}
+	for _, tt := range tests {
+		m := &pendingMarker{PID: tt.pid, Deadline: tt.deadline}
+		if got := m.stale(now); got != tt.want {
+			t.Errorf("%s: stale = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
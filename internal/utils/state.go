@@ -0,0 +1,15 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// nctl 持久化状态（基线、租约、快照）的存放目录
+func StateDir() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "nctl")
+	}
+	return "/var/lib/nctl"
+}
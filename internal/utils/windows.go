@@ -13,3 +13,28 @@ import (
 func IfaceUtils() interfaces.Ifaces {
 	return windows.Iface()
 }
+
+// 返回关于路由操作的工厂函数
+func RouteUtils() interfaces.Routes {
+	return windows.Route()
+}
+
+// 返回关于邻居表操作的工厂函数
+func NeighUtils() interfaces.Neighs {
+	return windows.Neigh()
+}
+
+// 返回关于套接字查询的工厂函数
+func SocketUtils() interfaces.Sockets {
+	return windows.Socket()
+}
+
+// 返回关于代理设置的工厂函数
+func ProxyUtils() interfaces.Proxies {
+	return windows.Proxy()
+}
+
+// 返回关于 WireGuard 接口操作的工厂函数
+func WireGuardUtils() interfaces.WireGuards {
+	return windows.WireGuard()
+}
@@ -0,0 +1,76 @@
+//go:build windows
+
+package windows
+
+import (
+	"nctl/interfaces"
+	"net"
+
+	"golang.org/x/sys/windows"
+)
+
+// IF_OPER_STATUS 对应的名称
+var operStatusNames = map[uint32]string{
+	1: "up",
+	2: "down",
+	3: "testing",
+	4: "unknown",
+	5: "dormant",
+	6: "notpresent",
+	7: "lowerlayerdown",
+}
+
+// IANA ifType 对应的名称
+var ifTypeNames = map[uint32]string{
+	6:   "ethernet",
+	24:  "loopback",
+	53:  "proprietary-virtual",
+	71:  "wireless",
+	131: "tunnel",
+	144: "ieee1394",
+}
+
+// 获取接口的链路层详情
+func (w *WindowsNctl) GetLinkDetails(iface string) (*interfaces.LinkDetails, error) {
+	aa, err := findAdapter(iface, windows.GAA_FLAG_INCLUDE_ALL_INTERFACES)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &interfaces.LinkDetails{
+		Up:        aa.OperStatus != windows.IfOperStatusDown,
+		MTU:       int(aa.Mtu),
+		OperState: operStatusNames[aa.OperStatus],
+		Carrier:   aa.OperStatus == windows.IfOperStatusUp,
+		Type:      ifTypeNames[aa.IfType],
+		Driver:    windows.UTF16PtrToString(aa.Description),
+	}
+	if d.Type == "" {
+		d.Type = "other"
+	}
+	// 链路速率单位为 bit/s，未知时为 ^uint64(0)
+	if aa.TransmitLinkSpeed != 0 && aa.TransmitLinkSpeed != ^uint64(0) {
+		d.Speed = int(aa.TransmitLinkSpeed / 1000000)
+	}
+
+	row := windows.MibIfRow2{InterfaceIndex: aa.IfIndex}
+	if aa.PhysicalAddressLength > 0 {
+		d.HardwareAddr = net.HardwareAddr(append([]byte(nil), aa.PhysicalAddress[:aa.PhysicalAddressLength]...))
+	}
+	if err := windows.GetIfEntry2Ex(windows.MibIfEntryNormal, &row); err == nil {
+		// NET_IF_ADMIN_STATUS_UP
+		d.Up = row.AdminStatus == 1
+		d.Stats = interfaces.LinkStats{
+			RxBytes:   row.InOctets,
+			RxPackets: row.InUcastPkts + row.InNUcastPkts,
+			RxErrors:  row.InErrors,
+			RxDropped: row.InDiscards,
+			TxBytes:   row.OutOctets,
+			TxPackets: row.OutUcastPkts + row.OutNUcastPkts,
+			TxErrors:  row.OutErrors,
+			TxDropped: row.OutDiscards,
+		}
+	}
+
+	return d, nil
+}
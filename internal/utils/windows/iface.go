@@ -5,6 +5,7 @@ package windows
 import (
 	"encoding/binary"
 	"fmt"
+	"nctl/interfaces"
 	"net"
 	"os"
 	"os/exec"
@@ -14,8 +15,8 @@ import (
 	"golang.org/x/sys/windows"
 )
 
-// 检查接口名称的存在性
-func (w *WindowsNctl) IsExistingIface(iface string) error {
+// 获取所有网卡的 IP_ADAPTER_ADDRESSES 链表
+func getAdapters(flags uint32) (*windows.IpAdapterAddresses, error) {
 	var b []byte
 	// 设置初始缓冲区为 1k
 	l := uint32(1024)
@@ -23,30 +24,45 @@ func (w *WindowsNctl) IsExistingIface(iface string) error {
 	for {
 		b = make([]byte, l)
 		size := l
-		err := windows.GetAdaptersAddresses(syscall.AF_UNSPEC, windows.GAA_FLAG_INCLUDE_ALL_INTERFACES, 0, (*windows.IpAdapterAddresses)(unsafe.Pointer(&b[0])), &size)
+		err := windows.GetAdaptersAddresses(syscall.AF_UNSPEC, flags, 0, (*windows.IpAdapterAddresses)(unsafe.Pointer(&b[0])), &size)
 		if err == nil {
 			break
 		}
 		if err.(syscall.Errno) != syscall.ERROR_BUFFER_OVERFLOW {
-			return fmt.Errorf("unexpected code errors [internal/utils/ifaces_windows.go: 30]: %w", err)
+			return nil, fmt.Errorf("unexpected code errors [internal/utils/windows/iface.go: getAdapters]: %w", err)
 		}
 
 		// 缓冲区太小，设置翻倍，但总大小不允许超过 128k
 		l *= 2
 		const maxBufferSize = 128 * 1024
 		if l > maxBufferSize {
-			return fmt.Errorf("Failed to allocate buffer, size exceeds %dKB", maxBufferSize)
+			return nil, fmt.Errorf("Failed to allocate buffer, size exceeds %dKB", maxBufferSize)
 		}
 	}
 
-	for aa := (*windows.IpAdapterAddresses)(unsafe.Pointer(&b[0])); aa != nil; aa = aa.Next {
-		friendlyName := windows.UTF16PtrToString(aa.FriendlyName)
-		if friendlyName == iface {
-			return nil
+	return (*windows.IpAdapterAddresses)(unsafe.Pointer(&b[0])), nil
+}
+
+// 根据友好名称查找网卡
+func findAdapter(iface string, flags uint32) (*windows.IpAdapterAddresses, error) {
+	first, err := getAdapters(flags)
+	if err != nil {
+		return nil, err
+	}
+
+	for aa := first; aa != nil; aa = aa.Next {
+		if windows.UTF16PtrToString(aa.FriendlyName) == iface {
+			return aa, nil
 		}
 	}
 
-	return fmt.Errorf("network interface '%s' does not exist", iface)
+	return nil, fmt.Errorf("network interface '%s' does not exist", iface)
+}
+
+// 检查接口名称的存在性
+func (w *WindowsNctl) IsExistingIface(iface string) error {
+	_, err := findAdapter(iface, windows.GAA_FLAG_INCLUDE_ALL_INTERFACES)
+	return err
 }
 
 // 转化接口的 Index 和 LUID
@@ -260,56 +276,65 @@ func (w *WindowsNctl) DelDNS(iface string, dnsIP net.IP) error {
 	return nil
 }
 
-// 设置默认网关
-func (w *WindowsNctl) SetGateway(iface string, gateway net.IP) error {
-	ifa, err := findIface(iface)
-	if err != nil {
-		return err
-	}
-
+// 设置默认网关，只替换该接口上同一地址族的默认路由
+func (w *WindowsNctl) SetGateway(iface string, gateway net.IP, metric int) error {
+	family := 4
+	dst := &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
 	if gateway.To4() == nil {
-		return fmt.Errorf("setting IPv6 gateway is not implemented")
+		family = 6
+		dst = &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
 	}
 
-	gatewayIP, err := ipToUint32(gateway)
+	old, err := w.defaultRoutes(iface, family)
 	if err != nil {
 		return err
 	}
-
-	var size uint32
-	w.getIpForwardTable.Call(0, uintptr(unsafe.Pointer(&size)), 1)
-	if size > 0 {
-		buffer := make([]byte, size)
-		r1, _, _ := w.getIpForwardTable.Call(uintptr(unsafe.Pointer(&buffer[0])), uintptr(unsafe.Pointer(&size)), 1)
-		if r1 == 0 { // NO_ERROR
-			numEntries := *(*uint32)(unsafe.Pointer(&buffer[0]))
-			table := unsafe.Slice((*MIB_IPFORWARDROW)(unsafe.Pointer(&buffer[4])), numEntries)
-			for _, row := range table {
-				// 检查是否是默认路由 (Dest=0.0.0.0) 并且是同一个接口
-				if row.DwForwardDest == 0 && row.DwForwardIfIndex == uint32(ifa.Index) {
-					fmt.Printf("deleting existing default gateway on interface '%s'\n", iface)
-					w.deleteIpForwardEntry.Call(uintptr(unsafe.Pointer(&row)))
-				}
-			}
+	for _, r := range old {
+		if err := w.DelRoute(&r); err != nil {
+			return fmt.Errorf("failed to delete old default gateway %s: %w", r.Gateway, err)
 		}
 	}
 
-	row := MIB_IPFORWARDROW{
-		DwForwardDest:    0,
-		DwForwardMask:    0,
-		DwForwardNextHop: gatewayIP,
-		DwForwardIfIndex: uint32(ifa.Index),
-		DwForwardProto:   3,
-		DwForwardType:    4,
-		DwForwardMetric1: 25,
+	if err := w.AddRoute(&interfaces.Route{Dst: dst, Gateway: gateway, Iface: iface, Metric: metric}); err != nil {
+		for _, r := range old {
+			w.AddRoute(&r)
+		}
+		return fmt.Errorf("failed to set gateway: %w", err)
 	}
+	return nil
+}
 
-	r1, _, err := w.createUnicastIpAddressEntry.Call(uintptr(unsafe.Pointer(&row)))
-	if r1 != 0 {
-		return fmt.Errorf("CreateIpForwardEntry failed [ifaces_setip: 271]: %w", err)
+// 添加一条默认路由，接口上已有的默认网关保持不变
+func (w *WindowsNctl) AddGateway(iface string, gateway net.IP, metric int) error {
+	dst := &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
+	if gateway.To4() == nil {
+		dst = &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
 	}
+	if err := w.AddRoute(&interfaces.Route{Dst: dst, Gateway: gateway, Iface: iface, Metric: metric}); err != nil {
+		return fmt.Errorf("failed to add gateway %s: %w", gateway, err)
+	}
+	return nil
+}
 
-	fmt.Fprintf(os.Stdout, "set defult gateway to '%s' on '%s' successful\n", gateway.String(), iface)
+// 删除接口上经由该网关的默认路由
+func (w *WindowsNctl) DelGateway(iface string, gateway net.IP) error {
+	routes, err := w.defaultRoutes(iface, 0)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, r := range routes {
+		if !r.Gateway.Equal(gateway) {
+			continue
+		}
+		if err := w.DelRoute(&r); err != nil {
+			return fmt.Errorf("failed to delete default gateway %s: %w", gateway, err)
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("gateway %s is not configured on %s", gateway, iface)
+	}
 	return nil
 }
 
@@ -0,0 +1,90 @@
+//go:build windows
+
+package windows
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+)
+
+// 执行 netsh 命令
+func runNetsh(args ...string) error {
+	cmd := exec.Command("netsh", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("netsh failed: %v\nOutput: %s", err, string(output))
+	}
+	return nil
+}
+
+// 启用或禁用接口，通常需要管理员权限
+func (w *WindowsNctl) SetLinkState(iface string, up bool) error {
+	action := "DISABLED"
+	if up {
+		action = "ENABLED"
+	}
+	return runNetsh("interface", "set", "interface", iface, action)
+}
+
+// windows 上修改 mac 需要写入驱动的注册表项，暂不支持
+func (w *WindowsNctl) SetMAC(iface string, mac net.HardwareAddr) error {
+	return fmt.Errorf("setting MAC address is not implemented on Windows")
+}
+
+// 设置 mtu
+func (w *WindowsNctl) SetMTU(iface string, mtu int) error {
+	for _, ipVersion := range []string{"ipv4", "ipv6"} {
+		if err := runNetsh("interface", ipVersion, "set", "subinterface", iface, fmt.Sprintf("mtu=%d", mtu), "store=persistent"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *WindowsNctl) SetPromisc(iface string, enable bool) error {
+	return fmt.Errorf("setting promiscuous mode is not supported on Windows")
+}
+
+func (w *WindowsNctl) SetARP(iface string, enable bool) error {
+	return fmt.Errorf("toggling ARP is not supported on Windows")
+}
+
+func (w *WindowsNctl) SetAlias(iface string, alias string) error {
+	return fmt.Errorf("setting interface description is not supported on Windows")
+}
+
+// 通过解除 ms_tcpip6 绑定禁用 ipv6
+func (w *WindowsNctl) SetIPv6(iface string, enable bool) error {
+	action := "Disable-NetAdapterBinding"
+	if enable {
+		action = "Enable-NetAdapterBinding"
+	}
+	cmd := exec.Command("powershell", "-NoProfile", "-Command", action, "-Name", fmt.Sprintf(`'%s'`, iface), "-ComponentID", "ms_tcpip6")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %v\nOutput: %s", action, err, string(output))
+	}
+	return nil
+}
+
+func (w *WindowsNctl) SetLinkMode(iface string, speed int, duplex string) error {
+	return fmt.Errorf("setting speed and duplex is not supported on Windows")
+}
+
+func (w *WindowsNctl) AddVLAN(iface string, id int) error {
+	return fmt.Errorf("creating VLAN interfaces is not supported on Windows")
+}
+
+// 设置接口的 hop limit
+func (w *WindowsNctl) SetTTL(iface string, ttl int) error {
+	if ttl < 1 || ttl > 255 {
+		return fmt.Errorf("TTL must be between 1 and 255, got %d", ttl)
+	}
+	for _, ipVersion := range []string{"ipv4", "ipv6"} {
+		if err := runNetsh("interface", ipVersion, "set", "interface", iface, fmt.Sprintf("currenthoplimit=%d", ttl)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
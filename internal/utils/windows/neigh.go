@@ -0,0 +1,218 @@
+//go:build windows
+
+package windows
+
+import (
+	"encoding/binary"
+	"fmt"
+	"nctl/interfaces"
+	"net"
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	procGetIpNetTable2    = modiphlpapi.NewProc("GetIpNetTable2")
+	procCreateIpNetEntry2 = modiphlpapi.NewProc("CreateIpNetEntry2")
+	procDeleteIpNetEntry2 = modiphlpapi.NewProc("DeleteIpNetEntry2")
+)
+
+// MIB_IPNET_ROW2
+type MIB_IPNET_ROW2 struct {
+	Address               SOCKADDR_INET
+	InterfaceIndex        uint32
+	InterfaceLuid         NET_LUID
+	PhysicalAddress       [32]byte
+	PhysicalAddressLength uint32
+	State                 uint32
+	// 第 0 位为 IsRouter，第 1 位为 IsUnreachable
+	Flags            uint8
+	_                [3]byte
+	ReachabilityTime uint32
+}
+
+// NL_NEIGHBOR_STATE 对应的名称，与 linux 上的状态名保持一致
+var neighStateNames = map[uint32]string{
+	0: "FAILED",
+	1: "INCOMPLETE",
+	2: "PROBE",
+	3: "DELAY",
+	4: "STALE",
+	5: "REACHABLE",
+	6: "PERMANENT",
+}
+
+const nlNeighborStatePermanent = 6
+
+func neighStateName(state uint32) string {
+	if s, ok := neighStateNames[state]; ok {
+		return s
+	}
+	return strconv.Itoa(int(state))
+}
+
+// 读取整个邻居表
+func getNetTable(family uint16) ([]MIB_IPNET_ROW2, error) {
+	var table unsafe.Pointer
+	r1, _, _ := procGetIpNetTable2.Call(uintptr(family), uintptr(unsafe.Pointer(&table)))
+	if r1 != 0 {
+		return nil, fmt.Errorf("GetIpNetTable2 failed: %w", windows.Errno(r1))
+	}
+	defer procFreeMibTable.Call(uintptr(table))
+
+	// MIB_IPNET_TABLE2 的行数组按 8 字节对齐
+	numEntries := binary.LittleEndian.Uint32((*[4]byte)(table)[:])
+	rows := unsafe.Slice((*MIB_IPNET_ROW2)(unsafe.Add(table, 8)), numEntries)
+	return append([]MIB_IPNET_ROW2(nil), rows...), nil
+}
+
+func fromNetRow(row *MIB_IPNET_ROW2) interfaces.Neigh {
+	n := interfaces.Neigh{
+		Family: 4,
+		IP:     sockaddrToIP(&row.Address),
+		State:  neighStateName(row.State),
+		Router: row.Flags&1 != 0,
+	}
+	if row.Address.Family == windows.AF_INET6 {
+		n.Family = 6
+	}
+	if l := row.PhysicalAddressLength; l > 0 && l <= uint32(len(row.PhysicalAddress)) {
+		n.MAC = net.HardwareAddr(append([]byte(nil), row.PhysicalAddress[:l]...))
+	}
+	if ifi, err := net.InterfaceByIndex(int(row.InterfaceIndex)); err == nil {
+		n.Iface = ifi.Name
+	} else {
+		n.Iface = strconv.Itoa(int(row.InterfaceIndex))
+	}
+	return n
+}
+
+func (w *WindowsNctl) ListNeighs(filter interfaces.NeighFilter) ([]interfaces.Neigh, error) {
+	family := uint16(windows.AF_UNSPEC)
+	switch filter.Family {
+	case 0:
+	case 4:
+		family = windows.AF_INET
+	case 6:
+		family = windows.AF_INET6
+	default:
+		return nil, fmt.Errorf("invalid address family %d (value: 4, 6)", filter.Family)
+	}
+	if filter.Iface != "" {
+		if _, err := findIface(filter.Iface); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := getNetTable(family)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []interfaces.Neigh
+	for _, row := range rows {
+		n := fromNetRow(&row)
+		if filter.Iface != "" && n.Iface != filter.Iface {
+			continue
+		}
+		if filter.State != "" && n.State != filter.State {
+			continue
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+// 转换为 MIB_IPNET_ROW2，接口必填
+func toNetRow(n *interfaces.Neigh) (*MIB_IPNET_ROW2, error) {
+	if n.Iface == "" {
+		return nil, fmt.Errorf("interface is required")
+	}
+	ifa, err := findIface(n.Iface)
+	if err != nil {
+		return nil, err
+	}
+
+	var row MIB_IPNET_ROW2
+	row.InterfaceIndex = uint32(ifa.Index)
+	ipToSockaddr(n.IP, &row.Address)
+	row.PhysicalAddressLength = uint32(copy(row.PhysicalAddress[:], n.MAC))
+	return &row, nil
+}
+
+// windows 上只能添加静态邻居，已存在时先删除再添加
+func (w *WindowsNctl) AddNeigh(n *interfaces.Neigh) error {
+	if n.State != "" && n.State != "PERMANENT" {
+		return fmt.Errorf("only permanent neighbors can be added on Windows")
+	}
+	if n.MAC == nil {
+		return fmt.Errorf("link layer address is required")
+	}
+	row, err := toNetRow(n)
+	if err != nil {
+		return err
+	}
+	row.State = nlNeighborStatePermanent
+
+	procDeleteIpNetEntry2.Call(uintptr(unsafe.Pointer(row)))
+	if r1, _, _ := procCreateIpNetEntry2.Call(uintptr(unsafe.Pointer(row))); r1 != 0 {
+		return fmt.Errorf("failed to add neighbor %s: %w", n.IP, windows.Errno(r1))
+	}
+	return nil
+}
+
+func (w *WindowsNctl) DelNeigh(n *interfaces.Neigh) error {
+	targets := []interfaces.Neigh{*n}
+	if n.Iface == "" {
+		targets = nil
+		neighs, err := w.ListNeighs(interfaces.NeighFilter{})
+		if err != nil {
+			return err
+		}
+		for _, item := range neighs {
+			if item.IP.Equal(n.IP) {
+				targets = append(targets, item)
+			}
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("neighbor %s not found", n.IP)
+		}
+	}
+
+	for _, t := range targets {
+		row, err := toNetRow(&t)
+		if err != nil {
+			return err
+		}
+		if r1, _, _ := procDeleteIpNetEntry2.Call(uintptr(unsafe.Pointer(row))); r1 != 0 {
+			return fmt.Errorf("failed to delete neighbor %s on %s: %w", t.IP, t.Iface, windows.Errno(r1))
+		}
+	}
+	return nil
+}
+
+// 逐条删除，与 linux 一致，未指定状态时保留静态邻居
+func (w *WindowsNctl) FlushNeighs(filter interfaces.NeighFilter) (int, error) {
+	neighs, err := w.ListNeighs(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, n := range neighs {
+		if filter.State == "" && n.State == "PERMANENT" {
+			continue
+		}
+		row, err := toNetRow(&n)
+		if err != nil {
+			return count, err
+		}
+		if r1, _, _ := procDeleteIpNetEntry2.Call(uintptr(unsafe.Pointer(row))); r1 != 0 {
+			return count, fmt.Errorf("failed to delete neighbor %s on %s: %w", n.IP, n.Iface, windows.Errno(r1))
+		}
+		count++
+	}
+	return count, nil
+}
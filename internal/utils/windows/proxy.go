@@ -0,0 +1,222 @@
+//go:build windows
+
+package windows
+
+import (
+	"errors"
+	"fmt"
+	"nctl/interfaces"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// WinINET 的代理设置，HKLM 下的设置在策略 ProxySettingsPerUser 为 0 时生效
+const internetSettingsKey = `Software\Microsoft\Windows\CurrentVersion\Internet Settings`
+
+var (
+	modwininet             = windows.NewLazySystemDLL("wininet.dll")
+	procInternetSetOptionW = modwininet.NewProc("InternetSetOptionW")
+)
+
+// 通知已运行的程序重新读取代理设置
+const (
+	internetOptionRefresh         = 37
+	internetOptionSettingsChanged = 39
+)
+
+func proxyRoot(scope string) (registry.Key, string, error) {
+	switch scope {
+	case interfaces.ProxyScopeSystem:
+		return registry.LOCAL_MACHINE, `HKLM\` + internetSettingsKey, nil
+	case interfaces.ProxyScopeUser:
+		return registry.CURRENT_USER, `HKCU\` + internetSettingsKey, nil
+	}
+	return 0, "", fmt.Errorf("unknown proxy scope '%s'", scope)
+}
+
+// ProxyServer 可以是 host:port（所有协议共用），也可以是 http=host:port;https=host:port;socks=host:port，
+// socks 代理用于没有单独设置代理的协议
+func parseProxyServer(server string) (httpProxy, httpsProxy string) {
+	withScheme := func(addr string) string {
+		if addr == "" || strings.Contains(addr, "://") {
+			return addr
+		}
+		return "http://" + addr
+	}
+	if !strings.Contains(server, "=") {
+		addr := withScheme(strings.TrimSpace(server))
+		return addr, addr
+	}
+	socks := ""
+	for _, part := range strings.Split(server, ";") {
+		proto, addr, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(proto) {
+		case "http":
+			httpProxy = withScheme(addr)
+		case "https":
+			httpsProxy = withScheme(addr)
+		case "socks":
+			socks = "socks5://" + addr
+		}
+	}
+	if httpProxy == "" {
+		httpProxy = socks
+	}
+	if httpsProxy == "" {
+		httpsProxy = socks
+	}
+	return httpProxy, httpsProxy
+}
+
+// WinINET 只接受 host:port，http 代理去掉协议前缀，socks5 代理写成 socks=host:port。
+// 注册表中的值以明文保存，WinINET 也不会读取其中的用户名和密码，因此不接受带凭据的地址
+func formatProxyServer(p *interfaces.ProxySettings) (string, error) {
+	entry := func(proto, addr string) (string, error) {
+		u, err := url.Parse(addr)
+		if err != nil || u.Host == "" {
+			return "", fmt.Errorf("invalid proxy address '%s'", addr)
+		}
+		if u.User != nil {
+			return "", fmt.Errorf("proxy credentials cannot be stored in the WinINET settings, remove them from '%s'", u.Redacted())
+		}
+		switch u.Scheme {
+		case "http":
+			if proto == "" {
+				return u.Host, nil
+			}
+			return proto + "=" + u.Host, nil
+		case "socks5", "socks5h":
+			return "socks=" + u.Host, nil
+		}
+		return "", fmt.Errorf("unsupported proxy scheme '%s' on windows (value: http, socks5, socks5h)", u.Scheme)
+	}
+
+	if p.HTTP == p.HTTPS {
+		if p.HTTP == "" {
+			return "", nil
+		}
+		return entry("", p.HTTP)
+	}
+	var parts []string
+	for _, e := range []struct{ proto, addr string }{{"http", p.HTTP}, {"https", p.HTTPS}} {
+		if e.addr == "" {
+			continue
+		}
+		part, err := entry(e.proto, e.addr)
+		if err != nil {
+			return "", err
+		}
+		// 两个协议使用同一个 socks 代理时只写一次
+		if !slices.Contains(parts, part) {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+func notifyProxyChanged() {
+	procInternetSetOptionW.Call(0, internetOptionSettingsChanged, 0, 0)
+	procInternetSetOptionW.Call(0, internetOptionRefresh, 0, 0)
+}
+
+func (w *WindowsNctl) GetProxy(scope string) (*interfaces.ProxySettings, error) {
+	root, source, err := proxyRoot(scope)
+	if err != nil {
+		return nil, err
+	}
+	k, err := registry.OpenKey(root, internetSettingsKey, registry.QUERY_VALUE)
+	if errors.Is(err, registry.ErrNotExist) {
+		return &interfaces.ProxySettings{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+
+	// ProxyEnable 为 0 时 ProxyServer 中残留的地址不生效
+	if enable, _, err := k.GetIntegerValue("ProxyEnable"); err != nil || enable == 0 {
+		return &interfaces.ProxySettings{}, nil
+	}
+	p := &interfaces.ProxySettings{Source: source}
+	if server, _, err := k.GetStringValue("ProxyServer"); err == nil {
+		p.HTTP, p.HTTPS = parseProxyServer(server)
+	}
+	if override, _, err := k.GetStringValue("ProxyOverride"); err == nil {
+		for _, host := range strings.Split(override, ";") {
+			if host = strings.TrimSpace(host); host != "" {
+				p.NoProxy = append(p.NoProxy, host)
+			}
+		}
+	}
+	return p, nil
+}
+
+func (w *WindowsNctl) SetProxy(scope string, p *interfaces.ProxySettings) error {
+	root, source, err := proxyRoot(scope)
+	if err != nil {
+		return err
+	}
+	// 先检查地址，不支持的设置不会写入注册表
+	server, err := formatProxyServer(p)
+	if err != nil {
+		return err
+	}
+	k, _, err := registry.CreateKey(root, internetSettingsKey, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", source, err)
+	}
+	defer k.Close()
+
+	if err := k.SetStringValue("ProxyServer", server); err != nil {
+		return err
+	}
+	if len(p.NoProxy) > 0 {
+		err = k.SetStringValue("ProxyOverride", strings.Join(p.NoProxy, ";"))
+	} else {
+		err = k.DeleteValue("ProxyOverride")
+		if errors.Is(err, registry.ErrNotExist) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err := k.SetDWordValue("ProxyEnable", 1); err != nil {
+		return err
+	}
+	notifyProxyChanged()
+	return nil
+}
+
+func (w *WindowsNctl) UnsetProxy(scope string) error {
+	root, source, err := proxyRoot(scope)
+	if err != nil {
+		return err
+	}
+	k, err := registry.OpenKey(root, internetSettingsKey, registry.SET_VALUE)
+	if errors.Is(err, registry.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", source, err)
+	}
+	defer k.Close()
+
+	if err := k.SetDWordValue("ProxyEnable", 0); err != nil {
+		return err
+	}
+	for _, name := range []string{"ProxyServer", "ProxyOverride"} {
+		if err := k.DeleteValue(name); err != nil && !errors.Is(err, registry.ErrNotExist) {
+			return err
+		}
+	}
+	notifyProxyChanged()
+	return nil
+}
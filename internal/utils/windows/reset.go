@@ -0,0 +1,180 @@
+//go:build windows
+
+package windows
+
+import (
+	"fmt"
+	"nctl/interfaces"
+	"nctl/internal/utils/dhcp"
+	"net"
+	"os/exec"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// 获取接口上的 ip，不包含 link-local 地址
+func (w *WindowsNctl) GetIPs(iface string) ([]*net.IPNet, error) {
+	ifa, err := findIface(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := ifa.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses for '%s': %w", iface, err)
+	}
+
+	var ipnets []*net.IPNet
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || ipnet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		ipnets = append(ipnets, ipnet)
+	}
+	return ipnets, nil
+}
+
+// IP_PREFIX_ORIGIN 中的 IpPrefixOriginDhcp 和 IpPrefixOriginRouterAdvertisement
+const (
+	prefixOriginDhcp                = 3
+	prefixOriginRouterAdvertisement = 4
+)
+
+// 获取接口上由 dhcp 或路由通告获取的地址
+func (w *WindowsNctl) GetDynamicIPs(iface string) ([]*net.IPNet, error) {
+	aa, err := findAdapter(iface, windows.GAA_FLAG_INCLUDE_ALL_INTERFACES)
+	if err != nil {
+		return nil, err
+	}
+
+	var ipnets []*net.IPNet
+	for ua := aa.FirstUnicastAddress; ua != nil; ua = ua.Next {
+		if ua.PrefixOrigin != prefixOriginDhcp && ua.PrefixOrigin != prefixOriginRouterAdvertisement {
+			continue
+		}
+		ip := ua.Address.IP()
+		if ip == nil || ip.IsLinkLocalUnicast() {
+			continue
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		ipnets = append(ipnets, &net.IPNet{IP: ip, Mask: net.CIDRMask(int(ua.OnLinkPrefixLength), bits)})
+	}
+	return ipnets, nil
+}
+
+// 获取接口上配置的 dns
+func (w *WindowsNctl) GetDNSs(iface string) ([]net.IP, error) {
+	aa, err := findAdapter(iface, windows.GAA_FLAG_INCLUDE_ALL_INTERFACES)
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []net.IP
+	for dns := aa.FirstDnsServerAddress; dns != nil; dns = dns.Next {
+		if ip := dns.Address.IP(); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// 获取接口上的默认网关
+func (w *WindowsNctl) GetGateways(iface string) ([]interfaces.Gateway, error) {
+	routes, err := w.defaultRoutes(iface, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var gws []interfaces.Gateway
+	for _, r := range routes {
+		gws = append(gws, interfaces.Gateway{IP: r.Gateway, Metric: r.Metric})
+	}
+	return gws, nil
+}
+
+// 删除接口上所有静态地址，原生实现过于复杂，调用系统命令实现
+func (w *WindowsNctl) FlushIPs(iface string) error {
+	ipnets, err := w.GetIPs(iface)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, ipnet := range ipnets {
+		ipVersion := "ipv4"
+		if ipnet.IP.To4() == nil {
+			ipVersion = "ipv6"
+		}
+		cmd := exec.Command("netsh", "interface", ipVersion, "delete", "address", fmt.Sprintf(`name="%s"`, iface), fmt.Sprintf("address=%s", ipnet.IP.String()))
+		if output, err := cmd.CombinedOutput(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to delete address %s using netsh: %v\nOutput: %s", ipnet.String(), err, string(output))
+		}
+	}
+	return firstErr
+}
+
+// 删除接口上所有非本地产生的 ipv4 路由
+func (w *WindowsNctl) FlushRoutes(iface string) error {
+	ifa, err := findIface(iface)
+	if err != nil {
+		return err
+	}
+
+	var size uint32
+	w.getIpForwardTable.Call(0, uintptr(unsafe.Pointer(&size)), 1)
+	if size == 0 {
+		return nil
+	}
+
+	buffer := make([]byte, size)
+	r1, _, _ := w.getIpForwardTable.Call(uintptr(unsafe.Pointer(&buffer[0])), uintptr(unsafe.Pointer(&size)), 1)
+	if r1 != 0 {
+		return fmt.Errorf("GetIpForwardTable failed [utils/windows/reset.go: FlushRoutes]: %w", windows.Errno(r1))
+	}
+
+	numEntries := *(*uint32)(unsafe.Pointer(&buffer[0]))
+	table := unsafe.Slice((*MIB_IPFORWARDROW)(unsafe.Pointer(&buffer[4])), numEntries)
+
+	var firstErr error
+	for _, row := range table {
+		// MIB_IPPROTO_LOCAL(2) 是系统根据地址自动生成的路由
+		if row.DwForwardIfIndex != uint32(ifa.Index) || row.DwForwardProto == 2 {
+			continue
+		}
+		r1, _, _ := w.deleteIpForwardEntry.Call(uintptr(unsafe.Pointer(&row)))
+		if r1 != 0 && firstErr == nil {
+			firstErr = fmt.Errorf("DeleteIpForwardEntry failed: %w", windows.Errno(r1))
+		}
+	}
+	return firstErr
+}
+
+// 清除接口上的静态 dns
+func (w *WindowsNctl) FlushDNS(iface string) error {
+	for _, ipVersion := range []string{"ipv4", "ipv6"} {
+		cmd := exec.Command("netsh", "interface", ipVersion, "set", "dnsserver", fmt.Sprintf(`name="%s"`, iface), "source=dhcp")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to reset %s DNS using netsh: %v\nOutput: %s", ipVersion, err, string(output))
+		}
+	}
+	return nil
+}
+
+// 将接口切换到 dhcp 并重新获取地址
+func (w *WindowsNctl) ObtainDHCP(iface string) (*dhcp.Lease, error) {
+	cmd := exec.Command("netsh", "interface", "ipv4", "set", "address", fmt.Sprintf(`name="%s"`, iface), "source=dhcp")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to enable DHCP using netsh: %v\nOutput: %s", err, string(output))
+	}
+
+	cmd = exec.Command("ipconfig", "/renew", iface)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to renew DHCP lease: %v\nOutput: %s", err, string(output))
+	}
+	return nil, nil
+}
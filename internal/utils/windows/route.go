@@ -0,0 +1,298 @@
+//go:build windows
+
+package windows
+
+import (
+	"encoding/binary"
+	"fmt"
+	"nctl/interfaces"
+	"net"
+	"sort"
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modiphlpapi = windows.NewLazySystemDLL("iphlpapi.dll")
+
+	procGetIpForwardTable2       = modiphlpapi.NewProc("GetIpForwardTable2")
+	procFreeMibTable             = modiphlpapi.NewProc("FreeMibTable")
+	procInitializeIpForwardEntry = modiphlpapi.NewProc("InitializeIpForwardEntry")
+	procCreateIpForwardEntry2    = modiphlpapi.NewProc("CreateIpForwardEntry2")
+	procDeleteIpForwardEntry2    = modiphlpapi.NewProc("DeleteIpForwardEntry2")
+	procGetBestRoute2            = modiphlpapi.NewProc("GetBestRoute2")
+)
+
+// MIB_IPFORWARD_ROW2，SOCKADDR_INET 在 C 中按 4 字节对齐，手动补齐填充
+type MIB_IPFORWARD_ROW2 struct {
+	InterfaceLuid        NET_LUID
+	InterfaceIndex       uint32
+	DestinationPrefix    SOCKADDR_INET
+	PrefixLength         uint8
+	_                    [3]byte
+	NextHop              SOCKADDR_INET
+	SitePrefixLength     uint8
+	_                    [3]byte
+	ValidLifetime        uint32
+	PreferredLifetime    uint32
+	Metric               uint32
+	Protocol             uint32
+	Loopback             bool
+	AutoconfigureAddress bool
+	Publish              bool
+	Immortal             bool
+	Age                  uint32
+	Origin               uint32
+}
+
+// NL_ROUTE_PROTOCOL 对应的名称，与 linux 上的协议名保持一致
+var routeProtocolNames = map[uint32]string{
+	1:     "unspec",
+	2:     "kernel",
+	3:     "static",
+	4:     "redirect",
+	8:     "rip",
+	13:    "ospf",
+	14:    "bgp",
+	10002: "static",
+	10006: "static",
+	10007: "static",
+}
+
+func routeProtocolName(p uint32) string {
+	if s, ok := routeProtocolNames[p]; ok {
+		return s
+	}
+	return strconv.Itoa(int(p))
+}
+
+func sockaddrToIP(sa *SOCKADDR_INET) net.IP {
+	switch sa.Family {
+	case windows.AF_INET:
+		return net.IP(append([]byte(nil), sa.Data[2:6]...))
+	case windows.AF_INET6:
+		return net.IP(append([]byte(nil), sa.Data[6:22]...))
+	}
+	return nil
+}
+
+func ipToSockaddr(ip net.IP, sa *SOCKADDR_INET) {
+	if ip4 := ip.To4(); ip4 != nil {
+		sa.Family = windows.AF_INET
+		copy(sa.Data[2:6], ip4)
+		return
+	}
+	sa.Family = windows.AF_INET6
+	copy(sa.Data[6:22], ip.To16())
+}
+
+// 读取整个路由表
+func getForwardTable(family uint16) ([]MIB_IPFORWARD_ROW2, error) {
+	var table unsafe.Pointer
+	r1, _, _ := procGetIpForwardTable2.Call(uintptr(family), uintptr(unsafe.Pointer(&table)))
+	if r1 != 0 {
+		return nil, fmt.Errorf("GetIpForwardTable2 failed: %w", windows.Errno(r1))
+	}
+	defer procFreeMibTable.Call(uintptr(table))
+
+	// MIB_IPFORWARD_TABLE2 的行数组按 8 字节对齐
+	numEntries := binary.LittleEndian.Uint32((*[4]byte)(table)[:])
+	rows := unsafe.Slice((*MIB_IPFORWARD_ROW2)(unsafe.Add(table, 8)), numEntries)
+	return append([]MIB_IPFORWARD_ROW2(nil), rows...), nil
+}
+
+// windows 上只有一张路由表，filter.Table 只接受 main
+func (w *WindowsNctl) ListRoutes(filter interfaces.RouteFilter) ([]interfaces.Route, error) {
+	if filter.Table != 0 && filter.Table != interfaces.TableMain {
+		return nil, nil
+	}
+
+	family := uint16(windows.AF_UNSPEC)
+	switch filter.Family {
+	case 0:
+	case 4:
+		family = windows.AF_INET
+	case 6:
+		family = windows.AF_INET6
+	default:
+		return nil, fmt.Errorf("invalid address family %d (value: 4, 6)", filter.Family)
+	}
+
+	rows, err := getForwardTable(family)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []interfaces.Route
+	for _, row := range rows {
+		route := fromForwardRow(&row)
+		if filter.Iface != "" && route.Iface != filter.Iface {
+			continue
+		}
+		if filter.Protocol != "" && route.Protocol != filter.Protocol {
+			continue
+		}
+		result = append(result, route)
+	}
+	return result, nil
+}
+
+// 转换为 interfaces.Route
+func fromForwardRow(row *MIB_IPFORWARD_ROW2) interfaces.Route {
+	route := interfaces.Route{
+		Family:   4,
+		Gateway:  sockaddrToIP(&row.NextHop),
+		Metric:   int(row.Metric),
+		Table:    interfaces.TableMain,
+		Protocol: routeProtocolName(row.Protocol),
+		Type:     "unicast",
+	}
+	bits := 32
+	if row.DestinationPrefix.Family == windows.AF_INET6 {
+		route.Family = 6
+		bits = 128
+	}
+	route.Dst = &net.IPNet{IP: sockaddrToIP(&row.DestinationPrefix), Mask: net.CIDRMask(int(row.PrefixLength), bits)}
+	if route.Gateway != nil && route.Gateway.IsUnspecified() {
+		route.Gateway = nil
+	}
+	if ifi, err := net.InterfaceByIndex(int(row.InterfaceIndex)); err == nil {
+		route.Iface = ifi.Name
+	} else {
+		route.Iface = strconv.Itoa(int(row.InterfaceIndex))
+	}
+	return route
+}
+
+// 通过 GetBestRoute2 查询路由，windows 上没有策略路由，不支持 mark 和 iif
+func (w *WindowsNctl) LookupRoute(q interfaces.RouteQuery) (*interfaces.RouteLookup, error) {
+	if q.Mark != 0 || q.Iif != "" {
+		return nil, fmt.Errorf("--mark and --iif are not supported on Windows")
+	}
+
+	var dst, src, bestSrc SOCKADDR_INET
+	ipToSockaddr(q.Dst, &dst)
+	srcPtr := uintptr(0)
+	if q.Src != nil {
+		ipToSockaddr(q.Src, &src)
+		srcPtr = uintptr(unsafe.Pointer(&src))
+	}
+
+	var row MIB_IPFORWARD_ROW2
+	r1, _, _ := procGetBestRoute2.Call(0, 0, srcPtr, uintptr(unsafe.Pointer(&dst)), 0,
+		uintptr(unsafe.Pointer(&row)), uintptr(unsafe.Pointer(&bestSrc)))
+	if r1 != 0 {
+		return nil, fmt.Errorf("failed to look up route to %s: %w", q.Dst, windows.Errno(r1))
+	}
+
+	route := fromForwardRow(&row)
+	route.Src = sockaddrToIP(&bestSrc)
+	return &interfaces.RouteLookup{Route: route}, nil
+}
+
+// 转换为 MIB_IPFORWARD_ROW2，windows 上出接口必填
+func toForwardRow(r *interfaces.Route) (*MIB_IPFORWARD_ROW2, error) {
+	if r.Dst == nil {
+		return nil, fmt.Errorf("route destination is required")
+	}
+	if r.Iface == "" {
+		return nil, fmt.Errorf("output interface is required on Windows")
+	}
+	if r.Table != 0 && r.Table != interfaces.TableMain {
+		return nil, fmt.Errorf("routing tables are not supported on Windows")
+	}
+
+	ifa, err := findIface(r.Iface)
+	if err != nil {
+		return nil, err
+	}
+
+	var row MIB_IPFORWARD_ROW2
+	procInitializeIpForwardEntry.Call(uintptr(unsafe.Pointer(&row)))
+	row.InterfaceIndex = uint32(ifa.Index)
+	ipToSockaddr(r.Dst.IP, &row.DestinationPrefix)
+	ones, _ := r.Dst.Mask.Size()
+	row.PrefixLength = uint8(ones)
+	if r.Gateway != nil {
+		ipToSockaddr(r.Gateway, &row.NextHop)
+	} else {
+		row.NextHop.Family = row.DestinationPrefix.Family
+	}
+	row.Metric = uint32(r.Metric)
+	row.Protocol = 3
+	return &row, nil
+}
+
+func (w *WindowsNctl) AddRoute(r *interfaces.Route) error {
+	row, err := toForwardRow(r)
+	if err != nil {
+		return err
+	}
+	if r1, _, _ := procCreateIpForwardEntry2.Call(uintptr(unsafe.Pointer(row))); r1 != 0 {
+		return fmt.Errorf("failed to add route %s: %w", r.Dst, windows.Errno(r1))
+	}
+	return nil
+}
+
+func (w *WindowsNctl) DelRoute(r *interfaces.Route) error {
+	row, err := toForwardRow(r)
+	if err != nil {
+		return err
+	}
+	if r1, _, _ := procDeleteIpForwardEntry2.Call(uintptr(unsafe.Pointer(row))); r1 != 0 {
+		return fmt.Errorf("failed to delete route %s: %w", r.Dst, windows.Errno(r1))
+	}
+	return nil
+}
+
+// 先删除接口上目的网段相同的路由，再添加
+func (w *WindowsNctl) ReplaceRoute(r *interfaces.Route) error {
+	routes, err := w.ListRoutes(interfaces.RouteFilter{Iface: r.Iface})
+	if err != nil {
+		return err
+	}
+	for _, old := range routes {
+		if old.Dst.String() != r.Dst.String() {
+			continue
+		}
+		if err := w.DelRoute(&old); err != nil {
+			return err
+		}
+	}
+	return w.AddRoute(r)
+}
+
+// 接口上带网关的默认路由，按 metric 从小到大排列
+func (w *WindowsNctl) defaultRoutes(iface string, family int) ([]interfaces.Route, error) {
+	if _, err := findIface(iface); err != nil {
+		return nil, err
+	}
+	routes, err := w.ListRoutes(interfaces.RouteFilter{Family: family, Iface: iface})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []interfaces.Route
+	for _, r := range routes {
+		if ones, _ := r.Dst.Mask.Size(); ones == 0 && r.Gateway != nil {
+			result = append(result, r)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Metric < result[j].Metric })
+	return result, nil
+}
+
+// windows 上没有策略路由
+func (w *WindowsNctl) ListRules(family int) ([]interfaces.Rule, error) {
+	return nil, fmt.Errorf("policy routing rules are not supported on Windows")
+}
+
+func (w *WindowsNctl) AddRule(r *interfaces.Rule) error {
+	return fmt.Errorf("policy routing rules are not supported on Windows")
+}
+
+func (w *WindowsNctl) DelRule(r *interfaces.Rule) error {
+	return fmt.Errorf("policy routing rules are not supported on Windows")
+}
@@ -0,0 +1,212 @@
+//go:build windows
+
+package windows
+
+import (
+	"encoding/binary"
+	"fmt"
+	"nctl/interfaces"
+	"net"
+	"path/filepath"
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	procGetExtendedTcpTable = modiphlpapi.NewProc("GetExtendedTcpTable")
+	procGetExtendedUdpTable = modiphlpapi.NewProc("GetExtendedUdpTable")
+)
+
+const (
+	tcpTableOwnerPidAll = 5
+	udpTableOwnerPid    = 1
+)
+
+// MIB_TCPROW_OWNER_PID
+type MIB_TCPROW_OWNER_PID struct {
+	State      uint32
+	LocalAddr  [4]byte
+	LocalPort  uint32
+	RemoteAddr [4]byte
+	RemotePort uint32
+	OwningPid  uint32
+}
+
+// MIB_TCP6ROW_OWNER_PID
+type MIB_TCP6ROW_OWNER_PID struct {
+	LocalAddr     [16]byte
+	LocalScopeId  uint32
+	LocalPort     uint32
+	RemoteAddr    [16]byte
+	RemoteScopeId uint32
+	RemotePort    uint32
+	State         uint32
+	OwningPid     uint32
+}
+
+// MIB_UDPROW_OWNER_PID
+type MIB_UDPROW_OWNER_PID struct {
+	LocalAddr [4]byte
+	LocalPort uint32
+	OwningPid uint32
+}
+
+// MIB_UDP6ROW_OWNER_PID
+type MIB_UDP6ROW_OWNER_PID struct {
+	LocalAddr    [16]byte
+	LocalScopeId uint32
+	LocalPort    uint32
+	OwningPid    uint32
+}
+
+// MIB_TCP_STATE 对应的名称，与 linux 上 ss 的写法保持一致
+var tcpStateNames = map[uint32]string{
+	1:  "UNCONN",
+	2:  "LISTEN",
+	3:  "SYN-SENT",
+	4:  "SYN-RECV",
+	5:  "ESTAB",
+	6:  "FIN-WAIT-1",
+	7:  "FIN-WAIT-2",
+	8:  "CLOSE-WAIT",
+	9:  "CLOSING",
+	10: "LAST-ACK",
+	11: "TIME-WAIT",
+	12: "DELETE-TCB",
+}
+
+func tcpStateName(state uint32) string {
+	if s, ok := tcpStateNames[state]; ok {
+		return s
+	}
+	return strconv.Itoa(int(state))
+}
+
+// 端口以网络字节序保存在低 16 位
+func portFromDword(p uint32) int {
+	return int(p&0xff)<<8 | int(p>>8&0xff)
+}
+
+// 调用 GetExtendedTcpTable / GetExtendedUdpTable，缓冲区不足时按返回的大小重试
+func getExtendedTable(proc *windows.LazyProc, family uint32, class uint32) ([]byte, error) {
+	var size uint32
+	var buf []byte
+	for i := 0; i < 5; i++ {
+		var ptr uintptr
+		if len(buf) > 0 {
+			ptr = uintptr(unsafe.Pointer(&buf[0]))
+		}
+		r1, _, _ := proc.Call(ptr, uintptr(unsafe.Pointer(&size)), 0, uintptr(family), uintptr(class), 0)
+		switch windows.Errno(r1) {
+		case 0:
+			return buf[:size], nil
+		case windows.ERROR_INSUFFICIENT_BUFFER:
+			buf = make([]byte, size)
+		default:
+			return nil, fmt.Errorf("%s failed: %w", proc.Name, windows.Errno(r1))
+		}
+	}
+	return nil, fmt.Errorf("%s failed: table keeps growing", proc.Name)
+}
+
+// 表头为 dwNumEntries，行数组紧随其后
+func tableRows[T any](buf []byte) []T {
+	if len(buf) < 4 {
+		return nil
+	}
+	n := binary.LittleEndian.Uint32(buf)
+	if n == 0 {
+		return nil
+	}
+	return unsafe.Slice((*T)(unsafe.Pointer(&buf[4])), n)
+}
+
+func (w *WindowsNctl) ListSockets(filter interfaces.SocketFilter) ([]interfaces.Socket, error) {
+	var result []interfaces.Socket
+	if filter.TCP || !filter.UDP {
+		buf, err := getExtendedTable(procGetExtendedTcpTable, windows.AF_INET, tcpTableOwnerPidAll)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range tableRows[MIB_TCPROW_OWNER_PID](buf) {
+			result = append(result, interfaces.Socket{
+				Proto: "tcp", Family: 4,
+				LocalIP: net.IP(r.LocalAddr[:]).To16(), LocalPort: portFromDword(r.LocalPort),
+				RemoteIP: net.IP(r.RemoteAddr[:]).To16(), RemotePort: portFromDword(r.RemotePort),
+				State: tcpStateName(r.State), PID: int(r.OwningPid),
+			})
+		}
+		if buf, err = getExtendedTable(procGetExtendedTcpTable, windows.AF_INET6, tcpTableOwnerPidAll); err != nil {
+			return nil, err
+		}
+		for _, r := range tableRows[MIB_TCP6ROW_OWNER_PID](buf) {
+			result = append(result, interfaces.Socket{
+				Proto: "tcp", Family: 6,
+				LocalIP: net.IP(append([]byte(nil), r.LocalAddr[:]...)), LocalPort: portFromDword(r.LocalPort),
+				RemoteIP: net.IP(append([]byte(nil), r.RemoteAddr[:]...)), RemotePort: portFromDword(r.RemotePort),
+				State: tcpStateName(r.State), PID: int(r.OwningPid),
+			})
+		}
+	}
+	if filter.UDP || !filter.TCP {
+		buf, err := getExtendedTable(procGetExtendedUdpTable, windows.AF_INET, udpTableOwnerPid)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range tableRows[MIB_UDPROW_OWNER_PID](buf) {
+			result = append(result, interfaces.Socket{
+				Proto: "udp", Family: 4,
+				LocalIP: net.IP(r.LocalAddr[:]).To16(), LocalPort: portFromDword(r.LocalPort),
+				RemoteIP: net.IPv4zero, State: "UNCONN", PID: int(r.OwningPid),
+			})
+		}
+		if buf, err = getExtendedTable(procGetExtendedUdpTable, windows.AF_INET6, udpTableOwnerPid); err != nil {
+			return nil, err
+		}
+		for _, r := range tableRows[MIB_UDP6ROW_OWNER_PID](buf) {
+			result = append(result, interfaces.Socket{
+				Proto: "udp", Family: 6,
+				LocalIP: net.IP(append([]byte(nil), r.LocalAddr[:]...)), LocalPort: portFromDword(r.LocalPort),
+				RemoteIP: net.IPv6zero, State: "UNCONN", PID: int(r.OwningPid),
+			})
+		}
+	}
+
+	var filtered []interfaces.Socket
+	names := map[int]string{}
+	for _, s := range result {
+		if filter.Listen && !s.Listening() {
+			continue
+		}
+		if filter.Port != 0 && s.LocalPort != filter.Port && s.RemotePort != filter.Port {
+			continue
+		}
+		if _, ok := names[s.PID]; !ok {
+			names[s.PID] = processName(uint32(s.PID))
+		}
+		s.Command = names[s.PID]
+		filtered = append(filtered, s)
+	}
+	return filtered, nil
+}
+
+// 进程的可执行文件名，无权限时返回空
+func processName(pid uint32) string {
+	if pid == 0 {
+		return ""
+	}
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer windows.CloseHandle(h)
+
+	buf := make([]uint16, windows.MAX_PATH)
+	size := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(h, 0, &buf[0], &size); err != nil {
+		return ""
+	}
+	return filepath.Base(windows.UTF16ToString(buf[:size]))
+}
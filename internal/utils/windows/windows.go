@@ -1,4 +1,4 @@
-// go: build windows
+//go:build windows
 
 package windows
 
@@ -12,6 +12,11 @@ import (
 
 // 编译时接口检查
 var _ interfaces.Ifaces = (*WindowsNctl)(nil)
+var _ interfaces.Routes = (*WindowsNctl)(nil)
+var _ interfaces.Neighs = (*WindowsNctl)(nil)
+var _ interfaces.Sockets = (*WindowsNctl)(nil)
+var _ interfaces.Proxies = (*WindowsNctl)(nil)
+var _ interfaces.WireGuards = (*WindowsNctl)(nil)
 
 // 工厂函数
 func Iface() interfaces.Ifaces {
@@ -22,6 +27,51 @@ func Iface() interfaces.Ifaces {
 	return w
 }
 
+// 路由操作的工厂函数
+func Route() interfaces.Routes {
+	w, err := newWindowsNctl()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to initialize Windows network controller: %v", err)
+	}
+	return w
+}
+
+// 邻居表操作的工厂函数
+func Neigh() interfaces.Neighs {
+	w, err := newWindowsNctl()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to initialize Windows network controller: %v", err)
+	}
+	return w
+}
+
+// 套接字查询的工厂函数
+func Socket() interfaces.Sockets {
+	w, err := newWindowsNctl()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to initialize Windows network controller: %v", err)
+	}
+	return w
+}
+
+// 代理设置的工厂函数
+func Proxy() interfaces.Proxies {
+	w, err := newWindowsNctl()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to initialize Windows network controller: %v", err)
+	}
+	return w
+}
+
+// WireGuard 接口操作的工厂函数
+func WireGuard() interfaces.WireGuards {
+	w, err := newWindowsNctl()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to initialize Windows network controller: %v", err)
+	}
+	return w
+}
+
 type WindowsNctl struct {
 	iphlpapi *windows.DLL
 	// 针对 ipv4
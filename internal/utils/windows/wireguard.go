@@ -0,0 +1,25 @@
+//go:build windows
+
+package windows
+
+import (
+	"fmt"
+	"nctl/interfaces"
+)
+
+// Windows 上的 WireGuard 接口由 wireguard-nt 驱动管理，不通过系统 API 暴露
+func (w *WindowsNctl) CreateWireGuard(name string) error {
+	return fmt.Errorf("managing WireGuard interfaces is not supported on Windows")
+}
+
+func (w *WindowsNctl) ListWireGuards(name string) ([]interfaces.WireGuardDevice, error) {
+	return nil, fmt.Errorf("managing WireGuard interfaces is not supported on Windows")
+}
+
+func (w *WindowsNctl) ConfigureWireGuard(name string, cfg *interfaces.WireGuardConfig) error {
+	return fmt.Errorf("managing WireGuard interfaces is not supported on Windows")
+}
+
+func (w *WindowsNctl) DeleteWireGuard(name string) error {
+	return fmt.Errorf("managing WireGuard interfaces is not supported on Windows")
+}